@@ -5,11 +5,26 @@ import (
 	"fmt"
 	"log"
 
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/address"
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/admin"
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/analytics"
 	bankaccount "github.com/ahmadnaufal/openidea-shopifyx/internal/bank_account"
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/commission"
 	"github.com/ahmadnaufal/openidea-shopifyx/internal/config"
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/conversation"
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/coupon"
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/idempotency"
 	"github.com/ahmadnaufal/openidea-shopifyx/internal/image"
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/invoice"
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/ledger"
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/notification"
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/payment"
 	"github.com/ahmadnaufal/openidea-shopifyx/internal/product"
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/realtime"
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/shipping"
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/tax"
 	"github.com/ahmadnaufal/openidea-shopifyx/internal/user"
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/webhook"
 	"github.com/ahmadnaufal/openidea-shopifyx/pkg/jwt"
 	"github.com/ahmadnaufal/openidea-shopifyx/pkg/middleware"
 	"github.com/ahmadnaufal/openidea-shopifyx/pkg/s3"
@@ -30,13 +45,19 @@ func main() {
 	app := fiber.New()
 	app.Use(logger.New())
 	app.Use(recover.New())
-	app.Use(compress.New())
+	app.Use(compress.New(compress.Config{
+		// compressing would buffer the event stream
+		Next: func(c *fiber.Ctx) bool {
+			return c.Path() == "/v1/events"
+		},
+	}))
 	// custom middleware to set all method not allowed response to not found
 	app.Use(middleware.CustomMiddleware404())
 
 	jwtProvider := jwt.NewJWTProvider(cfg.JWTSecret)
 
-	db := connectToDB(cfg.Database, cfg.Env)
+	dsn := buildDSN(cfg.Database, cfg.Env)
+	db := connectToDB(cfg.Database, dsn)
 
 	trxProvider := config.NewTransactionProvider(db)
 
@@ -56,13 +77,111 @@ func main() {
 	product.ProductRepoImpl = &productRepo
 	product.TrxProvider = &trxProvider
 	product.UserRepoImpl = &userRepo
+	product.TrashRetention = cfg.Product.TrashRetention
+	product.PaymentDeadline = cfg.Order.PaymentDeadline
 
 	bankAccountRepo := bankaccount.NewBankAccountRepo(db)
 	bankaccount.BankAccountRepoImpl = &bankAccountRepo
+	bankaccount.TrxProvider = &trxProvider
+	if cfg.BankAccount.VerifierMockEnabled {
+		bankaccount.VerifierImpl = bankaccount.FakeVerifier{}
+	}
+	bankaccount.ProductsUnpurchasableHandler = product.MarkSellerProductsUnpurchasable
 	product.BankAccountRepoImpl = &bankAccountRepo
 
+	addressRepo := address.NewAddressRepo(db)
+	address.AddressRepoImpl = &addressRepo
+	address.TrxProvider = &trxProvider
+	product.AddressRepoImpl = &addressRepo
+
+	shippingRepo := shipping.NewShippingRepo(db)
+	shipping.ShippingRepoImpl = &shippingRepo
+	shipping.TrxProvider = &trxProvider
+	product.ShippingProviderImpl = shipping.NewRateProvider(cfg.Shipping.Provider, &shippingRepo)
+
+	paymentRepo := payment.NewPaymentRepo(db)
+	paymentProviders, err := payment.NewProviders(cfg.Payment.MockEnabled, cfg.Payment.MockCallbackSecret)
+	if err != nil {
+		panic(err)
+	}
+	payment.PaymentRepoImpl = &paymentRepo
+	payment.TrxProvider = &trxProvider
+	payment.Providers = paymentProviders
+	payment.OrderPaidHandler = product.MarkOrderPaid
+	payment.OrderLockHandler = product.LockOrder
+	product.PaymentRepoImpl = &paymentRepo
+	product.PaymentProviders = paymentProviders
+
+	ledgerRepo := ledger.NewLedgerRepo(db)
+	ledger.LedgerRepoImpl = &ledgerRepo
+	ledger.BankAccountRepoImpl = &bankAccountRepo
+	ledger.TrxProvider = &trxProvider
+
+	commissionRepo := commission.NewCommissionRepo(db)
+	commission.CommissionRepoImpl = &commissionRepo
+	commission.DefaultRateBasisPoints = cfg.Commission.DefaultRateBasisPoints
+
+	taxRepo := tax.NewTaxRepo(db)
+	tax.TaxRepoImpl = &taxRepo
+	product.TaxRepoImpl = &taxRepo
+
+	invoiceRepo := invoice.NewInvoiceRepo(db)
+	invoice.InvoiceRepoImpl = &invoiceRepo
+	invoice.TrxProvider = &trxProvider
+
+	couponRepo := coupon.NewCouponRepo(db)
+	coupon.CouponRepoImpl = &couponRepo
+	product.CouponRepoImpl = &couponRepo
+
+	analyticsRepo := analytics.NewAnalyticsRepo(db)
+	analytics.AnalyticsRepoImpl = &analyticsRepo
+	analytics.TrxProvider = &trxProvider
+	analytics.OrderCancellationWindow = cfg.Order.PaymentDeadline
+	viewRecorder := analytics.NewViewRecorder(cfg.Analytics.ViewBufferSize, cfg.Analytics.ViewDedupWindow)
+	product.ViewRecorderImpl = viewRecorder
+
+	notificationRepo := notification.NewNotificationRepo(db)
+	notification.NotificationRepoImpl = &notificationRepo
+	notification.TrxProvider = &trxProvider
+	product.NotificationRepoImpl = &notificationRepo
+
+	conversationRepo := conversation.NewConversationRepo(db)
+	conversation.ConversationRepoImpl = &conversationRepo
+	conversation.TrxProvider = &trxProvider
+
+	webhookRepo := webhook.NewWebhookRepo(db)
+	webhook.WebhookRepoImpl = &webhookRepo
+	webhook.TrxProvider = &trxProvider
+	product.WebhookRepoImpl = &webhookRepo
+
+	realtimeRepo := realtime.NewRealtimeRepo(db)
+	realtime.RealtimeRepoImpl = &realtimeRepo
+	realtime.HubImpl = realtime.NewHub()
+	realtime.VisibleProductsFilter = product.FilterVisibleProductIDs
+
+	idempotencyRepo := idempotency.NewIdempotencyRepo(db)
+	idempotency.IdempotencyRepoImpl = &idempotencyRepo
+	idempotency.KeyTTL = cfg.Idempotency.KeyTTL
+
 	image.S3ProviderImpl = &s3Provider
 
+	admin.UserIDs = cfg.AdminUserIDs
+
+	// background jobs
+	go product.RunPurgeWorker(context.Background(), cfg.Product.PurgeInterval)
+	go product.RunOrderExpiryWorker(context.Background(), cfg.Order.ExpiryInterval)
+	go analytics.RunRefreshWorker(context.Background(), cfg.Analytics.RefreshInterval)
+	go viewRecorder.Run(context.Background())
+	go notification.RunOutboxWorker(context.Background(), cfg.Notification.OutboxInterval)
+	go webhook.RunDeliveryWorker(context.Background(), cfg.Webhook.DeliveryInterval, cfg.Webhook.Timeout)
+	go realtime.RunListener(context.Background(), dsn, realtime.HubImpl)
+	go idempotency.RunCleanupWorker(context.Background(), cfg.Idempotency.CleanupInterval)
+	if bankaccount.VerifierImpl != nil {
+		go bankaccount.RunVerificationWorker(context.Background(), cfg.BankAccount.VerificationInterval)
+	} else {
+		log.Print("no bank account verifier configured, pending bank accounts are verified by admins")
+	}
+
 	// setup instrumentation
 	prometheus := fiberprometheus.New("shopifyx")
 	prometheus.RegisterAt(app, "/metrics")
@@ -72,6 +191,18 @@ func main() {
 	user.RegisterRoute(app)
 	product.RegisterRoute(app, jwtProvider)
 	bankaccount.RegisterRoute(app, jwtProvider)
+	address.RegisterRoute(app, jwtProvider)
+	shipping.RegisterRoute(app, jwtProvider)
+	payment.RegisterRoute(app, jwtProvider)
+	ledger.RegisterRoute(app, jwtProvider)
+	commission.RegisterRoute(app, jwtProvider)
+	tax.RegisterRoute(app, jwtProvider)
+	coupon.RegisterRoute(app, jwtProvider)
+	analytics.RegisterRoute(app, jwtProvider)
+	notification.RegisterRoute(app, jwtProvider)
+	conversation.RegisterRoute(app, jwtProvider)
+	realtime.RegisterRoute(app, jwtProvider)
+	webhook.RegisterRoute(app, jwtProvider)
 	image.RegisterRoute(app, jwtProvider)
 
 	addr := fmt.Sprintf(":%s", cfg.AppPort)
@@ -79,7 +210,7 @@ func main() {
 	log.Fatal(app.Listen(addr))
 }
 
-func connectToDB(dbCfg config.DatabaseConfig, env string) *sqlx.DB {
+func buildDSN(dbCfg config.DatabaseConfig, env string) string {
 	var dsn string
 	if env == "production" {
 		dsn = fmt.Sprintf(
@@ -95,6 +226,10 @@ func connectToDB(dbCfg config.DatabaseConfig, env string) *sqlx.DB {
 		)
 	}
 
+	return dsn
+}
+
+func connectToDB(dbCfg config.DatabaseConfig, dsn string) *sqlx.DB {
 	db, err := sqlx.Open("postgres", dsn)
 	if err != nil {
 		panic(err)
@@ -0,0 +1,6 @@
+ALTER TABLE products
+  ADD COLUMN IF NOT EXISTS status VARCHAR(16) NOT NULL DEFAULT 'active',
+  ADD COLUMN IF NOT EXISTS publish_at TIMESTAMP(0),
+  ADD COLUMN IF NOT EXISTS unpublish_at TIMESTAMP(0);
+
+CREATE INDEX IF NOT EXISTS idx_products_status ON products(status);
@@ -0,0 +1,12 @@
+ALTER TABLE orders
+  DROP COLUMN IF EXISTS coupon_id,
+  DROP COLUMN IF EXISTS coupon_code,
+  DROP COLUMN IF EXISTS discount_amount;
+
+DROP INDEX IF EXISTS idx_coupons_user_id_code;
+DROP TABLE IF EXISTS coupons;
+
+ALTER TABLE products
+  DROP COLUMN IF EXISTS sale_price,
+  DROP COLUMN IF EXISTS sale_starts_at,
+  DROP COLUMN IF EXISTS sale_ends_at;
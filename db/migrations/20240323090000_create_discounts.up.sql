@@ -0,0 +1,26 @@
+ALTER TABLE products
+  ADD COLUMN IF NOT EXISTS sale_price INTEGER,
+  ADD COLUMN IF NOT EXISTS sale_starts_at TIMESTAMP(0),
+  ADD COLUMN IF NOT EXISTS sale_ends_at TIMESTAMP(0);
+
+CREATE TABLE IF NOT EXISTS coupons (
+  id VARCHAR(64) PRIMARY KEY,
+  user_id VARCHAR(64) NOT NULL,
+  product_id VARCHAR(64),
+  code VARCHAR(32) NOT NULL,
+  discount_type VARCHAR(16) NOT NULL,
+  value INTEGER NOT NULL,
+  usage_limit INTEGER,
+  used_count INTEGER NOT NULL DEFAULT 0,
+  expires_at TIMESTAMP(0),
+  created_at TIMESTAMP(0) DEFAULT NOW(),
+  updated_at TIMESTAMP(0) DEFAULT NOW(),
+  deleted_at TIMESTAMP(0)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_coupons_user_id_code ON coupons(user_id, code) WHERE deleted_at IS NULL;
+
+ALTER TABLE orders
+  ADD COLUMN IF NOT EXISTS coupon_id VARCHAR(64),
+  ADD COLUMN IF NOT EXISTS coupon_code VARCHAR(32),
+  ADD COLUMN IF NOT EXISTS discount_amount INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,20 @@
+DROP INDEX IF EXISTS idx_products_currency_price;
+
+UPDATE coupons SET value = value / 100 WHERE discount_type = 'fixed';
+
+ALTER TABLE coupons
+  DROP COLUMN IF EXISTS currency,
+  ALTER COLUMN value TYPE INTEGER;
+
+UPDATE orders SET discount_amount = discount_amount / 100;
+
+ALTER TABLE orders
+  DROP COLUMN IF EXISTS currency,
+  ALTER COLUMN discount_amount TYPE INTEGER;
+
+UPDATE products SET price = price / 100, sale_price = sale_price / 100;
+
+ALTER TABLE products
+  DROP COLUMN IF EXISTS currency,
+  ALTER COLUMN price TYPE INTEGER,
+  ALTER COLUMN sale_price TYPE INTEGER;
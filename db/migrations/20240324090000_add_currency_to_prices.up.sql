@@ -0,0 +1,21 @@
+-- prices are now stored in the currency's minor units (ISO 4217 exponent), existing listings are all in IDR
+ALTER TABLE products
+  ALTER COLUMN price TYPE BIGINT,
+  ALTER COLUMN sale_price TYPE BIGINT,
+  ADD COLUMN IF NOT EXISTS currency VARCHAR(3) NOT NULL DEFAULT 'IDR';
+
+UPDATE products SET price = price * 100, sale_price = sale_price * 100;
+
+ALTER TABLE orders
+  ALTER COLUMN discount_amount TYPE BIGINT,
+  ADD COLUMN IF NOT EXISTS currency VARCHAR(3) NOT NULL DEFAULT 'IDR';
+
+UPDATE orders SET discount_amount = discount_amount * 100;
+
+ALTER TABLE coupons
+  ALTER COLUMN value TYPE BIGINT,
+  ADD COLUMN IF NOT EXISTS currency VARCHAR(3);
+
+UPDATE coupons SET value = value * 100, currency = 'IDR' WHERE discount_type = 'fixed';
+
+CREATE INDEX IF NOT EXISTS idx_products_currency_price ON products(currency, price);
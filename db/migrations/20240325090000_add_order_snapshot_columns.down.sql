@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_orders_seller_id;
+
+ALTER TABLE orders
+  DROP COLUMN IF EXISTS seller_id,
+  DROP COLUMN IF EXISTS product_name,
+  DROP COLUMN IF EXISTS product_image_url,
+  DROP COLUMN IF EXISTS product_condition,
+  DROP COLUMN IF EXISTS unit_price,
+  DROP COLUMN IF EXISTS subtotal,
+  DROP COLUMN IF EXISTS total;
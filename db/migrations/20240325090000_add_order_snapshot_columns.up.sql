@@ -0,0 +1,29 @@
+ALTER TABLE orders
+  ADD COLUMN IF NOT EXISTS seller_id VARCHAR(64),
+  ADD COLUMN IF NOT EXISTS product_name VARCHAR(62),
+  ADD COLUMN IF NOT EXISTS product_image_url VARCHAR(255),
+  ADD COLUMN IF NOT EXISTS product_condition VARCHAR(16),
+  ADD COLUMN IF NOT EXISTS unit_price BIGINT NOT NULL DEFAULT 0,
+  ADD COLUMN IF NOT EXISTS subtotal BIGINT NOT NULL DEFAULT 0,
+  ADD COLUMN IF NOT EXISTS total BIGINT NOT NULL DEFAULT 0;
+
+-- best effort backfill for existing orders, using the product as it is now
+UPDATE orders o
+SET
+  seller_id = p.user_id,
+  product_name = p.name,
+  product_image_url = p.image_url,
+  product_condition = p.condition,
+  unit_price = p.price,
+  subtotal = p.price * o.quantity,
+  total = GREATEST(p.price * o.quantity - o.discount_amount, 0)
+FROM products p
+WHERE p.id = o.product_id;
+
+ALTER TABLE orders
+  ALTER COLUMN seller_id SET NOT NULL,
+  ALTER COLUMN product_name SET NOT NULL,
+  ALTER COLUMN product_image_url SET NOT NULL,
+  ALTER COLUMN product_condition SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_orders_seller_id ON orders(seller_id);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_orders_created_at;
+
+DROP TABLE IF EXISTS analytics_refresh_states;
+DROP TABLE IF EXISTS seller_daily_buyers;
+DROP TABLE IF EXISTS product_daily_views;
+DROP TABLE IF EXISTS product_daily_sales;
@@ -0,0 +1,40 @@
+CREATE TABLE IF NOT EXISTS product_daily_sales (
+  product_id VARCHAR(64) NOT NULL,
+  seller_id VARCHAR(64) NOT NULL,
+  day DATE NOT NULL,
+  currency VARCHAR(3) NOT NULL,
+  units BIGINT NOT NULL DEFAULT 0,
+  orders BIGINT NOT NULL DEFAULT 0,
+  revenue BIGINT NOT NULL DEFAULT 0,
+  PRIMARY KEY (product_id, day, currency)
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_daily_sales_seller_id_day ON product_daily_sales(seller_id, day);
+CREATE INDEX IF NOT EXISTS idx_product_daily_sales_day ON product_daily_sales(day);
+
+CREATE TABLE IF NOT EXISTS product_daily_views (
+  product_id VARCHAR(64) NOT NULL,
+  seller_id VARCHAR(64) NOT NULL,
+  day DATE NOT NULL,
+  views BIGINT NOT NULL DEFAULT 0,
+  PRIMARY KEY (product_id, day)
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_daily_views_seller_id_day ON product_daily_views(seller_id, day);
+
+CREATE TABLE IF NOT EXISTS seller_daily_buyers (
+  seller_id VARCHAR(64) NOT NULL,
+  buyer_id VARCHAR(64) NOT NULL,
+  day DATE NOT NULL,
+  orders BIGINT NOT NULL DEFAULT 0,
+  PRIMARY KEY (seller_id, day, buyer_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_seller_daily_buyers_day ON seller_daily_buyers(day);
+
+CREATE TABLE IF NOT EXISTS analytics_refresh_states (
+  name VARCHAR(32) PRIMARY KEY,
+  refreshed_until TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_orders_created_at ON orders(created_at);
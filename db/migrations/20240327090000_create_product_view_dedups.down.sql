@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_product_daily_views_day;
+
+DROP TABLE IF EXISTS product_view_dedups;
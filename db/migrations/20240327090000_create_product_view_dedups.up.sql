@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS product_view_dedups (
+  product_id VARCHAR(64) NOT NULL,
+  viewer_key VARCHAR(128) NOT NULL,
+  window_start TIMESTAMPTZ NOT NULL,
+  PRIMARY KEY (product_id, viewer_key, window_start)
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_view_dedups_window_start ON product_view_dedups(window_start);
+
+CREATE INDEX IF NOT EXISTS idx_product_daily_views_day ON product_daily_views(day);
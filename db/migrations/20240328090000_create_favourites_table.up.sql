@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS favourites (
+  user_id VARCHAR(64) NOT NULL,
+  product_id VARCHAR(64) NOT NULL,
+  created_at TIMESTAMP(0) DEFAULT NOW(),
+  PRIMARY KEY (user_id, product_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_favourites_product_id ON favourites(product_id);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS notifications;
+DROP TABLE IF EXISTS notification_outbox;
+DROP TABLE IF EXISTS product_subscriptions;
@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS conversations (
+  id VARCHAR(64) PRIMARY KEY,
+  product_id VARCHAR(64) NOT NULL,
+  buyer_id VARCHAR(64) NOT NULL,
+  seller_id VARCHAR(64) NOT NULL,
+  buyer_last_read_at TIMESTAMPTZ,
+  seller_last_read_at TIMESTAMPTZ,
+  last_message_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  UNIQUE (product_id, buyer_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_conversations_buyer_id ON conversations(buyer_id);
+CREATE INDEX IF NOT EXISTS idx_conversations_seller_id ON conversations(seller_id);
+
+CREATE TABLE IF NOT EXISTS conversation_messages (
+  id VARCHAR(64) PRIMARY KEY,
+  conversation_id VARCHAR(64) NOT NULL,
+  sender_id VARCHAR(64) NOT NULL,
+  body TEXT NOT NULL,
+  image_url VARCHAR(255),
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_conversation_messages_conversation_id_created_at ON conversation_messages(conversation_id, created_at, id);
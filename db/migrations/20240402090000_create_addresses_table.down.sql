@@ -0,0 +1,13 @@
+ALTER TABLE orders
+  DROP COLUMN IF EXISTS shipped_at,
+  DROP COLUMN IF EXISTS tracking_number,
+  DROP COLUMN IF EXISTS courier,
+  DROP COLUMN IF EXISTS shipping_country,
+  DROP COLUMN IF EXISTS shipping_postal_code,
+  DROP COLUMN IF EXISTS shipping_province,
+  DROP COLUMN IF EXISTS shipping_city,
+  DROP COLUMN IF EXISTS shipping_address_line,
+  DROP COLUMN IF EXISTS shipping_phone,
+  DROP COLUMN IF EXISTS shipping_recipient_name;
+
+DROP TABLE IF EXISTS addresses;
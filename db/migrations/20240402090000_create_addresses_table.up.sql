@@ -0,0 +1,31 @@
+CREATE TABLE IF NOT EXISTS addresses (
+  id VARCHAR(64) PRIMARY KEY,
+  user_id VARCHAR(64) NOT NULL,
+  label VARCHAR(30) NOT NULL DEFAULT '',
+  recipient_name VARCHAR(60) NOT NULL,
+  phone VARCHAR(20) NOT NULL,
+  address_line VARCHAR(255) NOT NULL,
+  city VARCHAR(60) NOT NULL,
+  province VARCHAR(60) NOT NULL,
+  postal_code VARCHAR(10) NOT NULL,
+  country VARCHAR(2) NOT NULL,
+  is_default BOOLEAN NOT NULL DEFAULT FALSE,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  deleted_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_addresses_user_id ON addresses(user_id) WHERE deleted_at IS NULL;
+-- a user can only have one default address
+CREATE UNIQUE INDEX IF NOT EXISTS idx_addresses_user_id_default ON addresses(user_id) WHERE is_default AND deleted_at IS NULL;
+
+ALTER TABLE orders
+  ADD COLUMN IF NOT EXISTS shipping_recipient_name VARCHAR(60),
+  ADD COLUMN IF NOT EXISTS shipping_phone VARCHAR(20),
+  ADD COLUMN IF NOT EXISTS shipping_address_line VARCHAR(255),
+  ADD COLUMN IF NOT EXISTS shipping_city VARCHAR(60),
+  ADD COLUMN IF NOT EXISTS shipping_province VARCHAR(60),
+  ADD COLUMN IF NOT EXISTS shipping_postal_code VARCHAR(10),
+  ADD COLUMN IF NOT EXISTS shipping_country VARCHAR(2),
+  ADD COLUMN IF NOT EXISTS courier VARCHAR(30),
+  ADD COLUMN IF NOT EXISTS tracking_number VARCHAR(40),
+  ADD COLUMN IF NOT EXISTS shipped_at TIMESTAMPTZ;
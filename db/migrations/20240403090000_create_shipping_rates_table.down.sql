@@ -0,0 +1,8 @@
+ALTER TABLE orders
+  DROP COLUMN IF EXISTS shipping_cost,
+  DROP COLUMN IF EXISTS shipping_service;
+
+DROP TABLE IF EXISTS shipping_rates;
+DROP TABLE IF EXISTS shipping_settings;
+
+ALTER TABLE products DROP COLUMN IF EXISTS weight_grams;
@@ -0,0 +1,24 @@
+ALTER TABLE products ADD COLUMN IF NOT EXISTS weight_grams INT NOT NULL DEFAULT 1000;
+
+CREATE TABLE IF NOT EXISTS shipping_settings (
+  user_id VARCHAR(64) PRIMARY KEY,
+  origin_region VARCHAR(60) NOT NULL,
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS shipping_rates (
+  id SERIAL PRIMARY KEY,
+  user_id VARCHAR(64) NOT NULL,
+  service VARCHAR(30) NOT NULL,
+  origin_region VARCHAR(60) NOT NULL,
+  destination_region VARCHAR(60) NOT NULL,
+  max_weight_grams INT NOT NULL,
+  cost BIGINT NOT NULL,
+  currency VARCHAR(3) NOT NULL,
+  estimated_days INT NOT NULL DEFAULT 0,
+  UNIQUE (user_id, service, origin_region, destination_region, max_weight_grams, currency)
+);
+
+ALTER TABLE orders
+  ADD COLUMN IF NOT EXISTS shipping_service VARCHAR(30),
+  ADD COLUMN IF NOT EXISTS shipping_cost BIGINT NOT NULL DEFAULT 0;
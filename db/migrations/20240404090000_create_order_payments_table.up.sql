@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS order_payments (
+  id VARCHAR(64) PRIMARY KEY,
+  order_id VARCHAR(64) NOT NULL UNIQUE,
+  user_id VARCHAR(64) NOT NULL,
+  provider VARCHAR(30) NOT NULL,
+  status VARCHAR(20) NOT NULL,
+  amount BIGINT NOT NULL,
+  currency VARCHAR(3) NOT NULL,
+  external_id VARCHAR(64) NOT NULL,
+  virtual_account_number VARCHAR(32),
+  payment_proof_image_url VARCHAR(128),
+  paid_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  UNIQUE (provider, external_id)
+);
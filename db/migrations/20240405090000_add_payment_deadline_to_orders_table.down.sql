@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_orders_payment_deadline;
+
+ALTER TABLE orders DROP COLUMN IF EXISTS payment_deadline;
@@ -0,0 +1,3 @@
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS payment_deadline TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_orders_payment_deadline ON orders(payment_deadline) WHERE status = 'pending_payment';
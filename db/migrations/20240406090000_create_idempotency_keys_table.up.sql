@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+  user_id VARCHAR(64) NOT NULL,
+  idempotency_key VARCHAR(255) NOT NULL,
+  request_hash VARCHAR(64) NOT NULL,
+  status VARCHAR(20) NOT NULL,
+  lease_token VARCHAR(64) NOT NULL,
+  response_status INT,
+  response_content_type VARCHAR(100),
+  response_body BYTEA,
+  created_at TIMESTAMP(0) NOT NULL DEFAULT NOW(),
+  expires_at TIMESTAMP(0) NOT NULL,
+  PRIMARY KEY (user_id, idempotency_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
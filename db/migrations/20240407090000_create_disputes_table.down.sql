@@ -0,0 +1,5 @@
+ALTER TABLE orders DROP COLUMN IF EXISTS refunded_amount;
+
+DROP TABLE IF EXISTS refunds;
+DROP TABLE IF EXISTS dispute_messages;
+DROP TABLE IF EXISTS disputes;
@@ -0,0 +1,42 @@
+CREATE TABLE IF NOT EXISTS disputes (
+  id VARCHAR(64) PRIMARY KEY,
+  order_id VARCHAR(64) NOT NULL UNIQUE,
+  buyer_id VARCHAR(64) NOT NULL,
+  seller_id VARCHAR(64) NOT NULL,
+  reason VARCHAR(30) NOT NULL,
+  status VARCHAR(20) NOT NULL,
+  proposed_refund_amount BIGINT,
+  proposed_restore_stock BOOLEAN NOT NULL DEFAULT FALSE,
+  proposed_by VARCHAR(64),
+  resolution VARCHAR(20),
+  resolved_by VARCHAR(64),
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  resolved_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_disputes_status_created_at ON disputes(status, created_at);
+
+CREATE TABLE IF NOT EXISTS dispute_messages (
+  id VARCHAR(64) PRIMARY KEY,
+  dispute_id VARCHAR(64) NOT NULL REFERENCES disputes(id) ON DELETE CASCADE,
+  sender_id VARCHAR(64) NOT NULL,
+  message TEXT NOT NULL,
+  evidence_image_urls TEXT[] NOT NULL DEFAULT '{}',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_dispute_messages_dispute_id ON dispute_messages(dispute_id, created_at);
+
+CREATE TABLE IF NOT EXISTS refunds (
+  id VARCHAR(64) PRIMARY KEY,
+  order_id VARCHAR(64) NOT NULL,
+  dispute_id VARCHAR(64) NOT NULL REFERENCES disputes(id),
+  amount BIGINT NOT NULL,
+  currency VARCHAR(3) NOT NULL,
+  restored_quantity INT NOT NULL DEFAULT 0,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_refunds_order_id ON refunds(order_id);
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS refunded_amount BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,4 @@
+ALTER TABLE orders DROP COLUMN IF EXISTS completed_at;
+
+DROP TABLE IF EXISTS payouts;
+DROP TABLE IF EXISTS ledger_entries;
@@ -0,0 +1,32 @@
+CREATE TABLE IF NOT EXISTS ledger_entries (
+  id BIGSERIAL PRIMARY KEY,
+  transaction_id VARCHAR(64) NOT NULL,
+  account VARCHAR(30) NOT NULL,
+  user_id VARCHAR(64),
+  entry_type VARCHAR(30) NOT NULL,
+  amount BIGINT NOT NULL,
+  currency VARCHAR(3) NOT NULL,
+  reference_id VARCHAR(64) NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_account_user_id ON ledger_entries(account, user_id, currency);
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_transaction_id ON ledger_entries(transaction_id);
+
+CREATE TABLE IF NOT EXISTS payouts (
+  id VARCHAR(64) PRIMARY KEY,
+  user_id VARCHAR(64) NOT NULL,
+  bank_account_id VARCHAR(64) NOT NULL,
+  bank_name VARCHAR(16) NOT NULL,
+  bank_account_name VARCHAR(16) NOT NULL,
+  bank_account_number VARCHAR(16) NOT NULL,
+  amount BIGINT NOT NULL,
+  currency VARCHAR(3) NOT NULL,
+  status VARCHAR(20) NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_payouts_user_id ON payouts(user_id, created_at);
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS completed_at TIMESTAMPTZ;
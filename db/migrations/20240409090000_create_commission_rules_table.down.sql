@@ -0,0 +1,9 @@
+ALTER TABLE product_daily_sales DROP COLUMN IF EXISTS commission;
+
+ALTER TABLE orders DROP COLUMN IF EXISTS commission_amount;
+ALTER TABLE orders DROP COLUMN IF EXISTS commission_rate_basis_points;
+ALTER TABLE orders DROP COLUMN IF EXISTS commission_rule_id;
+
+ALTER TABLE products DROP COLUMN IF EXISTS category;
+
+DROP TABLE IF EXISTS commission_rules;
@@ -0,0 +1,48 @@
+CREATE TABLE IF NOT EXISTS commission_rules (
+  id VARCHAR(64) PRIMARY KEY,
+  scope VARCHAR(20) NOT NULL,
+  scope_value VARCHAR(64) NOT NULL DEFAULT '',
+  rate_basis_points INT NOT NULL,
+  currency VARCHAR(3),
+  min_amount BIGINT,
+  max_amount BIGINT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  UNIQUE (scope, scope_value)
+);
+
+ALTER TABLE products ADD COLUMN IF NOT EXISTS category VARCHAR(50);
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS commission_rule_id VARCHAR(64);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS commission_rate_basis_points INT NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS commission_amount BIGINT NOT NULL DEFAULT 0;
+
+-- orders made before commission rules were charged the flat 5% platform fee
+UPDATE orders
+SET
+  commission_rate_basis_points = 500,
+  commission_amount = ROUND((total - shipping_cost) * 500 / 10000.0);
+
+ALTER TABLE product_daily_sales ADD COLUMN IF NOT EXISTS commission BIGINT NOT NULL DEFAULT 0;
+
+UPDATE product_daily_sales s
+SET
+  commission = o.commission
+FROM
+  (
+    SELECT
+      product_id,
+      created_at::date AS day,
+      currency,
+      SUM(commission_amount) AS commission
+    FROM
+      orders
+    WHERE
+      status <> 'cancelled'
+    GROUP BY
+      product_id, created_at::date, currency
+  ) o
+WHERE
+  s.product_id = o.product_id
+  AND s.day = o.day
+  AND s.currency = o.currency;
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS invoice_counters (
+  seller_id VARCHAR(64) PRIMARY KEY,
+  last_sequence BIGINT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS invoices (
+  id VARCHAR(64) PRIMARY KEY,
+  order_id VARCHAR(64) NOT NULL UNIQUE,
+  seller_id VARCHAR(64) NOT NULL,
+  sequence BIGINT NOT NULL,
+  issued_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  UNIQUE (seller_id, sequence)
+);
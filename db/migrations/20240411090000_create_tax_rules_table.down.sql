@@ -0,0 +1,8 @@
+ALTER TABLE product_daily_sales DROP COLUMN IF EXISTS tax;
+
+ALTER TABLE orders DROP COLUMN IF EXISTS tax_amount;
+
+DROP INDEX IF EXISTS order_tax_lines_order_id_idx;
+DROP TABLE IF EXISTS order_tax_lines;
+
+DROP TABLE IF EXISTS tax_rules;
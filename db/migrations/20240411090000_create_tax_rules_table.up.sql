@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS tax_rules (
+  id VARCHAR(64) PRIMARY KEY,
+  name VARCHAR(30) NOT NULL,
+  rate_basis_points INT NOT NULL,
+  inclusive BOOLEAN NOT NULL DEFAULT FALSE,
+  currency VARCHAR(3),
+  exempt_categories TEXT[] NOT NULL DEFAULT '{}',
+  is_active BOOLEAN NOT NULL DEFAULT TRUE,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS order_tax_lines (
+  id VARCHAR(64) PRIMARY KEY,
+  order_id VARCHAR(64) NOT NULL,
+  tax_rule_id VARCHAR(64),
+  name VARCHAR(30) NOT NULL,
+  rate_basis_points INT NOT NULL,
+  inclusive BOOLEAN NOT NULL,
+  taxable_amount BIGINT NOT NULL,
+  amount BIGINT NOT NULL,
+  currency VARCHAR(3) NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS order_tax_lines_order_id_idx ON order_tax_lines (order_id);
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS tax_amount BIGINT NOT NULL DEFAULT 0;
+
+ALTER TABLE product_daily_sales ADD COLUMN IF NOT EXISTS tax BIGINT NOT NULL DEFAULT 0;
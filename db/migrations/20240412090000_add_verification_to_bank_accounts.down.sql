@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_bank_accounts_pending;
+
+ALTER TABLE bank_accounts DROP COLUMN IF EXISTS verified_at;
+ALTER TABLE bank_accounts DROP COLUMN IF EXISTS verification_note;
+ALTER TABLE bank_accounts DROP COLUMN IF EXISTS verification_status;
+ALTER TABLE bank_accounts DROP COLUMN IF EXISTS bank_code;
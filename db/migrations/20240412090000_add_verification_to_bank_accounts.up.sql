@@ -0,0 +1,42 @@
+ALTER TABLE bank_accounts ADD COLUMN IF NOT EXISTS bank_code VARCHAR(16);
+ALTER TABLE bank_accounts ADD COLUMN IF NOT EXISTS verification_status VARCHAR(16) NOT NULL DEFAULT 'unverified';
+ALTER TABLE bank_accounts ADD COLUMN IF NOT EXISTS verification_note VARCHAR(255);
+ALTER TABLE bank_accounts ADD COLUMN IF NOT EXISTS verified_at TIMESTAMP(0);
+
+-- accounts made before the bank registry are matched to it by their free-form bank name
+UPDATE bank_accounts
+SET
+  bank_code = CASE REPLACE(LOWER(bank_name), ' ', '')
+    WHEN 'bca' THEN 'bca'
+    WHEN 'bankbca' THEN 'bca'
+    WHEN 'mandiri' THEN 'mandiri'
+    WHEN 'bankmandiri' THEN 'mandiri'
+    WHEN 'bni' THEN 'bni'
+    WHEN 'bankbni' THEN 'bni'
+    WHEN 'bri' THEN 'bri'
+    WHEN 'bankbri' THEN 'bri'
+    WHEN 'cimb' THEN 'cimb'
+    WHEN 'cimbniaga' THEN 'cimb'
+    WHEN 'permata' THEN 'permata'
+    WHEN 'permatabank' THEN 'permata'
+    WHEN 'bankpermata' THEN 'permata'
+    WHEN 'danamon' THEN 'danamon'
+    WHEN 'bankdanamon' THEN 'danamon'
+    WHEN 'bsi' THEN 'bsi'
+    WHEN 'btn' THEN 'btn'
+    WHEN 'bankbtn' THEN 'btn'
+    WHEN 'jago' THEN 'jago'
+    WHEN 'bankjago' THEN 'jago'
+  END
+WHERE
+  bank_code IS NULL;
+
+-- accounts made before verification were already being paid into, so they stay payable
+UPDATE bank_accounts
+SET
+  verification_status = 'verified',
+  verified_at = NOW()
+WHERE
+  deleted_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_bank_accounts_pending ON bank_accounts(updated_at) WHERE verification_status = 'pending';
@@ -0,0 +1,8 @@
+ALTER TABLE orders DROP COLUMN IF EXISTS bank_account_number;
+ALTER TABLE orders DROP COLUMN IF EXISTS bank_account_name;
+ALTER TABLE orders DROP COLUMN IF EXISTS bank_name;
+ALTER TABLE orders DROP COLUMN IF EXISTS bank_code;
+
+DROP INDEX IF EXISTS idx_bank_accounts_default;
+
+ALTER TABLE bank_accounts DROP COLUMN IF EXISTS is_default;
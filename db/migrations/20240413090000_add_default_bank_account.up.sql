@@ -0,0 +1,38 @@
+ALTER TABLE bank_accounts ADD COLUMN IF NOT EXISTS is_default BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- each seller's most recent account becomes their default
+UPDATE bank_accounts b
+SET
+  is_default = TRUE
+FROM
+  (
+    SELECT DISTINCT ON (user_id)
+      id
+    FROM
+      bank_accounts
+    WHERE
+      deleted_at IS NULL
+    ORDER BY
+      user_id, created_at DESC
+  ) d
+WHERE
+  b.id = d.id;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_bank_accounts_default ON bank_accounts(user_id) WHERE is_default AND deleted_at IS NULL;
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS bank_code VARCHAR(16);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS bank_name VARCHAR(16) NOT NULL DEFAULT '';
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS bank_account_name VARCHAR(16) NOT NULL DEFAULT '';
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS bank_account_number VARCHAR(16) NOT NULL DEFAULT '';
+
+-- existing orders take the account as it is now, deleted accounts included
+UPDATE orders o
+SET
+  bank_code = b.bank_code,
+  bank_name = b.bank_name,
+  bank_account_name = b.bank_account_name,
+  bank_account_number = b.bank_account_number
+FROM
+  bank_accounts b
+WHERE
+  b.id = o.bank_account_id;
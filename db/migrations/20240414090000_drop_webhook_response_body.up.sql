@@ -0,0 +1,2 @@
+-- endpoint responses aren't kept anymore, they could contain anything the endpoint returned
+ALTER TABLE webhook_delivery_attempts DROP COLUMN IF EXISTS response_body;
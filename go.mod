@@ -15,6 +15,7 @@ require (
 	github.com/google/uuid v1.6.0
 	github.com/jmoiron/sqlx v1.3.5
 	github.com/joeshaw/envdecode v0.0.0-20200121155833-099f1fc765bd
+	github.com/jung-kurt/gofpdf v1.16.2
 	github.com/lib/pq v1.2.0
 	github.com/pkg/errors v0.9.1
 	golang.org/x/crypto v0.19.0
@@ -0,0 +1,385 @@
+package address
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/config"
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/model"
+	"github.com/ahmadnaufal/openidea-shopifyx/pkg/jwt"
+	"github.com/ahmadnaufal/openidea-shopifyx/pkg/validation"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+var (
+	AddressRepoImpl *AddressRepo
+	TrxProvider     *config.TransactionProvider
+)
+
+func RegisterRoute(r *fiber.App, jwtProvider jwt.JWTProvider) {
+	addressGroup := r.Group("/v1/address")
+	authMiddleware := jwtProvider.Middleware()
+	addressGroup.Use(authMiddleware)
+
+	addressGroup.Post("/", CreateAddress)
+	addressGroup.Get("/", ListAddresses)
+	addressGroup.Patch("/:address_id", UpdateAddress)
+	addressGroup.Delete("/:address_id", DeleteAddress)
+	addressGroup.Post("/:address_id/default", SetDefaultAddress)
+}
+
+func CreateAddress(c *fiber.Ctx) error {
+	var payload AddressRequest
+
+	claims, err := jwt.GetLoggedInUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "forbidden",
+		})
+	}
+
+	if err := c.BodyParser(&payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "invalid_request_body",
+		})
+	}
+
+	// validation for request body
+	if err := validation.Validate(payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "failed_request_body_validation",
+		})
+	}
+
+	ctx := c.Context()
+	address := Address{
+		ID:        uuid.NewString(),
+		UserID:    claims.UserID,
+		CreatedAt: time.Now(),
+	}
+	applyAddressRequest(&address, payload)
+
+	address, err = createAddress(ctx, address, payload.IsDefault)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(model.DataResponse{
+		Message: "success",
+		Data:    addressEntityToResponse(address),
+	})
+}
+
+// createAddress saves the address, making it the default if asked to or if it's the user's first address
+func createAddress(ctx context.Context, address Address, isDefault bool) (Address, error) {
+	tx, err := TrxProvider.NewTransaction(ctx)
+	if err != nil {
+		return Address{}, err
+	}
+	defer tx.Rollback()
+
+	hasAddress, err := AddressRepoImpl.HasAddress(ctx, tx, address.UserID)
+	if err != nil {
+		return Address{}, err
+	}
+
+	err = AddressRepoImpl.CreateAddress(ctx, tx, address)
+	if err != nil {
+		return Address{}, err
+	}
+
+	if isDefault || !hasAddress {
+		err = AddressRepoImpl.SetDefaultAddress(ctx, tx, address.UserID, address.ID)
+		if err != nil {
+			return Address{}, err
+		}
+		address.IsDefault = true
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return Address{}, err
+	}
+
+	return address, nil
+}
+
+func ListAddresses(c *fiber.Ctx) error {
+	claims, err := jwt.GetLoggedInUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "forbidden",
+		})
+	}
+
+	ctx := c.Context()
+	addresses, err := AddressRepoImpl.ListAddressesByUserID(ctx, claims.UserID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	addressResponses := []AddressResponse{}
+	for _, address := range addresses {
+		addressResponses = append(addressResponses, addressEntityToResponse(address))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(model.DataResponse{
+		Message: "success",
+		Data:    addressResponses,
+	})
+}
+
+func UpdateAddress(c *fiber.Ctx) error {
+	var payload AddressRequest
+	addressID := c.Params("address_id")
+
+	claims, err := jwt.GetLoggedInUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "forbidden",
+		})
+	}
+
+	if err := c.BodyParser(&payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "invalid_request_body",
+		})
+	}
+
+	// validation for request body
+	if err := validation.Validate(payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "failed_request_body_validation",
+		})
+	}
+
+	ctx := c.Context()
+	// check if the mentioned address exists
+	address, err := AddressRepoImpl.GetAddressByID(ctx, addressID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return c.Status(fiber.StatusNotFound).JSON(model.ErrorResponse{
+				Message: "address not found",
+				Code:    "entity_not_found",
+			})
+		}
+
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	// check address ownership
+	if address.UserID != claims.UserID {
+		return c.Status(fiber.StatusForbidden).JSON(model.ErrorResponse{
+			Message: "cannot update an address that is owned by another user",
+			Code:    "update_address_forbidden",
+		})
+	}
+
+	applyAddressRequest(&address, payload)
+	address, err = updateAddress(ctx, address, payload.IsDefault)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(model.DataResponse{
+		Message: "success",
+		Data:    addressEntityToResponse(address),
+	})
+}
+
+// updateAddress saves the address. An address can be made the default here, but not undone: another
+// address has to be made the default instead, so the user always has one.
+func updateAddress(ctx context.Context, address Address, isDefault bool) (Address, error) {
+	tx, err := TrxProvider.NewTransaction(ctx)
+	if err != nil {
+		return Address{}, err
+	}
+	defer tx.Rollback()
+
+	err = AddressRepoImpl.UpdateAddress(ctx, tx, address)
+	if err != nil {
+		return Address{}, err
+	}
+
+	if isDefault && !address.IsDefault {
+		err = AddressRepoImpl.SetDefaultAddress(ctx, tx, address.UserID, address.ID)
+		if err != nil {
+			return Address{}, err
+		}
+		address.IsDefault = true
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return Address{}, err
+	}
+
+	return address, nil
+}
+
+func DeleteAddress(c *fiber.Ctx) error {
+	addressID := c.Params("address_id")
+
+	claims, err := jwt.GetLoggedInUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "forbidden",
+		})
+	}
+
+	ctx := c.Context()
+	// check if the mentioned address exists
+	address, err := AddressRepoImpl.GetAddressByID(ctx, addressID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return c.Status(fiber.StatusNotFound).JSON(model.ErrorResponse{
+				Message: "address not found",
+				Code:    "entity_not_found",
+			})
+		}
+
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	// check address ownership
+	if address.UserID != claims.UserID {
+		return c.Status(fiber.StatusForbidden).JSON(model.ErrorResponse{
+			Message: "cannot delete an address that is owned by another user",
+			Code:    "update_address_forbidden",
+		})
+	}
+
+	err = deleteAddress(ctx, address)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(model.DataResponse{
+		Message: "success",
+	})
+}
+
+func deleteAddress(ctx context.Context, address Address) error {
+	tx, err := TrxProvider.NewTransaction(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// lock the user's addresses so the default is promoted consistently
+	_, err = AddressRepoImpl.HasAddress(ctx, tx, address.UserID)
+	if err != nil {
+		return err
+	}
+
+	err = AddressRepoImpl.DeleteAddress(ctx, tx, address)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func SetDefaultAddress(c *fiber.Ctx) error {
+	addressID := c.Params("address_id")
+
+	claims, err := jwt.GetLoggedInUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "forbidden",
+		})
+	}
+
+	ctx := c.Context()
+	// check if the mentioned address exists
+	address, err := AddressRepoImpl.GetAddressByID(ctx, addressID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return c.Status(fiber.StatusNotFound).JSON(model.ErrorResponse{
+				Message: "address not found",
+				Code:    "entity_not_found",
+			})
+		}
+
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	// check address ownership
+	if address.UserID != claims.UserID {
+		return c.Status(fiber.StatusForbidden).JSON(model.ErrorResponse{
+			Message: "cannot update an address that is owned by another user",
+			Code:    "update_address_forbidden",
+		})
+	}
+
+	address, err = updateAddress(ctx, address, true)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(model.DataResponse{
+		Message: "success",
+		Data:    addressEntityToResponse(address),
+	})
+}
+
+func applyAddressRequest(address *Address, payload AddressRequest) {
+	address.Label = payload.Label
+	address.RecipientName = payload.RecipientName
+	address.Phone = payload.Phone
+	address.AddressLine = payload.AddressLine
+	address.City = payload.City
+	address.Province = payload.Province
+	address.PostalCode = payload.PostalCode
+	address.Country = payload.Country
+}
+
+func addressEntityToResponse(address Address) AddressResponse {
+	return AddressResponse{
+		AddressID:     address.ID,
+		Label:         address.Label,
+		RecipientName: address.RecipientName,
+		Phone:         address.Phone,
+		AddressLine:   address.AddressLine,
+		City:          address.City,
+		Province:      address.Province,
+		PostalCode:    address.PostalCode,
+		Country:       address.Country,
+		IsDefault:     address.IsDefault,
+	}
+}
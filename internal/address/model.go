@@ -0,0 +1,32 @@
+package address
+
+import "time"
+
+type AddressRequest struct {
+	Label         string `json:"label" validate:"omitempty,max=30"`
+	RecipientName string `json:"recipientName" validate:"required,min=3,max=60"`
+	Phone         string `json:"phone" validate:"required,e164"`
+	AddressLine   string `json:"addressLine" validate:"required,min=5,max=255"`
+	City          string `json:"city" validate:"required,max=60"`
+	Province      string `json:"province" validate:"required,max=60"`
+	PostalCode    string `json:"postalCode" validate:"required,numeric,min=4,max=10"`
+	// Country is the ISO 3166-1 alpha-2 code, e.g. ID
+	Country   string `json:"country" validate:"required,iso3166_1_alpha2"`
+	IsDefault bool   `json:"isDefault"`
+}
+
+type Address struct {
+	ID            string     `db:"id"`
+	UserID        string     `db:"user_id"`
+	Label         string     `db:"label"`
+	RecipientName string     `db:"recipient_name"`
+	Phone         string     `db:"phone"`
+	AddressLine   string     `db:"address_line"`
+	City          string     `db:"city"`
+	Province      string     `db:"province"`
+	PostalCode    string     `db:"postal_code"`
+	Country       string     `db:"country"`
+	IsDefault     bool       `db:"is_default"`
+	CreatedAt     time.Time  `db:"created_at"`
+	DeletedAt     *time.Time `db:"deleted_at"`
+}
@@ -0,0 +1,292 @@
+package address
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+type AddressRepo struct {
+	db *sqlx.DB
+}
+
+func NewAddressRepo(db *sqlx.DB) AddressRepo {
+	return AddressRepo{db: db}
+}
+
+func (r AddressRepo) CreateAddress(ctx context.Context, tx *sql.Tx, address Address) error {
+	query := `
+		INSERT INTO addresses
+			(
+				id,
+				user_id,
+				label,
+				recipient_name,
+				phone,
+				address_line,
+				city,
+				province,
+				postal_code,
+				country,
+				is_default,
+				created_at
+			)
+		VALUES
+			(
+				:id,
+				:user_id,
+				:label,
+				:recipient_name,
+				:phone,
+				:address_line,
+				:city,
+				:province,
+				:postal_code,
+				:country,
+				:is_default,
+				:created_at
+			)
+	`
+
+	updatedQuery, args, err := sqlx.Named(query, address)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, sqlx.Rebind(sqlx.DOLLAR, updatedQuery), args...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ListAddressesByUserID lists the user's addresses, the default one first
+func (r AddressRepo) ListAddressesByUserID(ctx context.Context, userID string) ([]Address, error) {
+	var addresses []Address
+
+	query := `
+		SELECT
+			id,
+			user_id,
+			label,
+			recipient_name,
+			phone,
+			address_line,
+			city,
+			province,
+			postal_code,
+			country,
+			is_default,
+			created_at,
+			deleted_at
+		FROM
+			addresses
+		WHERE
+			user_id = $1
+			AND deleted_at IS NULL
+		ORDER BY
+			is_default DESC, created_at DESC
+	`
+
+	err := r.db.SelectContext(ctx, &addresses, query, userID)
+	if err != nil {
+		return addresses, err
+	}
+
+	return addresses, nil
+}
+
+func (r AddressRepo) GetAddressByID(ctx context.Context, addressID string) (Address, error) {
+	var result Address
+
+	query := `
+		SELECT
+			id,
+			user_id,
+			label,
+			recipient_name,
+			phone,
+			address_line,
+			city,
+			province,
+			postal_code,
+			country,
+			is_default,
+			created_at,
+			deleted_at
+		FROM
+			addresses
+		WHERE
+			id = $1
+			AND deleted_at IS NULL
+		LIMIT 1
+	`
+
+	err := r.db.GetContext(ctx, &result, query, addressID)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// HasAddress checks whether the user has any address left, locking them so concurrent requests
+// agree on which address is the default
+func (r AddressRepo) HasAddress(ctx context.Context, tx *sql.Tx, userID string) (bool, error) {
+	query := `
+		SELECT
+			id
+		FROM
+			addresses
+		WHERE
+			user_id = $1
+			AND deleted_at IS NULL
+		FOR UPDATE
+	`
+
+	rows, err := tx.QueryContext(ctx, query, userID)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	hasAddress := rows.Next()
+	return hasAddress, rows.Err()
+}
+
+func (r AddressRepo) UpdateAddress(ctx context.Context, tx *sql.Tx, address Address) error {
+	query := `
+		UPDATE
+			addresses
+		SET
+			label = :label,
+			recipient_name = :recipient_name,
+			phone = :phone,
+			address_line = :address_line,
+			city = :city,
+			province = :province,
+			postal_code = :postal_code,
+			country = :country
+		WHERE
+			id = :id
+			AND deleted_at IS NULL
+	`
+
+	updatedQuery, args, err := sqlx.Named(query, address)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, sqlx.Rebind(sqlx.DOLLAR, updatedQuery), args...)
+	if err != nil {
+		return err
+	}
+
+	affectedRows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affectedRows != 1 {
+		return errors.New("error affected row count is not equal to 1")
+	}
+
+	return nil
+}
+
+// SetDefaultAddress makes the address the user's only default address
+func (r AddressRepo) SetDefaultAddress(ctx context.Context, tx *sql.Tx, userID, addressID string) error {
+	clearQuery := `
+		UPDATE
+			addresses
+		SET
+			is_default = FALSE
+		WHERE
+			user_id = $1
+			AND is_default = TRUE
+	`
+
+	_, err := tx.ExecContext(ctx, clearQuery, userID)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE
+			addresses
+		SET
+			is_default = TRUE
+		WHERE
+			id = $1
+			AND user_id = $2
+			AND deleted_at IS NULL
+	`
+
+	result, err := tx.ExecContext(ctx, query, addressID, userID)
+	if err != nil {
+		return err
+	}
+
+	affectedRows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affectedRows != 1 {
+		return errors.New("error affected row count is not equal to 1")
+	}
+
+	return nil
+}
+
+// DeleteAddress soft-deletes the address, as orders keep their own copy of the address they were sent to.
+// If it was the default, the most recently added remaining address becomes the default.
+func (r AddressRepo) DeleteAddress(ctx context.Context, tx *sql.Tx, address Address) error {
+	query := `
+		UPDATE
+			addresses
+		SET
+			deleted_at = NOW(),
+			is_default = FALSE
+		WHERE
+			id = $1
+			AND deleted_at IS NULL
+	`
+
+	_, err := tx.ExecContext(ctx, query, address.ID)
+	if err != nil {
+		return err
+	}
+
+	if !address.IsDefault {
+		return nil
+	}
+
+	promoteQuery := `
+		UPDATE
+			addresses
+		SET
+			is_default = TRUE
+		WHERE
+			id = (
+				SELECT
+					id
+				FROM
+					addresses
+				WHERE
+					user_id = $1
+					AND deleted_at IS NULL
+				ORDER BY
+					created_at DESC
+				LIMIT 1
+			)
+	`
+
+	_, err = tx.ExecContext(ctx, promoteQuery, address.UserID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
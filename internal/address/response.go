@@ -0,0 +1,14 @@
+package address
+
+type AddressResponse struct {
+	AddressID     string `json:"addressId"`
+	Label         string `json:"label"`
+	RecipientName string `json:"recipientName"`
+	Phone         string `json:"phone"`
+	AddressLine   string `json:"addressLine"`
+	City          string `json:"city"`
+	Province      string `json:"province"`
+	PostalCode    string `json:"postalCode"`
+	Country       string `json:"country"`
+	IsDefault     bool   `json:"isDefault"`
+}
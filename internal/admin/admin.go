@@ -0,0 +1,38 @@
+package admin
+
+import (
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/model"
+	"github.com/ahmadnaufal/openidea-shopifyx/pkg/jwt"
+	"github.com/gofiber/fiber/v2"
+)
+
+var (
+	// UserIDs are the users allowed to use the admin endpoints
+	UserIDs []string
+)
+
+// IsAdmin tells whether the user is one of the admins
+func IsAdmin(userID string) bool {
+	for _, adminUserID := range UserIDs {
+		if adminUserID == userID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Middleware only lets the admins through. It must be registered after the auth middleware.
+func Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims, err := jwt.GetLoggedInUser(c)
+		if err != nil || !IsAdmin(claims.UserID) {
+			return c.Status(fiber.StatusForbidden).JSON(model.ErrorResponse{
+				Message: "only admins can access this resource",
+				Code:    "forbidden",
+			})
+		}
+
+		return c.Next()
+	}
+}
@@ -0,0 +1,211 @@
+package analytics
+
+import (
+	"time"
+
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/config"
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/model"
+	"github.com/ahmadnaufal/openidea-shopifyx/pkg/jwt"
+	"github.com/ahmadnaufal/openidea-shopifyx/pkg/money"
+	"github.com/ahmadnaufal/openidea-shopifyx/pkg/validation"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	topProductsLimit = 5
+	// maxRangeDays limits how far back a single analytics request can go
+	maxRangeDays = 366
+)
+
+var (
+	AnalyticsRepoImpl *AnalyticsRepo
+	TrxProvider       *config.TransactionProvider
+
+	// OrderCancellationWindow is how long after being made an order can still be cancelled
+	OrderCancellationWindow time.Duration
+)
+
+func RegisterRoute(r *fiber.App, jwtProvider jwt.JWTProvider) {
+	sellerGroup := r.Group("/v1/seller/me")
+	authMiddleware := jwtProvider.Middleware()
+	sellerGroup.Use(authMiddleware)
+
+	sellerGroup.Get("/analytics", GetSellerAnalytics)
+}
+
+func GetSellerAnalytics(c *fiber.Ctx) error {
+	claims, err := jwt.GetLoggedInUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "forbidden",
+		})
+	}
+
+	var req SellerAnalyticsRequest
+	if err := c.QueryParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "invalid_request_body",
+		})
+	}
+
+	if err := validation.Validate(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "failed_request_body_validation",
+		})
+	}
+
+	// by default, show the last 30 days by day
+	to := time.Now()
+	if req.To != "" {
+		to, _ = time.Parse(dateLayout, req.To)
+	}
+	from := to.AddDate(0, 0, -29)
+	if req.From != "" {
+		from, _ = time.Parse(dateLayout, req.From)
+	}
+	granularity := req.Granularity
+	if granularity == "" {
+		granularity = GranularityDay
+	}
+
+	if to.Before(from) || to.Sub(from) > maxRangeDays*24*time.Hour {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: "invalid date range",
+			Code:    "failed_request_body_validation",
+		})
+	}
+
+	ctx := c.Context()
+	sellerID := claims.UserID
+
+	salesPeriods, err := AnalyticsRepoImpl.GetSalesByPeriod(ctx, sellerID, from, to, granularity)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	topProducts, err := AnalyticsRepoImpl.GetTopProducts(ctx, sellerID, from, to, topProductsLimit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	conversion, err := AnalyticsRepoImpl.GetConversion(ctx, sellerID, from, to)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	repeatBuyers, err := AnalyticsRepoImpl.GetRepeatBuyers(ctx, sellerID, from, to)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	// revenue can't be summed across currencies, so the totals are grouped per currency
+	salesResponses := []SalesPeriodResponse{}
+	totalsByCurrency := map[string]*SalesTotalResponse{}
+	totalResponses := []SalesTotalResponse{}
+	currencies := []string{}
+	for _, period := range salesPeriods {
+		revenue := money.New(period.Revenue, money.Currency(period.Currency))
+		commission := money.New(period.Commission, revenue.Currency)
+		tax := money.New(period.Tax, revenue.Currency)
+		salesResponses = append(salesResponses, SalesPeriodResponse{
+			Period:     period.Period.Format(dateLayout),
+			Revenue:    revenue,
+			Commission: commission,
+			Tax:        tax,
+			Units:      period.Units,
+			Orders:     period.Orders,
+		})
+
+		total, ok := totalsByCurrency[period.Currency]
+		if !ok {
+			total = &SalesTotalResponse{
+				Revenue:    money.New(0, revenue.Currency),
+				Commission: money.New(0, revenue.Currency),
+				Tax:        money.New(0, revenue.Currency),
+			}
+			totalsByCurrency[period.Currency] = total
+			currencies = append(currencies, period.Currency)
+		}
+		total.Revenue, err = total.Revenue.Add(revenue)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+				Message: "something wrong with the server. Please contact admin",
+				Code:    "internal_server_error",
+			})
+		}
+		total.Commission, err = total.Commission.Add(commission)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+				Message: "something wrong with the server. Please contact admin",
+				Code:    "internal_server_error",
+			})
+		}
+		total.Tax, err = total.Tax.Add(tax)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+				Message: "something wrong with the server. Please contact admin",
+				Code:    "internal_server_error",
+			})
+		}
+		total.Units += period.Units
+		total.Orders += period.Orders
+	}
+	for _, currency := range currencies {
+		totalResponses = append(totalResponses, *totalsByCurrency[currency])
+	}
+
+	topProductResponses := []TopProductResponse{}
+	for _, product := range topProducts {
+		topProductResponses = append(topProductResponses, TopProductResponse{
+			ProductID: product.ProductID,
+			Name:      product.Name,
+			Units:     product.Units,
+			Revenue:   money.New(product.Revenue, money.Currency(product.Currency)),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(model.DataResponse{
+		Message: "ok",
+		Data: SellerAnalyticsResponse{
+			From:        from.Format(dateLayout),
+			To:          to.Format(dateLayout),
+			Granularity: granularity,
+			Sales:       salesResponses,
+			Totals:      totalResponses,
+			TopProducts: topProductResponses,
+			Conversion: ConversionResponse{
+				Views:  conversion.Views,
+				Orders: conversion.Orders,
+				Rate:   ratio(conversion.Orders, conversion.Views),
+			},
+			RepeatBuyers: RepeatBuyersResponse{
+				Buyers:       repeatBuyers.Buyers,
+				RepeatBuyers: repeatBuyers.RepeatBuyers,
+				Rate:         ratio(repeatBuyers.RepeatBuyers, repeatBuyers.Buyers),
+			},
+		},
+	})
+}
+
+func ratio(numerator, denominator int64) float64 {
+	if denominator == 0 {
+		return 0
+	}
+
+	return float64(numerator) / float64(denominator)
+}
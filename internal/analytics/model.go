@@ -0,0 +1,47 @@
+package analytics
+
+import "time"
+
+const (
+	GranularityDay   = "day"
+	GranularityWeek  = "week"
+	GranularityMonth = "month"
+
+	dateLayout = "2006-01-02"
+)
+
+type SellerAnalyticsRequest struct {
+	From        string `query:"from" validate:"omitempty,datetime=2006-01-02"`
+	To          string `query:"to" validate:"omitempty,datetime=2006-01-02"`
+	Granularity string `query:"granularity" validate:"omitempty,oneof=day week month"`
+}
+
+type SalesPeriod struct {
+	Period   time.Time `db:"period"`
+	Currency string    `db:"currency"`
+	Revenue  int64     `db:"revenue"`
+	// Commission is the platform's part of the revenue
+	Commission int64 `db:"commission"`
+	// Tax is what the buyers paid in tax on top of the revenue
+	Tax    int64 `db:"tax"`
+	Units  int64 `db:"units"`
+	Orders int64 `db:"orders"`
+}
+
+type TopProduct struct {
+	ProductID string `db:"product_id"`
+	Name      string `db:"name"`
+	Currency  string `db:"currency"`
+	Units     int64  `db:"units"`
+	Revenue   int64  `db:"revenue"`
+}
+
+type Conversion struct {
+	Views  int64 `db:"views"`
+	Orders int64 `db:"orders"`
+}
+
+type RepeatBuyers struct {
+	Buyers       int64 `db:"buyers"`
+	RepeatBuyers int64 `db:"repeat_buyers"`
+}
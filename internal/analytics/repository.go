@@ -0,0 +1,280 @@
+package analytics
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const salesRefreshStateName = "sales"
+
+type AnalyticsRepo struct {
+	db *sqlx.DB
+}
+
+func NewAnalyticsRepo(db *sqlx.DB) AnalyticsRepo {
+	return AnalyticsRepo{db: db}
+}
+
+// RecordUniqueProductView counts the view in the daily views, unless the same viewer has already
+// viewed the product in the window starting at windowStart
+func (r AnalyticsRepo) RecordUniqueProductView(ctx context.Context, view ProductView, windowStart time.Time) error {
+	query := `
+		WITH inserted AS (
+			INSERT INTO product_view_dedups
+				(product_id, viewer_key, window_start)
+			VALUES
+				($1, $2, $3)
+			ON CONFLICT DO NOTHING
+			RETURNING
+				product_id
+		)
+		INSERT INTO product_daily_views
+			(product_id, seller_id, day, views)
+		SELECT
+			$1, $4, $5::date, 1
+		FROM
+			inserted
+		ON CONFLICT (product_id, day) DO UPDATE
+		SET
+			views = product_daily_views.views + 1
+	`
+
+	_, err := r.db.ExecContext(ctx, query, view.ProductID, view.ViewerKey, windowStart, view.SellerID, view.ViewedAt)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r AnalyticsRepo) DeleteProductViewDedups(ctx context.Context, windowStartBefore time.Time) error {
+	query := `
+		DELETE FROM
+			product_view_dedups
+		WHERE
+			window_start < $1
+	`
+
+	_, err := r.db.ExecContext(ctx, query, windowStartBefore)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetRefreshedUntil returns the time up to which the sales aggregates have been refreshed, locking the
+// refresh state so only one instance refreshes at a time
+func (r AnalyticsRepo) GetRefreshedUntil(ctx context.Context, tx *sql.Tx) (time.Time, error) {
+	query := `
+		INSERT INTO analytics_refresh_states
+			(name, refreshed_until)
+		VALUES
+			($1, TO_TIMESTAMP(0))
+		ON CONFLICT (name) DO UPDATE
+		SET
+			name = EXCLUDED.name
+		RETURNING
+			refreshed_until
+	`
+
+	var refreshedUntil time.Time
+	err := tx.QueryRowContext(ctx, query, salesRefreshStateName).Scan(&refreshedUntil)
+	if err != nil {
+		return refreshedUntil, err
+	}
+
+	return refreshedUntil, nil
+}
+
+func (r AnalyticsRepo) SetRefreshedUntil(ctx context.Context, tx *sql.Tx, refreshedUntil time.Time) error {
+	query := `
+		UPDATE
+			analytics_refresh_states
+		SET
+			refreshed_until = $2
+		WHERE
+			name = $1
+	`
+
+	_, err := tx.ExecContext(ctx, query, salesRefreshStateName, refreshedUntil)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// RebuildSalesAggregates recomputes the daily sales aggregates from the orders table for every day
+// starting from the day of from. Recomputing whole days keeps the refresh idempotent.
+func (r AnalyticsRepo) RebuildSalesAggregates(ctx context.Context, tx *sql.Tx, from time.Time) error {
+	queries := []string{
+		`DELETE FROM product_daily_sales WHERE day >= $1::date`,
+		`
+		INSERT INTO product_daily_sales
+			(product_id, seller_id, day, currency, units, orders, revenue, commission, tax)
+		SELECT
+			product_id,
+			seller_id,
+			created_at::date AS day,
+			currency,
+			SUM(quantity) AS units,
+			COUNT(*) AS orders,
+			-- shipping is passed on to the courier and taxes to the tax office, so they aren't part of the revenue
+			SUM(total - shipping_cost - tax_amount) AS revenue,
+			SUM(commission_amount) AS commission,
+			SUM(tax_amount) AS tax
+		FROM
+			orders
+		WHERE
+			created_at >= $1::date
+			AND status <> 'cancelled'
+		GROUP BY
+			product_id, seller_id, created_at::date, currency
+		`,
+		`DELETE FROM seller_daily_buyers WHERE day >= $1::date`,
+		`
+		INSERT INTO seller_daily_buyers
+			(seller_id, buyer_id, day, orders)
+		SELECT
+			seller_id,
+			user_id AS buyer_id,
+			created_at::date AS day,
+			COUNT(*) AS orders
+		FROM
+			orders
+		WHERE
+			created_at >= $1::date
+			AND status <> 'cancelled'
+		GROUP BY
+			seller_id, user_id, created_at::date
+		`,
+	}
+
+	for _, query := range queries {
+		_, err := tx.ExecContext(ctx, query, from)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r AnalyticsRepo) GetSalesByPeriod(ctx context.Context, sellerID string, from, to time.Time, granularity string) ([]SalesPeriod, error) {
+	var result []SalesPeriod
+
+	query := `
+		SELECT
+			DATE_TRUNC($4, day) AS period,
+			currency,
+			SUM(revenue) AS revenue,
+			SUM(commission) AS commission,
+			SUM(tax) AS tax,
+			SUM(units) AS units,
+			SUM(orders) AS orders
+		FROM
+			product_daily_sales
+		WHERE
+			seller_id = $1
+			AND day BETWEEN $2::date AND $3::date
+		GROUP BY
+			1, 2
+		ORDER BY
+			1 ASC, 2 ASC
+	`
+
+	err := r.db.SelectContext(ctx, &result, query, sellerID, from, to, granularity)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r AnalyticsRepo) GetTopProducts(ctx context.Context, sellerID string, from, to time.Time, limit int) ([]TopProduct, error) {
+	var result []TopProduct
+
+	query := `
+		SELECT
+			s.product_id,
+			COALESCE(p.name, '') AS name,
+			s.currency,
+			SUM(s.units) AS units,
+			SUM(s.revenue) AS revenue
+		FROM
+			product_daily_sales s
+			LEFT JOIN products p
+			ON p.id = s.product_id
+		WHERE
+			s.seller_id = $1
+			AND s.day BETWEEN $2::date AND $3::date
+		GROUP BY
+			s.product_id, p.name, s.currency
+		ORDER BY
+			units DESC, revenue DESC
+		LIMIT $4
+	`
+
+	err := r.db.SelectContext(ctx, &result, query, sellerID, from, to, limit)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r AnalyticsRepo) GetConversion(ctx context.Context, sellerID string, from, to time.Time) (Conversion, error) {
+	var result Conversion
+
+	query := `
+		SELECT
+			(
+				SELECT COALESCE(SUM(views), 0) FROM product_daily_views
+				WHERE seller_id = $1 AND day BETWEEN $2::date AND $3::date
+			) AS views,
+			(
+				SELECT COALESCE(SUM(orders), 0) FROM product_daily_sales
+				WHERE seller_id = $1 AND day BETWEEN $2::date AND $3::date
+			) AS orders
+	`
+
+	err := r.db.GetContext(ctx, &result, query, sellerID, from, to)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r AnalyticsRepo) GetRepeatBuyers(ctx context.Context, sellerID string, from, to time.Time) (RepeatBuyers, error) {
+	var result RepeatBuyers
+
+	query := `
+		SELECT
+			COUNT(*) AS buyers,
+			COUNT(*) FILTER (WHERE orders >= 2) AS repeat_buyers
+		FROM (
+			SELECT
+				buyer_id,
+				SUM(orders) AS orders
+			FROM
+				seller_daily_buyers
+			WHERE
+				seller_id = $1
+				AND day BETWEEN $2::date AND $3::date
+			GROUP BY
+				buyer_id
+		) AS buyer_orders
+	`
+
+	err := r.db.GetContext(ctx, &result, query, sellerID, from, to)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
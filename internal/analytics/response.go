@@ -0,0 +1,50 @@
+package analytics
+
+import "github.com/ahmadnaufal/openidea-shopifyx/pkg/money"
+
+type SellerAnalyticsResponse struct {
+	From         string                `json:"from"`
+	To           string                `json:"to"`
+	Granularity  string                `json:"granularity"`
+	Sales        []SalesPeriodResponse `json:"sales"`
+	Totals       []SalesTotalResponse  `json:"totals"`
+	TopProducts  []TopProductResponse  `json:"topProducts"`
+	Conversion   ConversionResponse    `json:"conversion"`
+	RepeatBuyers RepeatBuyersResponse  `json:"repeatBuyers"`
+}
+
+type SalesPeriodResponse struct {
+	Period     string      `json:"period"`
+	Revenue    money.Money `json:"revenue"`
+	Commission money.Money `json:"commission"`
+	Tax        money.Money `json:"tax"`
+	Units      int64       `json:"units"`
+	Orders     int64       `json:"orders"`
+}
+
+type SalesTotalResponse struct {
+	Revenue    money.Money `json:"revenue"`
+	Commission money.Money `json:"commission"`
+	Tax        money.Money `json:"tax"`
+	Units      int64       `json:"units"`
+	Orders     int64       `json:"orders"`
+}
+
+type TopProductResponse struct {
+	ProductID string      `json:"productId"`
+	Name      string      `json:"name"`
+	Units     int64       `json:"units"`
+	Revenue   money.Money `json:"revenue"`
+}
+
+type ConversionResponse struct {
+	Views  int64   `json:"views"`
+	Orders int64   `json:"orders"`
+	Rate   float64 `json:"rate"`
+}
+
+type RepeatBuyersResponse struct {
+	Buyers       int64   `json:"buyers"`
+	RepeatBuyers int64   `json:"repeatBuyers"`
+	Rate         float64 `json:"rate"`
+}
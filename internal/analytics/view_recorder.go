@@ -0,0 +1,67 @@
+package analytics
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+const viewDedupCleanupInterval = time.Hour
+
+type ProductView struct {
+	ProductID string
+	SellerID  string
+	// ViewerKey identifies the viewer for deduplication, e.g. the user ID or a hash of the client's address
+	ViewerKey string
+	ViewedAt  time.Time
+}
+
+// ViewRecorder records product views in the background so the request that triggered them isn't slowed down.
+// Repeated views by the same viewer within the dedup window are only counted once.
+type ViewRecorder struct {
+	views       chan ProductView
+	dedupWindow time.Duration
+}
+
+func NewViewRecorder(bufferSize int, dedupWindow time.Duration) *ViewRecorder {
+	return &ViewRecorder{
+		views:       make(chan ProductView, bufferSize),
+		dedupWindow: dedupWindow,
+	}
+}
+
+// Record queues a view to be recorded. It never blocks: when the buffer is full the view is dropped
+func (r *ViewRecorder) Record(view ProductView) {
+	select {
+	case r.views <- view:
+	default:
+		log.Printf("view recorder buffer is full, dropping view of product %s", view.ProductID)
+	}
+}
+
+// Run records the queued views until ctx is cancelled, so it should be run in its own goroutine
+func (r *ViewRecorder) Run(ctx context.Context) {
+	cleanupTicker := time.NewTicker(viewDedupCleanupInterval)
+	defer cleanupTicker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case view := <-r.views:
+			windowStart := view.ViewedAt.Truncate(r.dedupWindow)
+			err := AnalyticsRepoImpl.RecordUniqueProductView(ctx, view, windowStart)
+			if err != nil {
+				log.Printf("error recording product view: %v", err)
+			}
+
+		case <-cleanupTicker.C:
+			// dedup entries are only needed while their window is still open
+			err := AnalyticsRepoImpl.DeleteProductViewDedups(ctx, time.Now().Add(-r.dedupWindow))
+			if err != nil {
+				log.Printf("error cleaning up product view dedups: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package analytics
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// refreshLookback re-aggregates a bit before the last refresh, so orders committed late with
+// an earlier created_at are still counted
+const refreshLookback = 10 * time.Minute
+
+// RunRefreshWorker periodically refreshes the pre-aggregated sales tables. It blocks until ctx is
+// cancelled, so it should be run in its own goroutine.
+func RunRefreshWorker(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		err := refreshSalesAggregates(ctx)
+		if err != nil {
+			log.Printf("error refreshing sales aggregates: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func refreshSalesAggregates(ctx context.Context) error {
+	tx, err := TrxProvider.NewTransaction(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	refreshedUntil, err := AnalyticsRepoImpl.GetRefreshedUntil(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	// the aggregates are rebuilt from the start of the day containing this time. Unpaid orders can
+	// still be cancelled until their payment deadline, so the days they're in are rebuilt as well.
+	err = AnalyticsRepoImpl.RebuildSalesAggregates(ctx, tx, refreshedUntil.Add(-refreshLookback-OrderCancellationWindow))
+	if err != nil {
+		return err
+	}
+
+	err = AnalyticsRepoImpl.SetRefreshedUntil(ctx, tx, now)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
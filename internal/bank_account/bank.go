@@ -0,0 +1,68 @@
+package bankaccount
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Bank is a bank the sellers can be paid into, with the shape of its account numbers
+type Bank struct {
+	Code string
+	Name string
+	// MinLength and MaxLength bound the number of digits of the bank's account numbers
+	MinLength int
+	MaxLength int
+}
+
+// banks is the registry of supported banks, keyed by their code
+var banks = map[string]Bank{
+	"bca":     {Code: "bca", Name: "BCA", MinLength: 10, MaxLength: 10},
+	"mandiri": {Code: "mandiri", Name: "Bank Mandiri", MinLength: 13, MaxLength: 13},
+	"bni":     {Code: "bni", Name: "BNI", MinLength: 10, MaxLength: 10},
+	"bri":     {Code: "bri", Name: "BRI", MinLength: 15, MaxLength: 15},
+	"cimb":    {Code: "cimb", Name: "CIMB Niaga", MinLength: 13, MaxLength: 14},
+	"permata": {Code: "permata", Name: "Permata Bank", MinLength: 10, MaxLength: 10},
+	"danamon": {Code: "danamon", Name: "Bank Danamon", MinLength: 10, MaxLength: 10},
+	"bsi":     {Code: "bsi", Name: "BSI", MinLength: 10, MaxLength: 10},
+	"btn":     {Code: "btn", Name: "BTN", MinLength: 16, MaxLength: 16},
+	"jago":    {Code: "jago", Name: "Bank Jago", MinLength: 12, MaxLength: 12},
+}
+
+// GetBank returns the registered bank with the given code
+func GetBank(code string) (Bank, bool) {
+	bank, ok := banks[strings.ToLower(strings.TrimSpace(code))]
+	return bank, ok
+}
+
+// RegisteredBanks returns the registered banks, sorted by name
+func RegisteredBanks() []Bank {
+	result := make([]Bank, 0, len(banks))
+	for _, bank := range banks {
+		result = append(result, bank)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+
+	return result
+}
+
+// ValidateAccountNumber checks the account number has the shape of the bank's account numbers
+func (b Bank) ValidateAccountNumber(number string) error {
+	for _, r := range number {
+		if r < '0' || r > '9' {
+			return fmt.Errorf("%s account numbers must only contain digits", b.Name)
+		}
+	}
+
+	if len(number) < b.MinLength || len(number) > b.MaxLength {
+		if b.MinLength == b.MaxLength {
+			return fmt.Errorf("%s account numbers must be %d digits long", b.Name, b.MinLength)
+		}
+		return fmt.Errorf("%s account numbers must be %d to %d digits long", b.Name, b.MinLength, b.MaxLength)
+	}
+
+	return nil
+}
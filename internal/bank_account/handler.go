@@ -1,8 +1,14 @@
 package bankaccount
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"time"
 
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/admin"
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/config"
 	"github.com/ahmadnaufal/openidea-shopifyx/internal/model"
 	"github.com/ahmadnaufal/openidea-shopifyx/pkg/jwt"
 	"github.com/ahmadnaufal/openidea-shopifyx/pkg/validation"
@@ -12,9 +18,22 @@ import (
 
 var (
 	BankAccountRepoImpl *BankAccountRepo
+	TrxProvider         *config.TransactionProvider
+	VerifierImpl        Verifier
+
+	// ProductsUnpurchasableHandler takes all the seller's products off sale in the given transaction.
+	// It is provided by the products' owner, so this package doesn't depend on it.
+	ProductsUnpurchasableHandler func(ctx context.Context, tx *sql.Tx, sellerID string) (int, error)
 )
 
+// adminPendingListLimit is how many pending accounts admins are shown at once
+const adminPendingListLimit = 100
+
+var errLastBankAccount = errors.New("cannot delete the last verified bank account while there are purchasable products")
+
 func RegisterRoute(r *fiber.App, jwtProvider jwt.JWTProvider) {
+	r.Get("/v1/bank", ListBanks)
+
 	bankAccountGroup := r.Group("/v1/bank/account")
 	authMiddleware := jwtProvider.Middleware()
 	bankAccountGroup.Use(authMiddleware)
@@ -23,6 +42,31 @@ func RegisterRoute(r *fiber.App, jwtProvider jwt.JWTProvider) {
 	bankAccountGroup.Get("/", ListBankAccounts)
 	bankAccountGroup.Patch("/:bank_account_id", UpdateBankAccount)
 	bankAccountGroup.Delete("/:bank_account_id", DeleteBankAccount)
+	bankAccountGroup.Post("/:bank_account_id/verify", RequestVerification)
+	bankAccountGroup.Post("/:bank_account_id/default", SetDefaultBankAccount)
+
+	adminGroup := r.Group("/v1/admin/bank/account", authMiddleware, admin.Middleware())
+	adminGroup.Get("/pending", ListPendingBankAccounts)
+	adminGroup.Post("/:bank_account_id/verification", VerifyBankAccount)
+}
+
+func ListBanks(c *fiber.Ctx) error {
+	registeredBanks := RegisteredBanks()
+
+	bankResponses := make([]BankResponse, len(registeredBanks))
+	for i, bank := range registeredBanks {
+		bankResponses[i] = BankResponse{
+			Code:      bank.Code,
+			Name:      bank.Name,
+			MinLength: bank.MinLength,
+			MaxLength: bank.MaxLength,
+		}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(model.DataResponse{
+		Message: "success",
+		Data:    bankResponses,
+	})
 }
 
 func CreateBankAccount(c *fiber.Ctx) error {
@@ -51,17 +95,27 @@ func CreateBankAccount(c *fiber.Ctx) error {
 		})
 	}
 
+	bank, err := validateBankAccountRequest(payload)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "failed_request_body_validation",
+		})
+	}
+
 	ctx := c.Context()
 	bankAccountID := uuid.NewString()
 	bankAccount := BankAccount{
-		ID:                bankAccountID,
-		UserID:            claims.UserID,
-		BankName:          payload.BankName,
-		BankAccountName:   payload.BankAccountName,
-		BankAccountNumber: payload.BankAccountNumber,
+		ID:                 bankAccountID,
+		UserID:             claims.UserID,
+		BankCode:           &bank.Code,
+		BankName:           bank.Name,
+		BankAccountName:    payload.BankAccountName,
+		BankAccountNumber:  payload.BankAccountNumber,
+		VerificationStatus: VerificationStatusPending,
 	}
 	// save data to db
-	err = BankAccountRepoImpl.CreateBankAccount(ctx, bankAccount)
+	bankAccount.IsDefault, err = BankAccountRepoImpl.CreateBankAccount(ctx, bankAccount)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
 			Message: "something wrong with the server. Please contact admin",
@@ -131,6 +185,14 @@ func UpdateBankAccount(c *fiber.Ctx) error {
 		})
 	}
 
+	bank, err := validateBankAccountRequest(payload)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "failed_request_body_validation",
+		})
+	}
+
 	ctx := c.Context()
 	// check if the mentioned bank account exists
 	bankAccount, err := BankAccountRepoImpl.GetBankAccountByID(ctx, bankAccountID)
@@ -156,8 +218,19 @@ func UpdateBankAccount(c *fiber.Ctx) error {
 		})
 	}
 
+	// changed details have to be verified again before buyers can pay into the account
+	changed := bankAccount.BankCode == nil || *bankAccount.BankCode != bank.Code ||
+		bankAccount.BankAccountName != payload.BankAccountName ||
+		bankAccount.BankAccountNumber != payload.BankAccountNumber
+	if changed {
+		bankAccount.VerificationStatus = VerificationStatusPending
+		bankAccount.VerificationNote = nil
+		bankAccount.VerifiedAt = nil
+	}
+
 	// save data to db
-	bankAccount.BankName = payload.BankName
+	bankAccount.BankCode = &bank.Code
+	bankAccount.BankName = bank.Name
 	bankAccount.BankAccountName = payload.BankAccountName
 	bankAccount.BankAccountNumber = payload.BankAccountNumber
 	err = BankAccountRepoImpl.UpdateBankAccount(ctx, bankAccount)
@@ -210,25 +283,368 @@ func DeleteBankAccount(c *fiber.Ctx) error {
 		})
 	}
 
+	var payload DeleteBankAccountRequest
+	if err := c.QueryParser(&payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "invalid_request_body",
+		})
+	}
+
 	// delete bank account
-	err = BankAccountRepoImpl.DeleteBankAccount(ctx, bankAccount.ID)
+	err = deleteBankAccount(ctx, bankAccount, payload.MarkUnpurchasable)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return c.Status(fiber.StatusNotFound).JSON(model.ErrorResponse{
+				Message: "bank account not found",
+				Code:    "entity_not_found",
+			})
+		}
+
+		if err == errLastBankAccount {
+			return c.Status(fiber.StatusConflict).JSON(model.ErrorResponse{
+				Message: err.Error(),
+				Code:    "last_bank_account",
+			})
+		}
+
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(model.DataResponse{
+		Message: "success",
+	})
+}
+
+// deleteBankAccount deletes the account, handing the default over to another of the seller's accounts.
+// The seller's accounts are locked throughout, so two deletions at once can't both think they
+// aren't deleting the last account.
+func deleteBankAccount(ctx context.Context, bankAccount BankAccount, markUnpurchasable bool) error {
+	tx, err := TrxProvider.NewTransaction(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	bankAccounts, err := BankAccountRepoImpl.LockBankAccountsByUserID(ctx, tx, bankAccount.UserID)
+	if err != nil {
+		return err
+	}
+
+	// the account may have been deleted while waiting for the lock
+	var remaining []BankAccount
+	remainingVerified := 0
+	found := false
+	for _, account := range bankAccounts {
+		if account.ID == bankAccount.ID {
+			found = true
+			bankAccount.IsDefault = account.IsDefault
+			continue
+		}
+		remaining = append(remaining, account)
+		if account.IsVerified() {
+			remainingVerified++
+		}
+	}
+	if !found {
+		return sql.ErrNoRows
+	}
+
+	// buyers need somewhere to pay into, and only verified accounts can be paid into, so the seller's
+	// products go off sale with their last verified account
+	if remainingVerified == 0 {
+		count, err := BankAccountRepoImpl.CountPurchasableProducts(ctx, tx, bankAccount.UserID)
+		if err != nil {
+			return err
+		}
+
+		if count > 0 {
+			if !markUnpurchasable {
+				return errLastBankAccount
+			}
+
+			_, err = ProductsUnpurchasableHandler(ctx, tx, bankAccount.UserID)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	err = BankAccountRepoImpl.DeleteBankAccount(ctx, tx, bankAccount.ID)
+	if err != nil {
+		return err
+	}
+
+	// the remaining accounts are sorted verified first, so buyers keep being offered one they can pay into
+	if bankAccount.IsDefault && len(remaining) > 0 {
+		err = BankAccountRepoImpl.SetDefaultBankAccount(ctx, tx, bankAccount.UserID, remaining[0].ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func SetDefaultBankAccount(c *fiber.Ctx) error {
+	bankAccountID := c.Params("bank_account_id")
+
+	claims, err := jwt.GetLoggedInUser(c)
 	if err != nil {
 		return c.Status(fiber.StatusForbidden).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "forbidden",
+		})
+	}
+
+	ctx := c.Context()
+	bankAccount, err := BankAccountRepoImpl.GetBankAccountByID(ctx, bankAccountID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return c.Status(fiber.StatusNotFound).JSON(model.ErrorResponse{
+				Message: "bank account not found",
+				Code:    "entity_not_found",
+			})
+		}
+
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
 			Message: "something wrong with the server. Please contact admin",
 			Code:    "internal_server_error",
 		})
 	}
 
+	if bankAccount.UserID != claims.UserID {
+		return c.Status(fiber.StatusNotFound).JSON(model.ErrorResponse{
+			Message: "bank account not found",
+			Code:    "entity_not_found",
+		})
+	}
+
+	err = setDefaultBankAccount(ctx, bankAccount)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	bankAccount.IsDefault = true
+
 	return c.Status(fiber.StatusOK).JSON(model.DataResponse{
 		Message: "success",
+		Data:    bankAccountEntityToResponse(bankAccount),
 	})
 }
 
+func setDefaultBankAccount(ctx context.Context, bankAccount BankAccount) error {
+	tx, err := TrxProvider.NewTransaction(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// lock the seller's accounts so a concurrent change of default waits for this one
+	_, err = BankAccountRepoImpl.LockBankAccountsByUserID(ctx, tx, bankAccount.UserID)
+	if err != nil {
+		return err
+	}
+
+	err = BankAccountRepoImpl.SetDefaultBankAccount(ctx, tx, bankAccount.UserID, bankAccount.ID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func RequestVerification(c *fiber.Ctx) error {
+	bankAccountID := c.Params("bank_account_id")
+
+	claims, err := jwt.GetLoggedInUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "forbidden",
+		})
+	}
+
+	ctx := c.Context()
+	bankAccount, err := BankAccountRepoImpl.GetBankAccountByID(ctx, bankAccountID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return c.Status(fiber.StatusNotFound).JSON(model.ErrorResponse{
+				Message: "bank account not found",
+				Code:    "entity_not_found",
+			})
+		}
+
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	if bankAccount.UserID != claims.UserID {
+		return c.Status(fiber.StatusNotFound).JSON(model.ErrorResponse{
+			Message: "bank account not found",
+			Code:    "entity_not_found",
+		})
+	}
+
+	// only accounts that failed verification can be retried, the others are already or about to be verified
+	if bankAccount.VerificationStatus != VerificationStatusUnverified {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: fmt.Sprintf("bank account is already %s", bankAccount.VerificationStatus),
+			Code:    "invalid_verification_status",
+		})
+	}
+
+	err = BankAccountRepoImpl.RequestVerification(ctx, bankAccount.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	bankAccount.VerificationStatus = VerificationStatusPending
+	bankAccount.VerificationNote = nil
+
+	return c.Status(fiber.StatusOK).JSON(model.DataResponse{
+		Message: "success",
+		Data:    bankAccountEntityToResponse(bankAccount),
+	})
+}
+
+// ListPendingBankAccounts lists the oldest accounts waiting to be verified, for admins to verify
+// them when no verifier is configured
+func ListPendingBankAccounts(c *fiber.Ctx) error {
+	bankAccounts, err := BankAccountRepoImpl.ListPendingBankAccounts(c.Context(), adminPendingListLimit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	responses := make([]AdminBankAccountResponse, len(bankAccounts))
+	for i, bankAccount := range bankAccounts {
+		responses[i] = AdminBankAccountResponse{
+			BankAccountResponse: bankAccountEntityToResponse(bankAccount),
+			UserID:              bankAccount.UserID,
+		}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(model.DataResponse{
+		Message: "success",
+		Data:    responses,
+	})
+}
+
+// VerifyBankAccount records an admin's verification of a pending account
+func VerifyBankAccount(c *fiber.Ctx) error {
+	var payload VerifyBankAccountRequest
+
+	if err := c.BodyParser(&payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "invalid_request_body",
+		})
+	}
+
+	// validation for request body
+	if err := validation.Validate(payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "failed_request_body_validation",
+		})
+	}
+
+	ctx := c.Context()
+	bankAccount, err := BankAccountRepoImpl.GetBankAccountByID(ctx, c.Params("bank_account_id"))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return c.Status(fiber.StatusNotFound).JSON(model.ErrorResponse{
+				Message: "bank account not found",
+				Code:    "entity_not_found",
+			})
+		}
+
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	if bankAccount.VerificationStatus != VerificationStatusPending {
+		return c.Status(fiber.StatusConflict).JSON(model.ErrorResponse{
+			Message: fmt.Sprintf("bank account is already %s", bankAccount.VerificationStatus),
+			Code:    "invalid_verification_status",
+		})
+	}
+
+	result := VerificationResult{Verified: payload.Verified, Reason: payload.Reason}
+	now := time.Now()
+	updated, err := BankAccountRepoImpl.SetVerificationResult(ctx, bankAccount, result, now)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+	// the seller edited the account, or the verifier answered, since it was read
+	if !updated {
+		return c.Status(fiber.StatusConflict).JSON(model.ErrorResponse{
+			Message: "bank account changed while it was being verified",
+			Code:    "invalid_verification_status",
+		})
+	}
+
+	if result.Verified {
+		bankAccount.VerificationStatus = VerificationStatusVerified
+		bankAccount.VerifiedAt = &now
+	} else {
+		bankAccount.VerificationStatus = VerificationStatusUnverified
+		bankAccount.VerificationNote = &result.Reason
+	}
+
+	return c.Status(fiber.StatusOK).JSON(model.DataResponse{
+		Message: "success",
+		Data: AdminBankAccountResponse{
+			BankAccountResponse: bankAccountEntityToResponse(bankAccount),
+			UserID:              bankAccount.UserID,
+		},
+	})
+}
+
+// validateBankAccountRequest checks the bank is registered and the account number fits its rules
+func validateBankAccountRequest(payload BankAccountRequest) (Bank, error) {
+	bank, ok := GetBank(payload.BankCode)
+	if !ok {
+		return bank, errors.New("bank is not supported")
+	}
+
+	err := bank.ValidateAccountNumber(payload.BankAccountNumber)
+	if err != nil {
+		return bank, err
+	}
+
+	return bank, nil
+}
+
 func bankAccountEntityToResponse(bankAccount BankAccount) BankAccountResponse {
 	return BankAccountResponse{
-		BankAccountID:     bankAccount.ID,
-		BankName:          bankAccount.BankName,
-		BankAccountName:   bankAccount.BankAccountName,
-		BankAccountNumber: bankAccount.BankAccountNumber,
+		BankAccountID:      bankAccount.ID,
+		BankCode:           bankAccount.BankCode,
+		BankName:           bankAccount.BankName,
+		BankAccountName:    bankAccount.BankAccountName,
+		BankAccountNumber:  bankAccount.BankAccountNumber,
+		IsDefault:          bankAccount.IsDefault,
+		VerificationStatus: bankAccount.VerificationStatus,
+		VerificationNote:   bankAccount.VerificationNote,
+		VerifiedAt:         bankAccount.VerifiedAt,
 	}
 }
@@ -1,15 +1,47 @@
 package bankaccount
 
+import "time"
+
+const (
+	VerificationStatusUnverified = "unverified"
+	VerificationStatusPending    = "pending"
+	VerificationStatusVerified   = "verified"
+)
+
 type BankAccountRequest struct {
-	BankName          string `json:"bankName" validate:"required,min=5,max=15"`
+	BankCode          string `json:"bankCode" validate:"required"`
 	BankAccountName   string `json:"bankAccountName" validate:"required,min=5,max=15"`
-	BankAccountNumber string `json:"bankAccountNumber" validate:"required,min=5,max=15"`
+	BankAccountNumber string `json:"bankAccountNumber" validate:"required"`
+}
+
+// VerifyBankAccountRequest is an admin's answer for an account no verifier checked
+type VerifyBankAccountRequest struct {
+	Verified bool   `json:"verified"`
+	Reason   string `json:"reason" validate:"required_if=Verified false,max=255"`
+}
+
+type DeleteBankAccountRequest struct {
+	// MarkUnpurchasable allows deleting the last account by taking the seller's products off sale
+	MarkUnpurchasable bool `query:"markUnpurchasable"`
 }
 
 type BankAccount struct {
-	ID                string `db:"id"`
-	UserID            string `db:"user_id"`
-	BankName          string `db:"bank_name"`
-	BankAccountName   string `db:"bank_account_name"`
-	BankAccountNumber string `db:"bank_account_number"`
+	ID     string `db:"id"`
+	UserID string `db:"user_id"`
+	// BankCode is empty for accounts made before the bank registry whose bank couldn't be recognized
+	BankCode          *string `db:"bank_code"`
+	BankName          string  `db:"bank_name"`
+	BankAccountName   string  `db:"bank_account_name"`
+	BankAccountNumber string  `db:"bank_account_number"`
+	// IsDefault marks the account the seller prefers to be paid into, each seller has at most one
+	IsDefault bool `db:"is_default"`
+
+	VerificationStatus string     `db:"verification_status"`
+	VerificationNote   *string    `db:"verification_note"`
+	VerifiedAt         *time.Time `db:"verified_at"`
+}
+
+// IsVerified tells whether buyers can pay into the account
+func (b BankAccount) IsVerified() bool {
+	return b.VerificationStatus == VerificationStatusVerified
 }
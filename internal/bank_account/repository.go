@@ -2,8 +2,12 @@ package bankaccount
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"time"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 type BankAccountRepo struct {
@@ -14,25 +18,49 @@ func NewBankAccountRepo(db *sqlx.DB) BankAccountRepo {
 	return BankAccountRepo{db: db}
 }
 
-func (r BankAccountRepo) CreateBankAccount(ctx context.Context, bankAccount BankAccount) error {
+// defaultBankAccountIndex allows one default account per user
+const defaultBankAccountIndex = "idx_bank_accounts_default"
+
+// CreateBankAccount stores the account, making it the default if it's the user's first one,
+// and returns whether it was made the default
+func (r BankAccountRepo) CreateBankAccount(ctx context.Context, bankAccount BankAccount) (bool, error) {
+	isDefault, err := r.insertBankAccount(ctx, bankAccount)
+
+	// two first accounts made at once both see no default, the one the unique index turns
+	// down is inserted again, and sees the other one as the default this time
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == defaultBankAccountIndex {
+		return r.insertBankAccount(ctx, bankAccount)
+	}
+
+	return isDefault, err
+}
+
+func (r BankAccountRepo) insertBankAccount(ctx context.Context, bankAccount BankAccount) (bool, error) {
 	query := `
 		INSERT INTO bank_accounts
-			(id, user_id, bank_name, bank_account_name, bank_account_number)
+			(id, user_id, bank_code, bank_name, bank_account_name, bank_account_number, verification_status, is_default)
 		VALUES
-			(:id, :user_id, :bank_name, :bank_account_name, :bank_account_number)
+			(
+				:id, :user_id, :bank_code, :bank_name, :bank_account_name, :bank_account_number, :verification_status,
+				NOT EXISTS (SELECT 1 FROM bank_accounts WHERE user_id = :user_id AND is_default AND deleted_at IS NULL)
+			)
+		RETURNING
+			is_default
 	`
 
 	updatedQuery, args, err := sqlx.Named(query, bankAccount)
 	if err != nil {
-		return err
+		return false, err
 	}
 
-	_, err = r.db.ExecContext(ctx, sqlx.Rebind(sqlx.DOLLAR, updatedQuery), args...)
+	var isDefault bool
+	err = r.db.QueryRowContext(ctx, sqlx.Rebind(sqlx.DOLLAR, updatedQuery), args...).Scan(&isDefault)
 	if err != nil {
-		return err
+		return false, err
 	}
 
-	return nil
+	return isDefault, nil
 }
 
 func (r BankAccountRepo) GetBankAccountsByUserID(ctx context.Context, userID string) ([]BankAccount, error) {
@@ -42,15 +70,21 @@ func (r BankAccountRepo) GetBankAccountsByUserID(ctx context.Context, userID str
 		SELECT
 			id,
 			user_id,
+			bank_code,
 			bank_name,
 			bank_account_name,
-			bank_account_number
+			bank_account_number,
+			is_default,
+			verification_status,
+			verification_note,
+			verified_at
 		FROM
 			bank_accounts
 		WHERE
 			user_id = $1
 			AND deleted_at IS NULL
 		ORDER BY
+			is_default DESC,
 			created_at DESC
 	`
 
@@ -69,9 +103,14 @@ func (r BankAccountRepo) GetBankAccountByID(ctx context.Context, bankAccountID s
 		SELECT
 			id,
 			user_id,
+			bank_code,
 			bank_name,
 			bank_account_name,
-			bank_account_number
+			bank_account_number,
+			is_default,
+			verification_status,
+			verification_note,
+			verified_at
 		FROM
 			bank_accounts
 		WHERE
@@ -93,9 +132,14 @@ func (r BankAccountRepo) UpdateBankAccount(ctx context.Context, bankAccount Bank
 		UPDATE
 			bank_accounts
 		SET
+			bank_code = :bank_code,
 			bank_name = :bank_name,
 			bank_account_name = :bank_account_name,
-			bank_account_number = :bank_account_number
+			bank_account_number = :bank_account_number,
+			verification_status = :verification_status,
+			verification_note = :verification_note,
+			verified_at = :verified_at,
+			updated_at = NOW()
 		WHERE
 			id = :id
 			AND deleted_at IS NULL
@@ -114,21 +158,264 @@ func (r BankAccountRepo) UpdateBankAccount(ctx context.Context, bankAccount Bank
 	return nil
 }
 
-func (r BankAccountRepo) DeleteBankAccount(ctx context.Context, bankAccountID string) error {
+func (r BankAccountRepo) DeleteBankAccount(ctx context.Context, tx *sql.Tx, bankAccountID string) error {
 	query := `
 		UPDATE
 			bank_accounts
 		SET
+			is_default = FALSE,
 			deleted_at = NOW()
 		WHERE
 			id = $1
 			AND deleted_at IS NULL
 	`
 
-	_, err := r.db.ExecContext(ctx, query, bankAccountID)
+	_, err := tx.ExecContext(ctx, query, bankAccountID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// LockBankAccountsByUserID locks all the user's accounts, so the set of accounts and which one is the
+// default can't change until the transaction ends. The default account comes first, then verified ones.
+func (r BankAccountRepo) LockBankAccountsByUserID(ctx context.Context, tx *sql.Tx, userID string) ([]BankAccount, error) {
+	query := `
+		SELECT
+			id,
+			user_id,
+			is_default,
+			verification_status
+		FROM
+			bank_accounts
+		WHERE
+			user_id = $1
+			AND deleted_at IS NULL
+		ORDER BY
+			is_default DESC,
+			verification_status = $2 DESC,
+			created_at DESC
+		FOR UPDATE
+	`
+
+	rows, err := tx.QueryContext(ctx, query, userID, VerificationStatusVerified)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	bankAccounts := []BankAccount{}
+	for rows.Next() {
+		var bankAccount BankAccount
+		err = rows.Scan(
+			&bankAccount.ID,
+			&bankAccount.UserID,
+			&bankAccount.IsDefault,
+			&bankAccount.VerificationStatus,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		bankAccounts = append(bankAccounts, bankAccount)
+	}
+
+	return bankAccounts, rows.Err()
+}
+
+// SetDefaultBankAccount makes the account the user's default, in place of the previous one
+func (r BankAccountRepo) SetDefaultBankAccount(ctx context.Context, tx *sql.Tx, userID, bankAccountID string) error {
+	// the previous default is cleared first, as a user can't have two defaults even for a moment
+	query := `
+		UPDATE
+			bank_accounts
+		SET
+			is_default = FALSE,
+			updated_at = NOW()
+		WHERE
+			user_id = $1
+			AND id <> $2
+			AND is_default
+			AND deleted_at IS NULL
+	`
+
+	_, err := tx.ExecContext(ctx, query, userID, bankAccountID)
+	if err != nil {
+		return err
+	}
+
+	query = `
+		UPDATE
+			bank_accounts
+		SET
+			is_default = TRUE,
+			updated_at = NOW()
+		WHERE
+			id = $1
+			AND user_id = $2
+			AND deleted_at IS NULL
+	`
+
+	res, err := tx.ExecContext(ctx, query, bankAccountID, userID)
+	if err != nil {
+		return err
+	}
+
+	affectedRows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affectedRows != 1 {
+		return errors.New("error affected row count is not equal to 1")
+	}
+
+	return nil
+}
+
+// CountPurchasableProducts counts the user's products that buyers can currently buy
+func (r BankAccountRepo) CountPurchasableProducts(ctx context.Context, tx *sql.Tx, userID string) (int, error) {
+	query := `
+		SELECT
+			COUNT(*)
+		FROM
+			products
+		WHERE
+			user_id = $1
+			AND is_purchasable = TRUE
+			AND deleted_at IS NULL
+	`
+
+	var count int
+	err := tx.QueryRowContext(ctx, query, userID).Scan(&count)
+	if err != nil {
+		return count, err
+	}
+
+	return count, nil
+}
+
+// ListPendingBankAccounts returns the oldest accounts waiting to be verified
+func (r BankAccountRepo) ListPendingBankAccounts(ctx context.Context, limit int) ([]BankAccount, error) {
+	var result []BankAccount
+
+	query := `
+		SELECT
+			id,
+			user_id,
+			bank_code,
+			bank_name,
+			bank_account_name,
+			bank_account_number,
+			is_default,
+			verification_status,
+			verification_note,
+			verified_at
+		FROM
+			bank_accounts
+		WHERE
+			verification_status = $1
+			AND deleted_at IS NULL
+		ORDER BY
+			updated_at
+		LIMIT $2
+	`
+
+	err := r.db.SelectContext(ctx, &result, query, VerificationStatusPending, limit)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// SetVerificationResult stores the verifier's answer. It only applies if the account is still pending
+// with the details that were verified, so an answer never lands on an account edited in the meantime.
+func (r BankAccountRepo) SetVerificationResult(ctx context.Context, bankAccount BankAccount, result VerificationResult, now time.Time) (bool, error) {
+	status := VerificationStatusUnverified
+	var note *string
+	var verifiedAt *time.Time
+	if result.Verified {
+		status = VerificationStatusVerified
+		verifiedAt = &now
+	} else {
+		note = &result.Reason
+	}
+
+	query := `
+		UPDATE
+			bank_accounts
+		SET
+			verification_status = $1,
+			verification_note = $2,
+			verified_at = $3
+		WHERE
+			id = $4
+			AND verification_status = $5
+			AND bank_code IS NOT DISTINCT FROM $6
+			AND bank_account_name = $7
+			AND bank_account_number = $8
+			AND deleted_at IS NULL
+	`
+
+	res, err := r.db.ExecContext(ctx, query, status, note, verifiedAt, bankAccount.ID, VerificationStatusPending,
+		bankAccount.BankCode, bankAccount.BankAccountName, bankAccount.BankAccountNumber)
+	if err != nil {
+		return false, err
+	}
+
+	affectedRows, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affectedRows == 1, nil
+}
+
+// DeferVerification moves a pending account to the back of the verification queue
+func (r BankAccountRepo) DeferVerification(ctx context.Context, bankAccountID string) error {
+	query := `
+		UPDATE
+			bank_accounts
+		SET
+			updated_at = NOW()
+		WHERE
+			id = $1
+			AND verification_status = $2
+			AND deleted_at IS NULL
+	`
+
+	_, err := r.db.ExecContext(ctx, query, bankAccountID, VerificationStatusPending)
+	return err
+}
+
+// RequestVerification queues an unverified account for verification again
+func (r BankAccountRepo) RequestVerification(ctx context.Context, bankAccountID string) error {
+	query := `
+		UPDATE
+			bank_accounts
+		SET
+			verification_status = $1,
+			verification_note = NULL,
+			updated_at = NOW()
+		WHERE
+			id = $2
+			AND verification_status = $3
+			AND deleted_at IS NULL
+	`
+
+	res, err := r.db.ExecContext(ctx, query, VerificationStatusPending, bankAccountID, VerificationStatusUnverified)
 	if err != nil {
 		return err
 	}
 
+	affectedRows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affectedRows != 1 {
+		return errors.New("error affected row count is not equal to 1")
+	}
+
 	return nil
 }
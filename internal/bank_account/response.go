@@ -1,8 +1,28 @@
 package bankaccount
 
+import "time"
+
 type BankAccountResponse struct {
-	BankAccountID     string `json:"bankAccountId"`
-	BankName          string `json:"bankName"`
-	BankAccountName   string `json:"bankAccountName"`
-	BankAccountNumber string `json:"bankAccountNumber"`
+	BankAccountID      string     `json:"bankAccountId"`
+	BankCode           *string    `json:"bankCode"`
+	BankName           string     `json:"bankName"`
+	BankAccountName    string     `json:"bankAccountName"`
+	BankAccountNumber  string     `json:"bankAccountNumber"`
+	IsDefault          bool       `json:"isDefault"`
+	VerificationStatus string     `json:"verificationStatus"`
+	VerificationNote   *string    `json:"verificationNote"`
+	VerifiedAt         *time.Time `json:"verifiedAt"`
+}
+
+// AdminBankAccountResponse is a bank account as admins see it, with the seller it belongs to
+type AdminBankAccountResponse struct {
+	BankAccountResponse
+	UserID string `json:"userId"`
+}
+
+type BankResponse struct {
+	Code      string `json:"code"`
+	Name      string `json:"name"`
+	MinLength int    `json:"accountNumberMinLength"`
+	MaxLength int    `json:"accountNumberMaxLength"`
 }
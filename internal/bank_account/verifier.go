@@ -0,0 +1,37 @@
+package bankaccount
+
+import (
+	"context"
+	"strings"
+)
+
+// VerificationResult is the bank's answer on whether an account exists and belongs to its holder
+type VerificationResult struct {
+	Verified bool
+	// Reason explains why the account couldn't be verified
+	Reason string
+}
+
+// Verifier checks bank accounts against the bank. A failed check is a result, not an error;
+// errors are only returned when the bank couldn't be asked, so the account is retried later.
+type Verifier interface {
+	Verify(ctx context.Context, bankAccount BankAccount) (VerificationResult, error)
+}
+
+// FakeVerifier verifies every account except those whose number ends with "0000",
+// for tests and local development
+type FakeVerifier struct {
+	Err error
+}
+
+func (v FakeVerifier) Verify(ctx context.Context, bankAccount BankAccount) (VerificationResult, error) {
+	if v.Err != nil {
+		return VerificationResult{}, v.Err
+	}
+
+	if strings.HasSuffix(bankAccount.BankAccountNumber, "0000") {
+		return VerificationResult{Reason: "account not found at the bank"}, nil
+	}
+
+	return VerificationResult{Verified: true}, nil
+}
@@ -0,0 +1,81 @@
+package bankaccount
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+const verificationBatchSize = 50
+
+// RunVerificationWorker periodically sends the pending bank accounts to the verifier. It blocks
+// until ctx is cancelled, so it should be run in its own goroutine.
+func RunVerificationWorker(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		verified, err := verifyPendingBankAccounts(ctx)
+		if err != nil {
+			log.Printf("error verifying bank accounts: %v", err)
+		} else if verified > 0 {
+			log.Printf("verified %d bank accounts", verified)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// verifyPendingBankAccounts returns how many accounts got an answer. Accounts the verifier
+// couldn't check stay pending and are retried after the others.
+func verifyPendingBankAccounts(ctx context.Context) (int, error) {
+	bankAccounts, err := BankAccountRepoImpl.ListPendingBankAccounts(ctx, verificationBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for _, bankAccount := range bankAccounts {
+		result, err := verifyBankAccount(ctx, bankAccount)
+		if err != nil {
+			log.Printf("error verifying bank account %s: %v", bankAccount.ID, err)
+
+			// move it to the back of the queue, so accounts the verifier keeps failing on don't hold up the rest
+			err = BankAccountRepoImpl.DeferVerification(ctx, bankAccount.ID)
+			if err != nil {
+				return total, err
+			}
+			continue
+		}
+
+		updated, err := BankAccountRepoImpl.SetVerificationResult(ctx, bankAccount, result, time.Now())
+		if err != nil {
+			return total, err
+		}
+		if updated {
+			total++
+		}
+	}
+
+	return total, nil
+}
+
+func verifyBankAccount(ctx context.Context, bankAccount BankAccount) (VerificationResult, error) {
+	// accounts made before the registry may not belong to a supported bank, or not fit its rules
+	if bankAccount.BankCode == nil {
+		return VerificationResult{Reason: "bank is not supported"}, nil
+	}
+	bank, ok := GetBank(*bankAccount.BankCode)
+	if !ok {
+		return VerificationResult{Reason: "bank is not supported"}, nil
+	}
+	if err := bank.ValidateAccountNumber(bankAccount.BankAccountNumber); err != nil {
+		return VerificationResult{Reason: err.Error()}, nil
+	}
+
+	return VerifierImpl.Verify(ctx, bankAccount)
+}
@@ -0,0 +1,23 @@
+package commission
+
+import (
+	"context"
+	"database/sql"
+)
+
+// ResolveRule returns the rule an order of the seller's product in the category is charged with.
+// Without any matching rule, the order is charged the default rate.
+func ResolveRule(ctx context.Context, sellerID, category string) (Rule, error) {
+	rule, err := CommissionRepoImpl.FindApplicableRule(ctx, sellerID, NormalizeCategory(category))
+	if err == sql.ErrNoRows {
+		return Rule{
+			Scope:           ScopeGlobal,
+			RateBasisPoints: DefaultRateBasisPoints,
+		}, nil
+	}
+	if err != nil {
+		return rule, err
+	}
+
+	return rule, nil
+}
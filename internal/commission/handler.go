@@ -0,0 +1,238 @@
+package commission
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/admin"
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/model"
+	"github.com/ahmadnaufal/openidea-shopifyx/pkg/jwt"
+	"github.com/ahmadnaufal/openidea-shopifyx/pkg/money"
+	"github.com/ahmadnaufal/openidea-shopifyx/pkg/validation"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+var (
+	CommissionRepoImpl *CommissionRepo
+
+	// DefaultRateBasisPoints is the commission rate of orders no rule applies to
+	DefaultRateBasisPoints int64
+)
+
+func RegisterRoute(r *fiber.App, jwtProvider jwt.JWTProvider) {
+	authMiddleware := jwtProvider.Middleware()
+
+	ruleGroup := r.Group("/v1/admin/commission/rules", authMiddleware, admin.Middleware())
+	ruleGroup.Post("/", CreateRule)
+	ruleGroup.Get("/", ListRules)
+	ruleGroup.Put("/:rule_id", UpdateRule)
+	ruleGroup.Delete("/:rule_id", DeleteRule)
+}
+
+func CreateRule(c *fiber.Ctx) error {
+	var payload RuleRequest
+
+	if err := c.BodyParser(&payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "invalid_request_body",
+		})
+	}
+
+	// validation for request body
+	if err := validation.Validate(payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "failed_request_body_validation",
+		})
+	}
+
+	if err := validateRate(payload.RateRequest); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "failed_request_body_validation",
+		})
+	}
+
+	// there is a single global rule, and categories match regardless of case
+	scopeValue := payload.ScopeValue
+	switch payload.Scope {
+	case ScopeGlobal:
+		scopeValue = ""
+	case ScopeCategory:
+		scopeValue = NormalizeCategory(scopeValue)
+	}
+
+	now := time.Now()
+	rule := Rule{
+		ID:         uuid.NewString(),
+		Scope:      payload.Scope,
+		ScopeValue: scopeValue,
+		CreatedAt:  now,
+	}
+	applyRate(&rule, payload.RateRequest, now)
+
+	err := CommissionRepoImpl.CreateRule(c.Context(), rule)
+	if err != nil {
+		if err == ErrRuleExists {
+			return c.Status(fiber.StatusConflict).JSON(model.ErrorResponse{
+				Message: err.Error(),
+				Code:    "commission_rule_already_exists",
+			})
+		}
+
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(model.DataResponse{
+		Message: "Commission rule created successfully",
+		Data:    ruleEntityToResponse(rule),
+	})
+}
+
+func ListRules(c *fiber.Ctx) error {
+	rules, err := CommissionRepoImpl.ListRules(c.Context())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	responses := make([]RuleResponse, len(rules))
+	for i, rule := range rules {
+		responses[i] = ruleEntityToResponse(rule)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(model.DataResponse{
+		Message: "ok",
+		Data:    responses,
+	})
+}
+
+// UpdateRule changes the rule's rate. Orders already made keep the commission they were charged.
+func UpdateRule(c *fiber.Ctx) error {
+	var payload RateRequest
+
+	if err := c.BodyParser(&payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "invalid_request_body",
+		})
+	}
+
+	// validation for request body
+	if err := validation.Validate(payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "failed_request_body_validation",
+		})
+	}
+
+	if err := validateRate(payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "failed_request_body_validation",
+		})
+	}
+
+	ctx := c.Context()
+	rule, err := CommissionRepoImpl.GetRuleByID(ctx, c.Params("rule_id"))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return c.Status(fiber.StatusNotFound).JSON(model.ErrorResponse{
+				Message: "commission rule not found",
+				Code:    "entity_not_found",
+			})
+		}
+
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	applyRate(&rule, payload, time.Now())
+
+	err = CommissionRepoImpl.UpdateRule(ctx, rule)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(model.DataResponse{
+		Message: "Commission rule updated successfully",
+		Data:    ruleEntityToResponse(rule),
+	})
+}
+
+func DeleteRule(c *fiber.Ctx) error {
+	ctx := c.Context()
+	rule, err := CommissionRepoImpl.GetRuleByID(ctx, c.Params("rule_id"))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return c.Status(fiber.StatusNotFound).JSON(model.ErrorResponse{
+				Message: "commission rule not found",
+				Code:    "entity_not_found",
+			})
+		}
+
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	err = CommissionRepoImpl.DeleteRule(ctx, rule.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(model.DataResponse{
+		Message: "Commission rule deleted successfully",
+	})
+}
+
+func validateRate(payload RateRequest) error {
+	if payload.MinAmount != nil && payload.MaxAmount != nil && *payload.MinAmount > *payload.MaxAmount {
+		return errors.New("minimum commission cannot be more than the maximum")
+	}
+
+	return nil
+}
+
+func applyRate(rule *Rule, payload RateRequest, now time.Time) {
+	rule.RateBasisPoints = payload.RateBasisPoints
+	rule.Currency = nil
+	rule.MinAmount = payload.MinAmount
+	rule.MaxAmount = payload.MaxAmount
+	if payload.Currency != "" {
+		currency := money.Currency(payload.Currency)
+		rule.Currency = &currency
+	}
+	rule.UpdatedAt = now
+}
+
+func ruleEntityToResponse(rule Rule) RuleResponse {
+	return RuleResponse{
+		RuleID:          rule.ID,
+		Scope:           rule.Scope,
+		ScopeValue:      rule.ScopeValue,
+		RateBasisPoints: rule.RateBasisPoints,
+		Currency:        rule.Currency,
+		MinAmount:       rule.MinAmount,
+		MaxAmount:       rule.MaxAmount,
+		CreatedAt:       rule.CreatedAt,
+		UpdatedAt:       rule.UpdatedAt,
+	}
+}
@@ -0,0 +1,75 @@
+package commission
+
+import (
+	"strings"
+	"time"
+
+	"github.com/ahmadnaufal/openidea-shopifyx/pkg/money"
+	"github.com/pkg/errors"
+)
+
+// A rule applies to every order of its scope. When more than one rule matches an order, the seller's
+// rule overrides the category's, which overrides the global one.
+const (
+	ScopeGlobal   = "global"
+	ScopeCategory = "category"
+	ScopeSeller   = "seller"
+)
+
+// basisPointsPerUnit is 100%, rates are in hundredths of a percent
+const basisPointsPerUnit = 10000
+
+var (
+	ErrRuleExists = errors.New("a commission rule already exists for this scope")
+)
+
+type RuleRequest struct {
+	Scope string `json:"scope" validate:"oneof=global category seller"`
+	// ScopeValue is the category or the seller's user ID, it is ignored for the global rule
+	ScopeValue string `json:"scopeValue" validate:"required_unless=Scope global,max=64"`
+	RateRequest
+}
+
+type RateRequest struct {
+	// RateBasisPoints is the commission rate in hundredths of a percent, e.g. 250 for 2.5%
+	RateBasisPoints int64 `json:"rateBasisPoints" validate:"gte=0,lte=10000"`
+	// MinAmount and MaxAmount bound the commission of orders in Currency, in its minor units
+	Currency  string `json:"currency" validate:"required_with=MinAmount MaxAmount,omitempty,oneof=IDR SGD MYR"`
+	MinAmount *int64 `json:"minAmount" validate:"omitempty,gte=0"`
+	MaxAmount *int64 `json:"maxAmount" validate:"omitempty,gte=0"`
+}
+
+type Rule struct {
+	ID    string `db:"id"`
+	Scope string `db:"scope"`
+	// ScopeValue is empty for the global rule
+	ScopeValue      string          `db:"scope_value"`
+	RateBasisPoints int64           `db:"rate_basis_points"`
+	Currency        *money.Currency `db:"currency"`
+	MinAmount       *int64          `db:"min_amount"`
+	MaxAmount       *int64          `db:"max_amount"`
+	CreatedAt       time.Time       `db:"created_at"`
+	UpdatedAt       time.Time       `db:"updated_at"`
+}
+
+// Calculate returns the commission on amount. The minimum and maximum only apply to amounts in the
+// rule's currency, and the commission is never more than the amount itself.
+func (r Rule) Calculate(amount money.Money) (money.Money, error) {
+	commission := amount.Fraction(r.RateBasisPoints, basisPointsPerUnit)
+
+	if r.Currency != nil && *r.Currency == amount.Currency {
+		if r.MinAmount != nil && commission.Amount < *r.MinAmount {
+			commission.Amount = *r.MinAmount
+		}
+		if r.MaxAmount != nil && commission.Amount > *r.MaxAmount {
+			commission.Amount = *r.MaxAmount
+		}
+	}
+
+	return commission.Min(amount)
+}
+
+// NormalizeCategory makes categories match regardless of case and surrounding spaces
+func NormalizeCategory(category string) string {
+	return strings.ToLower(strings.TrimSpace(category))
+}
@@ -0,0 +1,216 @@
+package commission
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+type CommissionRepo struct {
+	db *sqlx.DB
+}
+
+func NewCommissionRepo(db *sqlx.DB) CommissionRepo {
+	return CommissionRepo{db: db}
+}
+
+// CreateRule stores the rule, failing with ErrRuleExists if its scope already has one
+func (r CommissionRepo) CreateRule(ctx context.Context, rule Rule) error {
+	query := `
+		INSERT INTO commission_rules
+			(
+				id,
+				scope,
+				scope_value,
+				rate_basis_points,
+				currency,
+				min_amount,
+				max_amount,
+				created_at,
+				updated_at
+			)
+		VALUES
+			(
+				:id,
+				:scope,
+				:scope_value,
+				:rate_basis_points,
+				:currency,
+				:min_amount,
+				:max_amount,
+				:created_at,
+				:updated_at
+			)
+		ON CONFLICT (scope, scope_value) DO NOTHING
+	`
+
+	updatedQuery, args, err := sqlx.Named(query, rule)
+	if err != nil {
+		return err
+	}
+
+	result, err := r.db.ExecContext(ctx, sqlx.Rebind(sqlx.DOLLAR, updatedQuery), args...)
+	if err != nil {
+		return err
+	}
+
+	affectedRows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affectedRows != 1 {
+		return ErrRuleExists
+	}
+
+	return nil
+}
+
+func (r CommissionRepo) ListRules(ctx context.Context) ([]Rule, error) {
+	var rules []Rule
+
+	query := `
+		SELECT
+			id,
+			scope,
+			scope_value,
+			rate_basis_points,
+			currency,
+			min_amount,
+			max_amount,
+			created_at,
+			updated_at
+		FROM
+			commission_rules
+		ORDER BY
+			scope, scope_value
+	`
+
+	err := r.db.SelectContext(ctx, &rules, query)
+	if err != nil {
+		return rules, err
+	}
+
+	return rules, nil
+}
+
+func (r CommissionRepo) GetRuleByID(ctx context.Context, ruleID string) (Rule, error) {
+	var result Rule
+
+	query := `
+		SELECT
+			id,
+			scope,
+			scope_value,
+			rate_basis_points,
+			currency,
+			min_amount,
+			max_amount,
+			created_at,
+			updated_at
+		FROM
+			commission_rules
+		WHERE
+			id = $1
+		LIMIT 1
+	`
+
+	err := r.db.GetContext(ctx, &result, query, ruleID)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// FindApplicableRule returns the most specific rule for an order of the seller's product in the
+// category. It returns sql.ErrNoRows if no rule applies.
+func (r CommissionRepo) FindApplicableRule(ctx context.Context, sellerID, category string) (Rule, error) {
+	var result Rule
+
+	query := `
+		SELECT
+			id,
+			scope,
+			scope_value,
+			rate_basis_points,
+			currency,
+			min_amount,
+			max_amount,
+			created_at,
+			updated_at
+		FROM
+			commission_rules
+		WHERE
+			(scope = $1 AND scope_value = $2)
+			OR (scope = $3 AND scope_value = $4)
+			OR scope = $5
+		ORDER BY
+			CASE scope WHEN $1 THEN 0 WHEN $3 THEN 1 ELSE 2 END
+		LIMIT 1
+	`
+
+	err := r.db.GetContext(ctx, &result, query, ScopeSeller, sellerID, ScopeCategory, category, ScopeGlobal)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r CommissionRepo) UpdateRule(ctx context.Context, rule Rule) error {
+	query := `
+		UPDATE commission_rules
+		SET
+			rate_basis_points = :rate_basis_points,
+			currency = :currency,
+			min_amount = :min_amount,
+			max_amount = :max_amount,
+			updated_at = :updated_at
+		WHERE
+			id = :id
+	`
+
+	updatedQuery, args, err := sqlx.Named(query, rule)
+	if err != nil {
+		return err
+	}
+
+	result, err := r.db.ExecContext(ctx, sqlx.Rebind(sqlx.DOLLAR, updatedQuery), args...)
+	if err != nil {
+		return err
+	}
+
+	affectedRows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affectedRows != 1 {
+		return errors.New("error affected row count is not equal to 1")
+	}
+
+	return nil
+}
+
+func (r CommissionRepo) DeleteRule(ctx context.Context, ruleID string) error {
+	query := `
+		DELETE FROM commission_rules
+		WHERE
+			id = $1
+	`
+
+	result, err := r.db.ExecContext(ctx, query, ruleID)
+	if err != nil {
+		return err
+	}
+
+	affectedRows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affectedRows != 1 {
+		return errors.New("error affected row count is not equal to 1")
+	}
+
+	return nil
+}
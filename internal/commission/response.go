@@ -0,0 +1,19 @@
+package commission
+
+import (
+	"time"
+
+	"github.com/ahmadnaufal/openidea-shopifyx/pkg/money"
+)
+
+type RuleResponse struct {
+	RuleID          string          `json:"ruleId"`
+	Scope           string          `json:"scope"`
+	ScopeValue      string          `json:"scopeValue"`
+	RateBasisPoints int64           `json:"rateBasisPoints"`
+	Currency        *money.Currency `json:"currency"`
+	MinAmount       *int64          `json:"minAmount"`
+	MaxAmount       *int64          `json:"maxAmount"`
+	CreatedAt       time.Time       `json:"createdAt"`
+	UpdatedAt       time.Time       `json:"updatedAt"`
+}
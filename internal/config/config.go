@@ -1,6 +1,10 @@
 package config
 
-import "github.com/joeshaw/envdecode"
+import (
+	"time"
+
+	"github.com/joeshaw/envdecode"
+)
 
 type DatabaseConfig struct {
 	Name              string `env:"DB_NAME"`
@@ -19,6 +23,73 @@ type S3Config struct {
 	Region    string `env:"S3_REGION"`
 }
 
+type ProductConfig struct {
+	// TrashRetention is how long a soft-deleted product can still be restored before it gets purged
+	TrashRetention time.Duration `env:"PRODUCT_TRASH_RETENTION,default=720h"`
+	// PurgeInterval is how often the purge job checks for expired soft-deleted products
+	PurgeInterval time.Duration `env:"PRODUCT_PURGE_INTERVAL,default=1h"`
+}
+
+type OrderConfig struct {
+	// PaymentDeadline is how long a buyer has to pay before the order is cancelled and its stock released
+	PaymentDeadline time.Duration `env:"ORDER_PAYMENT_DEADLINE,default=24h"`
+	// ExpiryInterval is how often unpaid orders past their deadline are cancelled
+	ExpiryInterval time.Duration `env:"ORDER_EXPIRY_INTERVAL,default=1m"`
+}
+
+type AnalyticsConfig struct {
+	// RefreshInterval is how often the pre-aggregated sales tables are refreshed from orders
+	RefreshInterval time.Duration `env:"ANALYTICS_REFRESH_INTERVAL,default=5m"`
+	// ViewDedupWindow is the window in which repeated views of a product by the same viewer count once
+	ViewDedupWindow time.Duration `env:"ANALYTICS_VIEW_DEDUP_WINDOW,default=30m"`
+	// ViewBufferSize is how many views can be queued before new ones are dropped
+	ViewBufferSize int `env:"ANALYTICS_VIEW_BUFFER_SIZE,default=1024"`
+}
+
+type NotificationConfig struct {
+	// OutboxInterval is how often pending notification events are turned into notifications
+	OutboxInterval time.Duration `env:"NOTIFICATION_OUTBOX_INTERVAL,default=10s"`
+}
+
+type WebhookConfig struct {
+	// DeliveryInterval is how often pending webhook deliveries are sent
+	DeliveryInterval time.Duration `env:"WEBHOOK_DELIVERY_INTERVAL,default=5s"`
+	// Timeout is how long an endpoint has to respond before the attempt is considered failed
+	Timeout time.Duration `env:"WEBHOOK_TIMEOUT,default=10s"`
+}
+
+type ShippingConfig struct {
+	// Provider selects where shipping rates come from: "table" for the sellers' own rate tables, or "fake"
+	Provider string `env:"SHIPPING_PROVIDER,default=table"`
+}
+
+type PaymentConfig struct {
+	// MockEnabled enables the mock payment gateway, for development only
+	MockEnabled bool `env:"PAYMENT_MOCK_ENABLED,default=false"`
+	// MockCallbackSecret signs the mock gateway's callbacks, it is required when the mock gateway is enabled
+	MockCallbackSecret string `env:"PAYMENT_MOCK_CALLBACK_SECRET"`
+}
+
+type BankAccountConfig struct {
+	// VerificationInterval is how often pending bank accounts are sent to the verifier
+	VerificationInterval time.Duration `env:"BANK_ACCOUNT_VERIFICATION_INTERVAL,default=1m"`
+	// VerifierMockEnabled verifies accounts with the fake verifier, for development only. Without a verifier
+	// admins verify pending bank accounts.
+	VerifierMockEnabled bool `env:"BANK_ACCOUNT_VERIFIER_MOCK_ENABLED,default=false"`
+}
+
+type CommissionConfig struct {
+	// DefaultRateBasisPoints is the commission rate, in hundredths of a percent, of orders no commission rule applies to
+	DefaultRateBasisPoints int64 `env:"COMMISSION_DEFAULT_RATE_BASIS_POINTS,default=500"`
+}
+
+type IdempotencyConfig struct {
+	// KeyTTL is how long an idempotency key is remembered for retries
+	KeyTTL time.Duration `env:"IDEMPOTENCY_KEY_TTL,default=24h"`
+	// CleanupInterval is how often expired idempotency keys are deleted
+	CleanupInterval time.Duration `env:"IDEMPOTENCY_CLEANUP_INTERVAL,default=1h"`
+}
+
 type Config struct {
 	Database          DatabaseConfig
 	AppPort           string `env:"APP_PORT"`
@@ -28,12 +99,26 @@ type Config struct {
 	// security-related options
 	JWTSecret  string `env:"JWT_SECRET"`
 	BcryptSalt int    `env:"BCRYPT_SALT"`
+	// AdminUserIDs are the users allowed to use the admin endpoints, separated by ";"
+	AdminUserIDs []string `env:"ADMIN_USER_IDS"`
 
 	// S3Enabled is a flag which if set to true, will set image upload to s3
 	S3Enabled bool `env:"S3_ENABLED"`
 
 	// S3 stores config to connect to S3
 	S3 S3Config
+
+	Product ProductConfig
+	Order   OrderConfig
+
+	Analytics    AnalyticsConfig
+	Notification NotificationConfig
+	Webhook      WebhookConfig
+	Shipping     ShippingConfig
+	Payment      PaymentConfig
+	BankAccount  BankAccountConfig
+	Commission   CommissionConfig
+	Idempotency  IdempotencyConfig
 }
 
 func InitializeConfig() Config {
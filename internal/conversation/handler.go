@@ -0,0 +1,432 @@
+package conversation
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/config"
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/model"
+	"github.com/ahmadnaufal/openidea-shopifyx/pkg/jwt"
+	"github.com/ahmadnaufal/openidea-shopifyx/pkg/validation"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+const defaultMessagePageSize = 20
+
+var (
+	ConversationRepoImpl *ConversationRepo
+	TrxProvider          *config.TransactionProvider
+)
+
+func RegisterRoute(r *fiber.App, jwtProvider jwt.JWTProvider) {
+	conversationGroup := r.Group("/v1/conversation")
+	authMiddleware := jwtProvider.Middleware()
+	conversationGroup.Use(authMiddleware)
+
+	conversationGroup.Post("/", StartConversation)
+	conversationGroup.Get("/", ListConversations)
+	conversationGroup.Get("/:conversation_id/messages", ListMessages)
+	conversationGroup.Post("/:conversation_id/messages", SendMessage)
+	conversationGroup.Post("/:conversation_id/read", MarkConversationRead)
+}
+
+// StartConversation sends a message to the seller of a product, in the buyer's existing conversation
+// about the product if there is one
+func StartConversation(c *fiber.Ctx) error {
+	var payload StartConversationRequest
+
+	claims, err := jwt.GetLoggedInUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "forbidden",
+		})
+	}
+
+	if err := c.BodyParser(&payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "invalid_request_body",
+		})
+	}
+
+	// validation for request body
+	if err := validation.Validate(payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "failed_request_body_validation",
+		})
+	}
+
+	ctx := c.Context()
+
+	sellerID, err := ConversationRepoImpl.GetProductSellerID(ctx, payload.ProductID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return c.Status(fiber.StatusNotFound).JSON(model.ErrorResponse{
+				Message: "product not found",
+				Code:    "entity_not_found",
+			})
+		}
+
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	if sellerID == claims.UserID {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: "cannot start a conversation about your own product",
+			Code:    "conversation_with_self",
+		})
+	}
+
+	conversation, message, err := startConversation(ctx, Conversation{
+		ID:        uuid.NewString(),
+		ProductID: payload.ProductID,
+		BuyerID:   claims.UserID,
+		SellerID:  sellerID,
+	}, payload.SendMessageRequest)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	messageResponse := messageEntityToResponse(message)
+	return c.Status(fiber.StatusOK).JSON(model.DataResponse{
+		Message: "Message sent successfully",
+		Data: ConversationResponse{
+			ID:            conversation.ID,
+			ProductID:     conversation.ProductID,
+			BuyerID:       conversation.BuyerID,
+			SellerID:      conversation.SellerID,
+			LastMessage:   &message.Body,
+			LastMessageAt: &message.CreatedAt,
+			Message:       &messageResponse,
+		},
+	})
+}
+
+func startConversation(ctx context.Context, conversation Conversation, payload SendMessageRequest) (Conversation, Message, error) {
+	tx, err := TrxProvider.NewTransaction(ctx)
+	if err != nil {
+		return Conversation{}, Message{}, err
+	}
+	defer tx.Rollback()
+
+	conversation, err = ConversationRepoImpl.GetOrCreateConversation(ctx, tx, conversation)
+	if err != nil {
+		return Conversation{}, Message{}, err
+	}
+
+	message := newMessage(conversation.ID, conversation.BuyerID, payload)
+	err = ConversationRepoImpl.CreateMessage(ctx, tx, message)
+	if err != nil {
+		return Conversation{}, Message{}, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return Conversation{}, Message{}, err
+	}
+
+	return conversation, message, nil
+}
+
+func ListConversations(c *fiber.Ctx) error {
+	claims, err := jwt.GetLoggedInUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "forbidden",
+		})
+	}
+
+	var req ListConversationsRequest
+	if err := c.QueryParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "invalid_request_body",
+		})
+	}
+
+	ctx := c.Context()
+	conversations, count, err := ConversationRepoImpl.ListConversations(ctx, claims.UserID, req)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	responses := []ConversationResponse{}
+	for _, conversation := range conversations {
+		responses = append(responses, ConversationResponse{
+			ID:            conversation.ID,
+			ProductID:     conversation.ProductID,
+			ProductName:   conversation.ProductName,
+			BuyerID:       conversation.BuyerID,
+			SellerID:      conversation.SellerID,
+			LastMessage:   conversation.LastMessageBody,
+			LastMessageAt: conversation.LastMessageAt,
+			UnreadCount:   conversation.UnreadCount,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(model.DataResponse{
+		Message: "ok",
+		Data:    responses,
+		Meta: &model.ResponseMeta{
+			Limit:  req.Limit,
+			Offset: req.Offset,
+			Total:  count,
+		},
+	})
+}
+
+func ListMessages(c *fiber.Ctx) error {
+	conversationID := c.Params("conversation_id")
+
+	claims, err := jwt.GetLoggedInUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "forbidden",
+		})
+	}
+
+	var req ListMessagesRequest
+	if err := c.QueryParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "invalid_request_body",
+		})
+	}
+
+	if err := validation.Validate(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "failed_request_body_validation",
+		})
+	}
+
+	var cursor *messageCursor
+	if req.Cursor != "" {
+		decoded, err := decodeMessageCursor(req.Cursor)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+				Message: err.Error(),
+				Code:    "invalid_request_body",
+			})
+		}
+		cursor = &decoded
+	}
+
+	ctx := c.Context()
+
+	conversation, err := getParticipatingConversation(ctx, conversationID, claims.UserID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return c.Status(fiber.StatusNotFound).JSON(model.ErrorResponse{
+				Message: "conversation not found",
+				Code:    "entity_not_found",
+			})
+		}
+
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultMessagePageSize
+	}
+
+	// fetch one extra message to know whether there is another page
+	messages, err := ConversationRepoImpl.ListMessages(ctx, conversation.ID, cursor, limit+1)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	page := MessagePageResponse{Messages: []MessageResponse{}}
+	if len(messages) > limit {
+		messages = messages[:limit]
+		lastMessage := messages[limit-1]
+		page.NextCursor = messageCursor{CreatedAt: lastMessage.CreatedAt, ID: lastMessage.ID}.Encode()
+	}
+
+	for _, message := range messages {
+		page.Messages = append(page.Messages, messageEntityToResponse(message))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(model.DataResponse{
+		Message: "ok",
+		Data:    page,
+	})
+}
+
+func SendMessage(c *fiber.Ctx) error {
+	conversationID := c.Params("conversation_id")
+
+	claims, err := jwt.GetLoggedInUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "forbidden",
+		})
+	}
+
+	var payload SendMessageRequest
+	if err := c.BodyParser(&payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "invalid_request_body",
+		})
+	}
+
+	// validation for request body
+	if err := validation.Validate(payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "failed_request_body_validation",
+		})
+	}
+
+	ctx := c.Context()
+
+	conversation, err := getParticipatingConversation(ctx, conversationID, claims.UserID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return c.Status(fiber.StatusNotFound).JSON(model.ErrorResponse{
+				Message: "conversation not found",
+				Code:    "entity_not_found",
+			})
+		}
+
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	message, err := sendMessage(ctx, newMessage(conversation.ID, claims.UserID, payload))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(model.DataResponse{
+		Message: "Message sent successfully",
+		Data:    messageEntityToResponse(message),
+	})
+}
+
+func sendMessage(ctx context.Context, message Message) (Message, error) {
+	tx, err := TrxProvider.NewTransaction(ctx)
+	if err != nil {
+		return Message{}, err
+	}
+	defer tx.Rollback()
+
+	err = ConversationRepoImpl.CreateMessage(ctx, tx, message)
+	if err != nil {
+		return Message{}, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return Message{}, err
+	}
+
+	return message, nil
+}
+
+func MarkConversationRead(c *fiber.Ctx) error {
+	conversationID := c.Params("conversation_id")
+
+	claims, err := jwt.GetLoggedInUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "forbidden",
+		})
+	}
+
+	ctx := c.Context()
+
+	conversation, err := getParticipatingConversation(ctx, conversationID, claims.UserID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return c.Status(fiber.StatusNotFound).JSON(model.ErrorResponse{
+				Message: "conversation not found",
+				Code:    "entity_not_found",
+			})
+		}
+
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	err = ConversationRepoImpl.MarkConversationRead(ctx, conversation, claims.UserID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(model.DataResponse{
+		Message: "ok",
+	})
+}
+
+// getParticipatingConversation returns sql.ErrNoRows when the conversation doesn't exist or the user is
+// not part of it, so the existence of other users' conversations isn't leaked
+func getParticipatingConversation(ctx context.Context, conversationID, userID string) (Conversation, error) {
+	conversation, err := ConversationRepoImpl.GetConversationByID(ctx, conversationID)
+	if err != nil {
+		return conversation, err
+	}
+
+	if !conversation.IsParticipant(userID) {
+		return Conversation{}, sql.ErrNoRows
+	}
+
+	return conversation, nil
+}
+
+func newMessage(conversationID, senderID string, payload SendMessageRequest) Message {
+	return Message{
+		ID:             uuid.NewString(),
+		ConversationID: conversationID,
+		SenderID:       senderID,
+		Body:           payload.Body,
+		ImageURL:       payload.ImageURL,
+		// postgres only keeps microseconds, keep the same precision so the cursor matches what's stored
+		CreatedAt: time.Now().Truncate(time.Microsecond),
+	}
+}
+
+func messageEntityToResponse(message Message) MessageResponse {
+	return MessageResponse{
+		ID:        message.ID,
+		SenderID:  message.SenderID,
+		Message:   message.Body,
+		ImageURL:  message.ImageURL,
+		CreatedAt: message.CreatedAt,
+	}
+}
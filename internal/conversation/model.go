@@ -0,0 +1,95 @@
+package conversation
+
+import (
+	"encoding/base64"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+type StartConversationRequest struct {
+	ProductID string `json:"productId" validate:"required"`
+	SendMessageRequest
+}
+
+type SendMessageRequest struct {
+	Body string `json:"message" validate:"required_without=ImageURL,max=2000"`
+	// ImageURL is an image uploaded beforehand through the image upload endpoint
+	ImageURL *string `json:"imageUrl" validate:"omitempty,url"`
+}
+
+type ListConversationsRequest struct {
+	Limit  int `query:"limit"`
+	Offset int `query:"offset"`
+}
+
+type ListMessagesRequest struct {
+	Cursor string `query:"cursor"`
+	Limit  int    `query:"limit" validate:"omitempty,gte=1,lte=100"`
+}
+
+// Conversation is a thread about one product between a buyer and the product's seller.
+// Each participant's last read time is used to count their unread messages.
+type Conversation struct {
+	ID               string     `db:"id"`
+	ProductID        string     `db:"product_id"`
+	BuyerID          string     `db:"buyer_id"`
+	SellerID         string     `db:"seller_id"`
+	BuyerLastReadAt  *time.Time `db:"buyer_last_read_at"`
+	SellerLastReadAt *time.Time `db:"seller_last_read_at"`
+	LastMessageAt    *time.Time `db:"last_message_at"`
+	CreatedAt        time.Time  `db:"created_at"`
+}
+
+func (c Conversation) IsParticipant(userID string) bool {
+	return c.BuyerID == userID || c.SellerID == userID
+}
+
+type ConversationSummary struct {
+	Conversation
+	ProductName     string  `db:"product_name"`
+	LastMessageBody *string `db:"last_message_body"`
+	UnreadCount     int     `db:"unread_count"`
+}
+
+type Message struct {
+	ID             string    `db:"id"`
+	ConversationID string    `db:"conversation_id"`
+	SenderID       string    `db:"sender_id"`
+	Body           string    `db:"body"`
+	ImageURL       *string   `db:"image_url"`
+	CreatedAt      time.Time `db:"created_at"`
+}
+
+// messageCursor points at the last message of a page; the next page continues with the messages before it
+type messageCursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+func (c messageCursor) Encode() string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + c.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeMessageCursor(cursor string) (messageCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return messageCursor{}, ErrInvalidCursor
+	}
+
+	parts := strings.SplitN(string(raw), "|", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return messageCursor{}, ErrInvalidCursor
+	}
+
+	createdAt, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return messageCursor{}, ErrInvalidCursor
+	}
+
+	return messageCursor{CreatedAt: createdAt, ID: parts[1]}, nil
+}
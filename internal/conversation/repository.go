@@ -0,0 +1,319 @@
+package conversation
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+type ConversationRepo struct {
+	db *sqlx.DB
+}
+
+func NewConversationRepo(db *sqlx.DB) ConversationRepo {
+	return ConversationRepo{db: db}
+}
+
+// GetProductSellerID returns the seller of a product that is currently published.
+// Conversations can't import the product package, so this checks the products table directly.
+func (r ConversationRepo) GetProductSellerID(ctx context.Context, productID string) (string, error) {
+	query := `
+		SELECT
+			user_id
+		FROM
+			products
+		WHERE
+			id = $1
+			AND deleted_at IS NULL
+			AND status = 'active'
+			AND (publish_at IS NULL OR publish_at <= NOW())
+			AND (unpublish_at IS NULL OR unpublish_at > NOW())
+	`
+
+	var sellerID string
+	err := r.db.GetContext(ctx, &sellerID, query, productID)
+	if err != nil {
+		return sellerID, err
+	}
+
+	return sellerID, nil
+}
+
+// GetOrCreateConversation returns the buyer's existing conversation about the product, or creates it
+func (r ConversationRepo) GetOrCreateConversation(ctx context.Context, tx *sql.Tx, conversation Conversation) (Conversation, error) {
+	var result Conversation
+
+	insertQuery := `
+		INSERT INTO conversations
+			(id, product_id, buyer_id, seller_id)
+		VALUES
+			($1, $2, $3, $4)
+		ON CONFLICT (product_id, buyer_id) DO NOTHING
+	`
+
+	_, err := tx.ExecContext(ctx, insertQuery, conversation.ID, conversation.ProductID, conversation.BuyerID, conversation.SellerID)
+	if err != nil {
+		return result, err
+	}
+
+	query := `
+		SELECT
+			id,
+			product_id,
+			buyer_id,
+			seller_id,
+			buyer_last_read_at,
+			seller_last_read_at,
+			last_message_at,
+			created_at
+		FROM
+			conversations
+		WHERE
+			product_id = $1
+			AND buyer_id = $2
+		FOR UPDATE
+	`
+
+	row := tx.QueryRowContext(ctx, query, conversation.ProductID, conversation.BuyerID)
+	err = row.Scan(
+		&result.ID,
+		&result.ProductID,
+		&result.BuyerID,
+		&result.SellerID,
+		&result.BuyerLastReadAt,
+		&result.SellerLastReadAt,
+		&result.LastMessageAt,
+		&result.CreatedAt,
+	)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r ConversationRepo) GetConversationByID(ctx context.Context, conversationID string) (Conversation, error) {
+	var result Conversation
+
+	query := `
+		SELECT
+			id,
+			product_id,
+			buyer_id,
+			seller_id,
+			buyer_last_read_at,
+			seller_last_read_at,
+			last_message_at,
+			created_at
+		FROM
+			conversations
+		WHERE
+			id = $1
+		LIMIT 1
+	`
+
+	err := r.db.GetContext(ctx, &result, query, conversationID)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// ListConversations lists the user's conversations as either buyer or seller, most recently active first
+func (r ConversationRepo) ListConversations(ctx context.Context, userID string, req ListConversationsRequest) ([]ConversationSummary, int, error) {
+	var conversations []ConversationSummary
+
+	countQuery := `
+		SELECT
+			COUNT(*)
+		FROM
+			conversations
+		WHERE
+			buyer_id = $1
+			OR seller_id = $1
+	`
+
+	var count int
+	err := r.db.GetContext(ctx, &count, countQuery, userID)
+	if err != nil {
+		return conversations, count, err
+	}
+
+	limit := req.Limit
+	if limit <= 0 {
+		limit = 10
+	}
+
+	offset := req.Offset
+	if offset < 0 {
+		offset = 0
+	}
+
+	query := `
+		SELECT
+			c.id,
+			c.product_id,
+			c.buyer_id,
+			c.seller_id,
+			c.buyer_last_read_at,
+			c.seller_last_read_at,
+			c.last_message_at,
+			c.created_at,
+			COALESCE(p.name, '') AS product_name,
+			lm.body AS last_message_body,
+			(
+				SELECT
+					COUNT(*)
+				FROM
+					conversation_messages m
+				WHERE
+					m.conversation_id = c.id
+					AND m.sender_id <> $1
+					AND m.created_at > COALESCE(
+						CASE WHEN c.buyer_id = $1 THEN c.buyer_last_read_at ELSE c.seller_last_read_at END,
+						'-infinity'
+					)
+			) AS unread_count
+		FROM
+			conversations c
+			LEFT JOIN products p
+			ON p.id = c.product_id
+			LEFT JOIN LATERAL (
+				SELECT
+					body
+				FROM
+					conversation_messages
+				WHERE
+					conversation_id = c.id
+				ORDER BY
+					created_at DESC, id DESC
+				LIMIT 1
+			) lm ON TRUE
+		WHERE
+			c.buyer_id = $1
+			OR c.seller_id = $1
+		ORDER BY
+			COALESCE(c.last_message_at, c.created_at) DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	err = r.db.SelectContext(ctx, &conversations, query, userID, limit, offset)
+	if err != nil {
+		return conversations, count, err
+	}
+
+	return conversations, count, nil
+}
+
+func (r ConversationRepo) CreateMessage(ctx context.Context, tx *sql.Tx, message Message) error {
+	query := `
+		INSERT INTO conversation_messages
+			(id, conversation_id, sender_id, body, image_url, created_at)
+		VALUES
+			(:id, :conversation_id, :sender_id, :body, :image_url, :created_at)
+	`
+
+	updatedQuery, args, err := sqlx.Named(query, message)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, sqlx.Rebind(sqlx.DOLLAR, updatedQuery), args...)
+	if err != nil {
+		return err
+	}
+
+	// the sender has obviously read everything up to their own message
+	senderQuery := `
+		UPDATE conversations
+		SET
+			last_message_at = $1,
+			buyer_last_read_at = CASE WHEN buyer_id = $2 THEN $1 ELSE buyer_last_read_at END,
+			seller_last_read_at = CASE WHEN seller_id = $2 THEN $1 ELSE seller_last_read_at END
+		WHERE
+			id = $3
+	`
+
+	result, err := tx.ExecContext(ctx, senderQuery, message.CreatedAt, message.SenderID, message.ConversationID)
+	if err != nil {
+		return err
+	}
+
+	affectedRows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affectedRows != 1 {
+		return errors.New("error affected row count is not equal to 1")
+	}
+
+	return nil
+}
+
+// ListMessages lists the conversation's messages newest first, starting after the cursor if one is given
+func (r ConversationRepo) ListMessages(ctx context.Context, conversationID string, cursor *messageCursor, limit int) ([]Message, error) {
+	var messages []Message
+
+	// far enough in the future that every message comes before it
+	before := messageCursor{CreatedAt: time.Date(9999, 1, 1, 0, 0, 0, 0, time.UTC)}
+	if cursor != nil {
+		before = *cursor
+	}
+
+	query := `
+		SELECT
+			id,
+			conversation_id,
+			sender_id,
+			body,
+			image_url,
+			created_at
+		FROM
+			conversation_messages
+		WHERE
+			conversation_id = $1
+			AND (created_at, id) < ($2, $3)
+		ORDER BY
+			created_at DESC, id DESC
+		LIMIT $4
+	`
+
+	err := r.db.SelectContext(ctx, &messages, query, conversationID, before.CreatedAt, before.ID, limit)
+	if err != nil {
+		return messages, err
+	}
+
+	return messages, nil
+}
+
+// MarkConversationRead marks every message in the conversation as read for the user
+func (r ConversationRepo) MarkConversationRead(ctx context.Context, conversation Conversation, userID string) error {
+	readColumn := "buyer_last_read_at"
+	if conversation.SellerID == userID {
+		readColumn = "seller_last_read_at"
+	}
+
+	// use the latest message's time rather than NOW(), so a message sent in the meantime stays unread
+	query := fmt.Sprintf(`
+		UPDATE conversations
+		SET
+			%[1]s = GREATEST(
+				%[1]s,
+				(SELECT MAX(created_at) FROM conversation_messages WHERE conversation_id = $1)
+			)
+		WHERE
+			id = $1
+	`, readColumn)
+
+	_, err := r.db.ExecContext(ctx, query, conversation.ID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,29 @@
+package conversation
+
+import "time"
+
+type ConversationResponse struct {
+	ID            string           `json:"id"`
+	ProductID     string           `json:"productId"`
+	ProductName   string           `json:"productName,omitempty"`
+	BuyerID       string           `json:"buyerId"`
+	SellerID      string           `json:"sellerId"`
+	LastMessage   *string          `json:"lastMessage,omitempty"`
+	LastMessageAt *time.Time       `json:"lastMessageAt"`
+	UnreadCount   int              `json:"unreadCount"`
+	Message       *MessageResponse `json:"message,omitempty"`
+}
+
+type MessageResponse struct {
+	ID        string    `json:"id"`
+	SenderID  string    `json:"senderId"`
+	Message   string    `json:"message"`
+	ImageURL  *string   `json:"imageUrl,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type MessagePageResponse struct {
+	Messages []MessageResponse `json:"messages"`
+	// NextCursor fetches the older messages, it is empty on the last page
+	NextCursor string `json:"nextCursor,omitempty"`
+}
@@ -0,0 +1,223 @@
+package coupon
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/model"
+	"github.com/ahmadnaufal/openidea-shopifyx/pkg/jwt"
+	"github.com/ahmadnaufal/openidea-shopifyx/pkg/money"
+	"github.com/ahmadnaufal/openidea-shopifyx/pkg/validation"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+var (
+	CouponRepoImpl *CouponRepo
+)
+
+func RegisterRoute(r *fiber.App, jwtProvider jwt.JWTProvider) {
+	couponGroup := r.Group("/v1/coupon")
+	authMiddleware := jwtProvider.Middleware()
+	couponGroup.Use(authMiddleware)
+
+	couponGroup.Post("/", CreateCoupon)
+	couponGroup.Get("/", ListCoupons)
+	couponGroup.Delete("/:coupon_id", DeleteCoupon)
+}
+
+func CreateCoupon(c *fiber.Ctx) error {
+	var payload CouponRequest
+
+	claims, err := jwt.GetLoggedInUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "forbidden",
+		})
+	}
+
+	if err := c.BodyParser(&payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "invalid_request_body",
+		})
+	}
+
+	// validation for request body
+	if err := validation.Validate(payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "failed_request_body_validation",
+		})
+	}
+
+	if payload.DiscountType == DiscountTypePercent && payload.Value > 100 {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: "percent discount cannot be more than 100",
+			Code:    "failed_request_body_validation",
+		})
+	}
+
+	ctx := c.Context()
+	code := strings.ToUpper(payload.Code)
+
+	// coupon codes must be unique per seller
+	existingCoupon, err := CouponRepoImpl.GetCouponByCode(ctx, claims.UserID, code)
+	if err != nil && err != sql.ErrNoRows {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+	if existingCoupon.ID != "" {
+		return c.Status(fiber.StatusConflict).JSON(model.ErrorResponse{
+			Message: "coupon code already used",
+			Code:    "coupon_code_already_exists",
+		})
+	}
+
+	// per-product coupons can only target the seller's own product
+	if payload.ProductID != nil {
+		owned, err := CouponRepoImpl.IsProductOwnedByUser(ctx, *payload.ProductID, claims.UserID)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+				Message: "something wrong with the server. Please contact admin",
+				Code:    "internal_server_error",
+			})
+		}
+		if !owned {
+			return c.Status(fiber.StatusNotFound).JSON(model.ErrorResponse{
+				Message: "product not found",
+				Code:    "entity_not_found",
+			})
+		}
+	}
+
+	var currency *money.Currency
+	if payload.DiscountType == DiscountTypeFixed {
+		couponCurrency := money.Currency(payload.Currency)
+		currency = &couponCurrency
+	}
+
+	coupon := Coupon{
+		ID:           uuid.NewString(),
+		UserID:       claims.UserID,
+		ProductID:    payload.ProductID,
+		Code:         code,
+		DiscountType: payload.DiscountType,
+		Value:        payload.Value,
+		Currency:     currency,
+		UsageLimit:   payload.UsageLimit,
+		ExpiresAt:    payload.ExpiresAt,
+	}
+	err = CouponRepoImpl.CreateCoupon(ctx, coupon)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(model.DataResponse{
+		Message: "success",
+		Data:    couponEntityToResponse(coupon),
+	})
+}
+
+func ListCoupons(c *fiber.Ctx) error {
+	claims, err := jwt.GetLoggedInUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "forbidden",
+		})
+	}
+
+	ctx := c.Context()
+	coupons, err := CouponRepoImpl.GetCouponsByUserID(ctx, claims.UserID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	couponResponses := make([]CouponResponse, len(coupons))
+	for i, coupon := range coupons {
+		couponResponses[i] = couponEntityToResponse(coupon)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(model.DataResponse{
+		Message: "success",
+		Data:    couponResponses,
+	})
+}
+
+func DeleteCoupon(c *fiber.Ctx) error {
+	couponID := c.Params("coupon_id")
+
+	claims, err := jwt.GetLoggedInUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "forbidden",
+		})
+	}
+
+	ctx := c.Context()
+	coupon, err := CouponRepoImpl.GetCouponByID(ctx, couponID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return c.Status(fiber.StatusNotFound).JSON(model.ErrorResponse{
+				Message: "coupon not found",
+				Code:    "entity_not_found",
+			})
+		}
+
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	// check coupon ownership
+	if coupon.UserID != claims.UserID {
+		return c.Status(fiber.StatusForbidden).JSON(model.ErrorResponse{
+			Message: "cannot delete a coupon that is owned by another user",
+			Code:    "delete_coupon_forbidden",
+		})
+	}
+
+	err = CouponRepoImpl.DeleteCoupon(ctx, coupon.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(model.DataResponse{
+		Message: "success",
+	})
+}
+
+func couponEntityToResponse(coupon Coupon) CouponResponse {
+	var currency *string
+	if coupon.Currency != nil {
+		couponCurrency := string(*coupon.Currency)
+		currency = &couponCurrency
+	}
+
+	return CouponResponse{
+		CouponID:     coupon.ID,
+		Code:         coupon.Code,
+		DiscountType: coupon.DiscountType,
+		Value:        coupon.Value,
+		Currency:     currency,
+		ProductID:    coupon.ProductID,
+		UsageLimit:   coupon.UsageLimit,
+		UsedCount:    coupon.UsedCount,
+		ExpiresAt:    coupon.ExpiresAt,
+	}
+}
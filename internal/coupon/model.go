@@ -0,0 +1,74 @@
+package coupon
+
+import (
+	"time"
+
+	"github.com/ahmadnaufal/openidea-shopifyx/pkg/money"
+	"github.com/pkg/errors"
+)
+
+const (
+	DiscountTypePercent = "percent"
+	DiscountTypeFixed   = "fixed"
+)
+
+var (
+	ErrCouponExpired       = errors.New("coupon has expired")
+	ErrCouponUsageExceeded = errors.New("coupon usage limit has been reached")
+	ErrCouponNotApplicable = errors.New("coupon cannot be applied to this product")
+	ErrCurrencyMismatch    = errors.New("coupon currency does not match the product currency")
+)
+
+type CouponRequest struct {
+	Code         string `json:"code" validate:"required,alphanum,min=4,max=32"`
+	DiscountType string `json:"discountType" validate:"oneof=percent fixed"`
+	// Value is a percentage for percent coupons, or an amount in the currency's minor units for fixed coupons
+	Value      int64      `json:"value" validate:"required,gt=0"`
+	Currency   string     `json:"currency" validate:"required_if=DiscountType fixed,omitempty,oneof=IDR SGD MYR"`
+	ProductID  *string    `json:"productId" validate:"omitempty,min=1"`
+	UsageLimit *int       `json:"usageLimit" validate:"omitempty,gte=1"`
+	ExpiresAt  *time.Time `json:"expiresAt"`
+}
+
+type Coupon struct {
+	ID           string          `db:"id"`
+	UserID       string          `db:"user_id"`
+	ProductID    *string         `db:"product_id"`
+	Code         string          `db:"code"`
+	DiscountType string          `db:"discount_type"`
+	Value        int64           `db:"value"`
+	Currency     *money.Currency `db:"currency"`
+	UsageLimit   *int            `db:"usage_limit"`
+	UsedCount    int             `db:"used_count"`
+	ExpiresAt    *time.Time      `db:"expires_at"`
+}
+
+// CheckApplicable returns an error if the coupon can't be used on the given product at the given time
+func (c Coupon) CheckApplicable(productID string, now time.Time) error {
+	if c.ExpiresAt != nil && !now.Before(*c.ExpiresAt) {
+		return ErrCouponExpired
+	}
+
+	if c.UsageLimit != nil && c.UsedCount >= *c.UsageLimit {
+		return ErrCouponUsageExceeded
+	}
+
+	if c.ProductID != nil && *c.ProductID != productID {
+		return ErrCouponNotApplicable
+	}
+
+	return nil
+}
+
+// Discount calculates the discount for the given amount. The discount never exceeds the amount itself
+func (c Coupon) Discount(amount money.Money) (money.Money, error) {
+	if c.DiscountType == DiscountTypePercent {
+		return amount.Percentage(c.Value).Min(amount)
+	}
+
+	if c.Currency == nil || *c.Currency != amount.Currency {
+		return money.Money{}, ErrCurrencyMismatch
+	}
+
+	return money.New(c.Value, amount.Currency).Min(amount)
+}
@@ -0,0 +1,255 @@
+package coupon
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+type CouponRepo struct {
+	db *sqlx.DB
+}
+
+func NewCouponRepo(db *sqlx.DB) CouponRepo {
+	return CouponRepo{db: db}
+}
+
+func (r CouponRepo) CreateCoupon(ctx context.Context, coupon Coupon) error {
+	query := `
+		INSERT INTO coupons
+			(id, user_id, product_id, code, discount_type, value, currency, usage_limit, expires_at)
+		VALUES
+			(:id, :user_id, :product_id, :code, :discount_type, :value, :currency, :usage_limit, :expires_at)
+	`
+
+	updatedQuery, args, err := sqlx.Named(query, coupon)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx, sqlx.Rebind(sqlx.DOLLAR, updatedQuery), args...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r CouponRepo) GetCouponsByUserID(ctx context.Context, userID string) ([]Coupon, error) {
+	var result []Coupon
+
+	query := `
+		SELECT
+			id,
+			user_id,
+			product_id,
+			code,
+			discount_type,
+			value,
+			currency,
+			usage_limit,
+			used_count,
+			expires_at
+		FROM
+			coupons
+		WHERE
+			user_id = $1
+			AND deleted_at IS NULL
+		ORDER BY
+			created_at DESC
+	`
+
+	err := r.db.SelectContext(ctx, &result, query, userID)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r CouponRepo) GetCouponByID(ctx context.Context, couponID string) (Coupon, error) {
+	var result Coupon
+
+	query := `
+		SELECT
+			id,
+			user_id,
+			product_id,
+			code,
+			discount_type,
+			value,
+			currency,
+			usage_limit,
+			used_count,
+			expires_at
+		FROM
+			coupons
+		WHERE
+			id = $1
+			AND deleted_at IS NULL
+		LIMIT 1
+	`
+
+	err := r.db.GetContext(ctx, &result, query, couponID)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r CouponRepo) GetCouponByCode(ctx context.Context, userID, code string) (Coupon, error) {
+	var result Coupon
+
+	query := `
+		SELECT
+			id
+		FROM
+			coupons
+		WHERE
+			user_id = $1
+			AND code = $2
+			AND deleted_at IS NULL
+		LIMIT 1
+	`
+
+	err := r.db.GetContext(ctx, &result, query, userID, code)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// GetCouponByCodeForUpdate fetches a seller's coupon by its code and locks it until the transaction ends,
+// so concurrent orders can't exceed the usage limit
+func (r CouponRepo) GetCouponByCodeForUpdate(ctx context.Context, tx *sql.Tx, userID, code string) (Coupon, error) {
+	var result Coupon
+
+	query := `
+		SELECT
+			id,
+			user_id,
+			product_id,
+			code,
+			discount_type,
+			value,
+			currency,
+			usage_limit,
+			used_count,
+			expires_at
+		FROM
+			coupons
+		WHERE
+			user_id = $1
+			AND code = $2
+			AND deleted_at IS NULL
+		LIMIT 1
+		FOR UPDATE
+	`
+
+	row := tx.QueryRowContext(ctx, query, userID, code)
+	err := row.Scan(
+		&result.ID,
+		&result.UserID,
+		&result.ProductID,
+		&result.Code,
+		&result.DiscountType,
+		&result.Value,
+		&result.Currency,
+		&result.UsageLimit,
+		&result.UsedCount,
+		&result.ExpiresAt,
+	)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r CouponRepo) IncrementCouponUsage(ctx context.Context, tx *sql.Tx, couponID string) error {
+	query := `
+		UPDATE
+			coupons
+		SET
+			used_count = used_count + 1,
+			updated_at = NOW()
+		WHERE
+			id = $1
+			AND deleted_at IS NULL
+	`
+
+	result, err := tx.ExecContext(ctx, query, couponID)
+	if err != nil {
+		return err
+	}
+
+	affectedRows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affectedRows != 1 {
+		return errors.New("error affected row count is not equal to 1")
+	}
+
+	return nil
+}
+
+func (r CouponRepo) DeleteCoupon(ctx context.Context, couponID string) error {
+	query := `
+		UPDATE
+			coupons
+		SET
+			deleted_at = NOW()
+		WHERE
+			id = $1
+			AND deleted_at IS NULL
+	`
+
+	_, err := r.db.ExecContext(ctx, query, couponID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r CouponRepo) IsProductOwnedByUser(ctx context.Context, productID, userID string) (bool, error) {
+	query := `
+		SELECT
+			EXISTS (
+				SELECT 1 FROM products WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+			)
+	`
+
+	var exists bool
+	err := r.db.GetContext(ctx, &exists, query, productID, userID)
+	if err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
+
+// ReleaseCouponUsage gives back the usage of an order that was cancelled
+func (r CouponRepo) ReleaseCouponUsage(ctx context.Context, tx *sql.Tx, couponID string) error {
+	query := `
+		UPDATE
+			coupons
+		SET
+			used_count = GREATEST(used_count - 1, 0),
+			updated_at = NOW()
+		WHERE
+			id = $1
+	`
+
+	_, err := tx.ExecContext(ctx, query, couponID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
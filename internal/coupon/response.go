@@ -0,0 +1,15 @@
+package coupon
+
+import "time"
+
+type CouponResponse struct {
+	CouponID     string     `json:"couponId"`
+	Code         string     `json:"code"`
+	DiscountType string     `json:"discountType"`
+	Value        int64      `json:"value"`
+	Currency     *string    `json:"currency"`
+	ProductID    *string    `json:"productId"`
+	UsageLimit   *int       `json:"usageLimit"`
+	UsedCount    int        `json:"usedCount"`
+	ExpiresAt    *time.Time `json:"expiresAt"`
+}
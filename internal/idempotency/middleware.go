@@ -0,0 +1,167 @@
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"log"
+	"time"
+
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/model"
+	"github.com/ahmadnaufal/openidea-shopifyx/pkg/jwt"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// inProgressLease is how long a key is held for a request being processed. It outlives any request,
+// and lets retries take the key over if the request never finished, e.g. when the server crashed.
+const inProgressLease = 2 * time.Minute
+
+var (
+	IdempotencyRepoImpl *IdempotencyRepo
+
+	// KeyTTL is how long a key is remembered, retries after that are treated as new requests
+	KeyTTL time.Duration
+)
+
+// Middleware makes the route safe to retry: requests with an Idempotency-Key header are only
+// processed once per user and key, and retries get the first response replayed. It must be
+// registered after the auth middleware, as keys are scoped to the logged in user.
+func Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		idempotencyKey := c.Get(HeaderIdempotencyKey)
+		if idempotencyKey == "" {
+			return c.Next()
+		}
+
+		if len(idempotencyKey) > maxKeyLength {
+			return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+				Message: "idempotency key must be at most 255 characters",
+				Code:    "invalid_idempotency_key",
+			})
+		}
+
+		claims, err := jwt.GetLoggedInUser(c)
+		if err != nil {
+			return c.Status(fiber.StatusForbidden).JSON(model.ErrorResponse{
+				Message: err.Error(),
+				Code:    "forbidden",
+			})
+		}
+
+		ctx := c.Context()
+		now := time.Now()
+		requestHash := hashRequest(c)
+		leaseToken := uuid.NewString()
+
+		reserved, err := IdempotencyRepoImpl.ReserveKey(ctx, Key{
+			UserID:      claims.UserID,
+			Key:         idempotencyKey,
+			RequestHash: requestHash,
+			Status:      StatusInProgress,
+			LeaseToken:  leaseToken,
+			CreatedAt:   now,
+			ExpiresAt:   now.Add(inProgressLease),
+		})
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+				Message: "something wrong with the server. Please contact admin",
+				Code:    "internal_server_error",
+			})
+		}
+
+		if !reserved {
+			return replayResponse(c, claims.UserID, idempotencyKey, requestHash)
+		}
+
+		// a panicking handler didn't finish the request, so the key is released for the retry
+		defer func() {
+			if r := recover(); r != nil {
+				releaseKey(ctx, claims.UserID, idempotencyKey, leaseToken)
+				panic(r)
+			}
+		}()
+
+		err = c.Next()
+
+		// only remember responses the client shouldn't retry, so failures can be retried with the same key
+		statusCode := c.Response().StatusCode()
+		if err != nil || statusCode >= fiber.StatusInternalServerError {
+			releaseKey(ctx, claims.UserID, idempotencyKey, leaseToken)
+			return err
+		}
+
+		// the request went through, so its response is returned even if it can't be remembered;
+		// retries then process it again once the in-progress lease runs out
+		body := append([]byte{}, c.Response().Body()...)
+		contentType := string(c.Response().Header.ContentType())
+		completed, err := IdempotencyRepoImpl.CompleteKey(ctx, claims.UserID, idempotencyKey, leaseToken, statusCode, contentType, body, time.Now().Add(KeyTTL))
+		if err != nil {
+			log.Printf("error completing idempotency key %s of user %s: %v", idempotencyKey, claims.UserID, err)
+		} else if !completed {
+			log.Printf("idempotency key %s of user %s was taken over before its request finished", idempotencyKey, claims.UserID)
+		}
+
+		return nil
+	}
+}
+
+// releaseKey forgets the key of a failed request. If it can't, the key is taken over once its lease runs out.
+func releaseKey(ctx context.Context, userID, idempotencyKey, leaseToken string) {
+	err := IdempotencyRepoImpl.ReleaseKey(ctx, userID, idempotencyKey, leaseToken)
+	if err != nil {
+		log.Printf("error releasing idempotency key %s of user %s: %v", idempotencyKey, userID, err)
+	}
+}
+
+func replayResponse(c *fiber.Ctx, userID, idempotencyKey, requestHash string) error {
+	key, err := IdempotencyRepoImpl.GetKey(c.Context(), userID, idempotencyKey)
+	if err != nil {
+		// the first request failed and released the key in between, so the client can retry with the same key
+		if err == sql.ErrNoRows {
+			return c.Status(fiber.StatusConflict).JSON(model.ErrorResponse{
+				Message: "the request with this idempotency key has just failed, please retry",
+				Code:    "idempotency_key_released",
+			})
+		}
+
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	if key.RequestHash != requestHash {
+		return c.Status(fiber.StatusConflict).JSON(model.ErrorResponse{
+			Message: "idempotency key was already used for a different request",
+			Code:    "idempotency_key_reused",
+		})
+	}
+
+	if key.Status != StatusCompleted {
+		return c.Status(fiber.StatusConflict).JSON(model.ErrorResponse{
+			Message: "a request with this idempotency key is in progress",
+			Code:    "idempotency_key_in_progress",
+		})
+	}
+
+	c.Set(HeaderIdempotentReplayed, "true")
+	if key.ResponseType != nil {
+		c.Set(fiber.HeaderContentType, *key.ResponseType)
+	}
+
+	return c.Status(*key.ResponseStatus).Send(key.ResponseBody)
+}
+
+// hashRequest identifies the request by its method, path and body, so a key can't be reused for another request
+func hashRequest(c *fiber.Ctx) string {
+	hash := sha256.New()
+	hash.Write([]byte(c.Method()))
+	hash.Write([]byte{0})
+	hash.Write([]byte(c.Path()))
+	hash.Write([]byte{0})
+	hash.Write(c.Body())
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
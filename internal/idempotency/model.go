@@ -0,0 +1,31 @@
+package idempotency
+
+import "time"
+
+// HeaderIdempotencyKey is the request header clients set to make a request safe to retry
+const HeaderIdempotencyKey = "Idempotency-Key"
+
+// HeaderIdempotentReplayed is set on responses replayed from an earlier request with the same key
+const HeaderIdempotentReplayed = "Idempotent-Replayed"
+
+const maxKeyLength = 255
+
+const (
+	StatusInProgress = "in_progress"
+	StatusCompleted  = "completed"
+)
+
+type Key struct {
+	UserID      string `db:"user_id"`
+	Key         string `db:"idempotency_key"`
+	RequestHash string `db:"request_hash"`
+	Status      string `db:"status"`
+	// LeaseToken identifies the request holding the key, so a request that outlived its lease
+	// can't overwrite the key of the request that took it over
+	LeaseToken     string    `db:"lease_token"`
+	ResponseStatus *int      `db:"response_status"`
+	ResponseType   *string   `db:"response_content_type"`
+	ResponseBody   []byte    `db:"response_body"`
+	CreatedAt      time.Time `db:"created_at"`
+	ExpiresAt      time.Time `db:"expires_at"`
+}
@@ -0,0 +1,168 @@
+package idempotency
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type IdempotencyRepo struct {
+	db *sqlx.DB
+}
+
+func NewIdempotencyRepo(db *sqlx.DB) IdempotencyRepo {
+	return IdempotencyRepo{db: db}
+}
+
+// ReserveKey claims the key for a new request. It returns false if the key is already used by
+// a request that hasn't expired; an expired key, either a completed one past its TTL or one whose
+// request never finished within its lease, is taken over as if it was never used.
+func (r IdempotencyRepo) ReserveKey(ctx context.Context, key Key) (bool, error) {
+	query := `
+		INSERT INTO idempotency_keys
+			(
+				user_id,
+				idempotency_key,
+				request_hash,
+				status,
+				lease_token,
+				created_at,
+				expires_at
+			)
+		VALUES
+			(
+				:user_id,
+				:idempotency_key,
+				:request_hash,
+				:status,
+				:lease_token,
+				:created_at,
+				:expires_at
+			)
+		ON CONFLICT (user_id, idempotency_key) DO UPDATE
+		SET
+			request_hash = EXCLUDED.request_hash,
+			status = EXCLUDED.status,
+			lease_token = EXCLUDED.lease_token,
+			response_status = NULL,
+			response_content_type = NULL,
+			response_body = NULL,
+			created_at = EXCLUDED.created_at,
+			expires_at = EXCLUDED.expires_at
+		WHERE
+			idempotency_keys.expires_at <= EXCLUDED.created_at
+	`
+
+	updatedQuery, args, err := sqlx.Named(query, key)
+	if err != nil {
+		return false, err
+	}
+
+	result, err := r.db.ExecContext(ctx, sqlx.Rebind(sqlx.DOLLAR, updatedQuery), args...)
+	if err != nil {
+		return false, err
+	}
+
+	affectedRows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affectedRows == 1, nil
+}
+
+func (r IdempotencyRepo) GetKey(ctx context.Context, userID, key string) (Key, error) {
+	var result Key
+
+	query := `
+		SELECT
+			user_id,
+			idempotency_key,
+			request_hash,
+			status,
+			response_status,
+			response_content_type,
+			response_body,
+			created_at,
+			expires_at
+		FROM
+			idempotency_keys
+		WHERE
+			user_id = $1
+			AND idempotency_key = $2
+		LIMIT 1
+	`
+
+	err := r.db.GetContext(ctx, &result, query, userID, key)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// CompleteKey stores the response to replay for retries of the request, until the key expires. It returns
+// false if the request no longer holds the key, because its lease ran out and a retry took the key over.
+func (r IdempotencyRepo) CompleteKey(ctx context.Context, userID, key, leaseToken string, responseStatus int, contentType string, body []byte, expiresAt time.Time) (bool, error) {
+	query := `
+		UPDATE idempotency_keys
+		SET
+			status = $1,
+			response_status = $2,
+			response_content_type = $3,
+			response_body = $4,
+			expires_at = $5
+		WHERE
+			user_id = $6
+			AND idempotency_key = $7
+			AND lease_token = $8
+			AND status = $9
+	`
+
+	result, err := r.db.ExecContext(ctx, query, StatusCompleted, responseStatus, contentType, body, expiresAt, userID, key, leaseToken, StatusInProgress)
+	if err != nil {
+		return false, err
+	}
+
+	affectedRows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affectedRows == 1, nil
+}
+
+// ReleaseKey forgets the key of a request that failed, so it can be retried with the same key.
+// Only the request holding the key can release it.
+func (r IdempotencyRepo) ReleaseKey(ctx context.Context, userID, key, leaseToken string) error {
+	query := `
+		DELETE FROM idempotency_keys
+		WHERE
+			user_id = $1
+			AND idempotency_key = $2
+			AND lease_token = $3
+			AND status = $4
+	`
+
+	_, err := r.db.ExecContext(ctx, query, userID, key, leaseToken, StatusInProgress)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r IdempotencyRepo) DeleteExpiredKeys(ctx context.Context, now time.Time) (int, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE expires_at <= $1`, now)
+	if err != nil {
+		return 0, err
+	}
+
+	affectedRows, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(affectedRows), nil
+}
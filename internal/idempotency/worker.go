@@ -0,0 +1,29 @@
+package idempotency
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// RunCleanupWorker periodically deletes expired idempotency keys. It blocks until ctx is
+// cancelled, so it should be run in its own goroutine.
+func RunCleanupWorker(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		deleted, err := IdempotencyRepoImpl.DeleteExpiredKeys(ctx, time.Now())
+		if err != nil {
+			log.Printf("error deleting expired idempotency keys: %v", err)
+		} else if deleted > 0 {
+			log.Printf("deleted %d expired idempotency keys", deleted)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
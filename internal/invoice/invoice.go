@@ -0,0 +1,66 @@
+package invoice
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/config"
+	"github.com/google/uuid"
+)
+
+var (
+	InvoiceRepoImpl *InvoiceRepo
+	TrxProvider     *config.TransactionProvider
+)
+
+// IssueInvoice returns the order's invoice, numbering it on the first request
+func IssueInvoice(ctx context.Context, orderID, sellerID string) (Invoice, error) {
+	invoice, err := InvoiceRepoImpl.GetInvoiceByOrderID(ctx, orderID)
+	if err != sql.ErrNoRows {
+		return invoice, err
+	}
+
+	invoice, err = createInvoice(ctx, orderID, sellerID)
+	if err == errInvoiceExists {
+		// the invoice was issued by a concurrent request, whose number is kept
+		return InvoiceRepoImpl.GetInvoiceByOrderID(ctx, orderID)
+	}
+	if err != nil {
+		return Invoice{}, err
+	}
+
+	return invoice, nil
+}
+
+func createInvoice(ctx context.Context, orderID, sellerID string) (Invoice, error) {
+	tx, err := TrxProvider.NewTransaction(ctx)
+	if err != nil {
+		return Invoice{}, err
+	}
+	defer tx.Rollback()
+
+	sequence, err := InvoiceRepoImpl.NextSequence(ctx, tx, sellerID)
+	if err != nil {
+		return Invoice{}, err
+	}
+
+	invoice := Invoice{
+		ID:       uuid.NewString(),
+		OrderID:  orderID,
+		SellerID: sellerID,
+		Sequence: sequence,
+		IssuedAt: time.Now(),
+	}
+	err = InvoiceRepoImpl.CreateInvoice(ctx, tx, invoice)
+	if err != nil {
+		return Invoice{}, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return Invoice{}, err
+	}
+
+	return invoice, nil
+}
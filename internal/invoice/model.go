@@ -0,0 +1,81 @@
+package invoice
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ahmadnaufal/openidea-shopifyx/pkg/money"
+	"github.com/pkg/errors"
+)
+
+var (
+	errInvoiceExists = errors.New("order has already been invoiced")
+)
+
+// Invoice is the number given to an order's invoice. Numbers are sequential per seller and never
+// change once given, the document itself is rendered from the order on every request.
+type Invoice struct {
+	ID       string    `db:"id"`
+	OrderID  string    `db:"order_id"`
+	SellerID string    `db:"seller_id"`
+	Sequence int64     `db:"sequence"`
+	IssuedAt time.Time `db:"issued_at"`
+}
+
+func (i Invoice) Number() string {
+	return fmt.Sprintf("INV-%06d", i.Sequence)
+}
+
+// Document is everything printed on an invoice
+type Document struct {
+	Number    string
+	IssuedAt  time.Time
+	OrderID   string
+	OrderedAt time.Time
+
+	Seller Party
+	Buyer  Party
+
+	Items           []Item
+	Subtotal        money.Money
+	Discount        money.Money
+	CouponCode      *string
+	ShippingService *string
+	ShippingCost    money.Money
+	Taxes           []Tax
+	Total           money.Money
+	Refunded        money.Money
+
+	PaymentMethod string
+	PaymentStatus string
+	// BankAccount is the seller's account the order was paid to, if it is known
+	BankAccount *BankAccount
+}
+
+type Party struct {
+	Name     string
+	Username string
+	// Address lines are only set for the buyer, when the order has a shipping address
+	Address []string
+}
+
+type Item struct {
+	Description string
+	Quantity    int
+	UnitPrice   money.Money
+	Amount      money.Money
+}
+
+type Tax struct {
+	Name            string
+	RateBasisPoints int64
+	// Inclusive taxes are already part of the prices, and are only shown for information
+	Inclusive bool
+	Amount    money.Money
+}
+
+type BankAccount struct {
+	BankName          string
+	BankAccountName   string
+	BankAccountNumber string
+}
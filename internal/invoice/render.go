@@ -0,0 +1,180 @@
+package invoice
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+const (
+	dateLayout = "02 Jan 2006"
+
+	pageMargin  = 15.0
+	lineHeight  = 6.0
+	pageWidth   = 210.0 - 2*pageMargin
+	columnWidth = pageWidth / 2
+)
+
+// itemColumns are the widths of the items table's description, quantity, unit price and amount columns
+var itemColumns = []float64{pageWidth - 100, 20, 40, 40}
+
+// Render draws the invoice as an A4 PDF. It only uses the PDF core fonts, so it needs no font files.
+func Render(doc Document) ([]byte, error) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(pageMargin, pageMargin, pageMargin)
+	pdf.SetAutoPageBreak(true, pageMargin)
+	pdf.SetTitle(fmt.Sprintf("Invoice %s", doc.Number), true)
+	pdf.AddPage()
+
+	// the core fonts are encoded in cp1252, so names are translated from UTF-8
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+
+	pdf.SetFont("Helvetica", "B", 20)
+	pdf.CellFormat(columnWidth, 10, "INVOICE", "", 0, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.CellFormat(columnWidth, 10, doc.Number, "", 1, "R", false, 0, "")
+
+	writeLabelled(pdf, "Invoice date", doc.IssuedAt.Format(dateLayout))
+	writeLabelled(pdf, "Order ID", doc.OrderID)
+	writeLabelled(pdf, "Order date", doc.OrderedAt.Format(dateLayout))
+	pdf.Ln(lineHeight)
+
+	// the seller and buyer are printed side by side
+	y := pdf.GetY()
+	writeParty(pdf, tr, "Seller", doc.Seller, pageMargin, y)
+	sellerEnd := pdf.GetY()
+	writeParty(pdf, tr, "Bill to", doc.Buyer, pageMargin+columnWidth, y)
+	if sellerEnd > pdf.GetY() {
+		pdf.SetY(sellerEnd)
+	}
+	pdf.Ln(lineHeight)
+
+	writeItems(pdf, tr, doc.Items)
+	pdf.Ln(2)
+
+	writeTotal(pdf, "Subtotal", doc.Subtotal.Format(), false)
+	if !doc.Discount.IsZero() {
+		label := "Discount"
+		if doc.CouponCode != nil {
+			label = fmt.Sprintf("Discount (%s)", *doc.CouponCode)
+		}
+		writeTotal(pdf, tr(label), "-"+doc.Discount.Format(), false)
+	}
+	if doc.ShippingService != nil || !doc.ShippingCost.IsZero() {
+		label := "Shipping"
+		if doc.ShippingService != nil {
+			label = fmt.Sprintf("Shipping (%s)", *doc.ShippingService)
+		}
+		writeTotal(pdf, tr(label), doc.ShippingCost.Format(), false)
+	}
+	for _, tax := range doc.Taxes {
+		if !tax.Inclusive {
+			writeTotal(pdf, tr(taxLabel(tax)), tax.Amount.Format(), false)
+		}
+	}
+	writeTotal(pdf, "Total", doc.Total.Format(), true)
+	for _, tax := range doc.Taxes {
+		if tax.Inclusive {
+			writeTotal(pdf, tr("Includes "+taxLabel(tax)), tax.Amount.Format(), false)
+		}
+	}
+	if !doc.Refunded.IsZero() {
+		writeTotal(pdf, "Refunded", "-"+doc.Refunded.Format(), false)
+	}
+	pdf.Ln(lineHeight)
+
+	pdf.SetFont("Helvetica", "B", 10)
+	pdf.CellFormat(pageWidth, lineHeight, "Payment", "", 1, "L", false, 0, "")
+	writeLabelled(pdf, "Method", humanize(doc.PaymentMethod))
+	writeLabelled(pdf, "Status", humanize(doc.PaymentStatus))
+	if doc.BankAccount != nil {
+		writeLabelled(pdf, "Bank", tr(doc.BankAccount.BankName))
+		writeLabelled(pdf, "Account name", tr(doc.BankAccount.BankAccountName))
+		writeLabelled(pdf, "Account number", doc.BankAccount.BankAccountNumber)
+	}
+
+	var buf bytes.Buffer
+	err := pdf.Output(&buf)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func writeLabelled(pdf *gofpdf.Fpdf, label, value string) {
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.CellFormat(35, lineHeight, label, "", 0, "L", false, 0, "")
+	pdf.CellFormat(pageWidth-35, lineHeight, value, "", 1, "L", false, 0, "")
+}
+
+func writeParty(pdf *gofpdf.Fpdf, tr func(string) string, title string, party Party, x, y float64) {
+	pdf.SetXY(x, y)
+	pdf.SetFont("Helvetica", "B", 10)
+	pdf.CellFormat(columnWidth, lineHeight, title, "", 2, "L", false, 0, "")
+
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.CellFormat(columnWidth, lineHeight, tr(party.Name), "", 2, "L", false, 0, "")
+	pdf.CellFormat(columnWidth, lineHeight, tr("@"+party.Username), "", 2, "L", false, 0, "")
+	for _, line := range party.Address {
+		pdf.MultiCell(columnWidth, lineHeight, tr(line), "", "L", false)
+		pdf.SetX(x)
+	}
+}
+
+func writeItems(pdf *gofpdf.Fpdf, tr func(string) string, items []Item) {
+	headers := []string{"Item", "Qty", "Unit price", "Amount"}
+	alignments := []string{"L", "R", "R", "R"}
+
+	pdf.SetFont("Helvetica", "B", 10)
+	pdf.SetFillColor(235, 235, 235)
+	for i, header := range headers {
+		pdf.CellFormat(itemColumns[i], lineHeight+1, header, "B", 0, alignments[i], true, 0, "")
+	}
+	pdf.Ln(-1)
+
+	pdf.SetFont("Helvetica", "", 10)
+	for _, item := range items {
+		values := []string{
+			tr(item.Description),
+			fmt.Sprintf("%d", item.Quantity),
+			item.UnitPrice.Format(),
+			item.Amount.Format(),
+		}
+		for i, value := range values {
+			pdf.CellFormat(itemColumns[i], lineHeight+1, value, "B", 0, alignments[i], false, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+}
+
+func writeTotal(pdf *gofpdf.Fpdf, label, value string, bold bool) {
+	style := ""
+	if bold {
+		style = "B"
+	}
+
+	pdf.SetFont("Helvetica", style, 10)
+	pdf.SetX(pageMargin + pageWidth - 100)
+	pdf.CellFormat(60, lineHeight, label, "", 0, "R", false, 0, "")
+	pdf.CellFormat(40, lineHeight, value, "", 1, "R", false, 0, "")
+}
+
+// taxLabel names the tax with its rate, e.g. "PPN 11%" or "GST 8.25%"
+func taxLabel(tax Tax) string {
+	rate := strconv.FormatFloat(float64(tax.RateBasisPoints)/100, 'f', -1, 64)
+	return fmt.Sprintf("%s %s%%", tax.Name, rate)
+}
+
+// humanize turns identifiers like manual_transfer into "Manual transfer"
+func humanize(value string) string {
+	value = strings.ReplaceAll(value, "_", " ")
+	if value == "" {
+		return value
+	}
+
+	return strings.ToUpper(value[:1]) + value[1:]
+}
@@ -0,0 +1,108 @@
+package invoice
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type InvoiceRepo struct {
+	db *sqlx.DB
+}
+
+func NewInvoiceRepo(db *sqlx.DB) InvoiceRepo {
+	return InvoiceRepo{db: db}
+}
+
+func (r InvoiceRepo) GetInvoiceByOrderID(ctx context.Context, orderID string) (Invoice, error) {
+	var result Invoice
+
+	query := `
+		SELECT
+			id,
+			order_id,
+			seller_id,
+			sequence,
+			issued_at
+		FROM
+			invoices
+		WHERE
+			order_id = $1
+		LIMIT 1
+	`
+
+	err := r.db.GetContext(ctx, &result, query, orderID)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// NextSequence takes the seller's next invoice number. The seller's counter stays locked until tx ends,
+// so their invoices are numbered one at a time, and a rolled back number is given to the next invoice.
+func (r InvoiceRepo) NextSequence(ctx context.Context, tx *sql.Tx, sellerID string) (int64, error) {
+	query := `
+		INSERT INTO invoice_counters
+			(seller_id, last_sequence)
+		VALUES
+			($1, 1)
+		ON CONFLICT (seller_id) DO UPDATE
+		SET
+			last_sequence = invoice_counters.last_sequence + 1
+		RETURNING
+			last_sequence
+	`
+
+	var sequence int64
+	err := tx.QueryRowContext(ctx, query, sellerID).Scan(&sequence)
+	if err != nil {
+		return sequence, err
+	}
+
+	return sequence, nil
+}
+
+// CreateInvoice stores the invoice, failing with errInvoiceExists if the order already has one
+func (r InvoiceRepo) CreateInvoice(ctx context.Context, tx *sql.Tx, invoice Invoice) error {
+	query := `
+		INSERT INTO invoices
+			(
+				id,
+				order_id,
+				seller_id,
+				sequence,
+				issued_at
+			)
+		VALUES
+			(
+				:id,
+				:order_id,
+				:seller_id,
+				:sequence,
+				:issued_at
+			)
+		ON CONFLICT (order_id) DO NOTHING
+	`
+
+	updatedQuery, args, err := sqlx.Named(query, invoice)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, sqlx.Rebind(sqlx.DOLLAR, updatedQuery), args...)
+	if err != nil {
+		return err
+	}
+
+	affectedRows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affectedRows != 1 {
+		return errInvoiceExists
+	}
+
+	return nil
+}
@@ -0,0 +1,413 @@
+package ledger
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/admin"
+	bankaccount "github.com/ahmadnaufal/openidea-shopifyx/internal/bank_account"
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/config"
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/model"
+	"github.com/ahmadnaufal/openidea-shopifyx/pkg/jwt"
+	"github.com/ahmadnaufal/openidea-shopifyx/pkg/money"
+	"github.com/ahmadnaufal/openidea-shopifyx/pkg/validation"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+var (
+	LedgerRepoImpl      *LedgerRepo
+	BankAccountRepoImpl *bankaccount.BankAccountRepo
+	TrxProvider         *config.TransactionProvider
+)
+
+func RegisterRoute(r *fiber.App, jwtProvider jwt.JWTProvider) {
+	authMiddleware := jwtProvider.Middleware()
+
+	sellerGroup := r.Group("/v1/seller/me")
+	sellerGroup.Use(authMiddleware)
+
+	sellerGroup.Get("/balance", GetBalance)
+	sellerGroup.Get("/ledger", ListEntries)
+	sellerGroup.Post("/payouts", RequestPayout)
+	sellerGroup.Get("/payouts", ListPayouts)
+
+	adminGroup := r.Group("/v1/admin/payouts", authMiddleware, admin.Middleware())
+	adminGroup.Post("/:payout_id/status", UpdatePayoutStatus)
+}
+
+func GetBalance(c *fiber.Ctx) error {
+	claims, err := jwt.GetLoggedInUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "forbidden",
+		})
+	}
+
+	ctx := c.Context()
+	available, err := LedgerRepoImpl.GetSellerBalances(ctx, claims.UserID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	pending, err := LedgerRepoImpl.GetPendingAmounts(ctx, claims.UserID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(model.DataResponse{
+		Message: "ok",
+		Data:    balancesToResponse(available, pending),
+	})
+}
+
+func ListEntries(c *fiber.Ctx) error {
+	var req ListEntriesRequest
+
+	claims, err := jwt.GetLoggedInUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "forbidden",
+		})
+	}
+
+	if err := c.QueryParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "invalid_request_body",
+		})
+	}
+
+	entries, count, err := LedgerRepoImpl.ListEntriesByUserID(c.Context(), claims.UserID, req)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	responses := make([]EntryResponse, len(entries))
+	for i, entry := range entries {
+		responses[i] = EntryResponse{
+			Type:        entry.EntryType,
+			Amount:      money.New(entry.Amount, entry.Currency),
+			ReferenceID: entry.ReferenceID,
+			CreatedAt:   entry.CreatedAt,
+		}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(model.DataResponse{
+		Message: "ok",
+		Data:    responses,
+		Meta: &model.ResponseMeta{
+			Limit:  req.Limit,
+			Offset: req.Offset,
+			Total:  count,
+		},
+	})
+}
+
+func RequestPayout(c *fiber.Ctx) error {
+	var payload PayoutRequest
+
+	claims, err := jwt.GetLoggedInUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "forbidden",
+		})
+	}
+
+	if err := c.BodyParser(&payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "invalid_request_body",
+		})
+	}
+
+	// validation for request body
+	if err := validation.Validate(payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "failed_request_body_validation",
+		})
+	}
+
+	amount := payload.Amount.Round()
+	if amount.Amount <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: "payout amount must be greater than zero",
+			Code:    "failed_request_body_validation",
+		})
+	}
+
+	ctx := c.Context()
+	bankAccount, err := BankAccountRepoImpl.GetBankAccountByID(ctx, payload.BankAccountID)
+	if err != nil && err != sql.ErrNoRows {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+	if err == sql.ErrNoRows || bankAccount.UserID != claims.UserID {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: "bank account not found",
+			Code:    "invalid_bank_account",
+		})
+	}
+
+	// payouts only go to accounts the bank confirmed belong to the seller
+	if !bankAccount.IsVerified() {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: "bank account is not verified",
+			Code:    "invalid_bank_account",
+		})
+	}
+
+	now := time.Now()
+	payout := Payout{
+		ID:                uuid.NewString(),
+		UserID:            claims.UserID,
+		BankAccountID:     bankAccount.ID,
+		BankName:          bankAccount.BankName,
+		BankAccountName:   bankAccount.BankAccountName,
+		BankAccountNumber: bankAccount.BankAccountNumber,
+		Amount:            amount.Amount,
+		Currency:          amount.Currency,
+		Status:            PayoutStatusRequested,
+		CreatedAt:         now,
+		UpdatedAt:         now,
+	}
+
+	err = requestPayout(ctx, payout)
+	if err != nil {
+		if err == ErrInsufficientBalance {
+			return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+				Message: err.Error(),
+				Code:    "insufficient_balance",
+			})
+		}
+		if err == ErrBalanceOwed {
+			return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+				Message: err.Error(),
+				Code:    "balance_owed",
+			})
+		}
+
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(model.DataResponse{
+		Message: "Payout requested successfully",
+		Data:    payoutToResponse(payout),
+	})
+}
+
+// requestPayout debits the payout from the seller's balance. The balance is locked while it is checked,
+// so concurrent payouts can't spend the same money twice.
+func requestPayout(ctx context.Context, payout Payout) error {
+	tx, err := TrxProvider.NewTransaction(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = LedgerRepoImpl.LockSellerBalance(ctx, tx, payout.UserID)
+	if err != nil {
+		return err
+	}
+
+	balance, err := LedgerRepoImpl.GetSellerBalance(ctx, tx, payout.UserID, payout.Currency)
+	if err != nil {
+		return err
+	}
+	if balance < 0 {
+		return ErrBalanceOwed
+	}
+	if balance < payout.Amount {
+		return ErrInsufficientBalance
+	}
+
+	err = LedgerRepoImpl.CreatePayout(ctx, tx, payout)
+	if err != nil {
+		return err
+	}
+
+	err = recordPayout(ctx, tx, payout)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func ListPayouts(c *fiber.Ctx) error {
+	claims, err := jwt.GetLoggedInUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "forbidden",
+		})
+	}
+
+	payouts, err := LedgerRepoImpl.ListPayoutsByUserID(c.Context(), claims.UserID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	responses := make([]PayoutResponse, len(payouts))
+	for i, payout := range payouts {
+		responses[i] = payoutToResponse(payout)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(model.DataResponse{
+		Message: "ok",
+		Data:    responses,
+	})
+}
+
+// UpdatePayoutStatus records the outcome of a payout's bank transfer. A failed payout is given
+// back to the seller's balance.
+func UpdatePayoutStatus(c *fiber.Ctx) error {
+	var payload UpdatePayoutStatusRequest
+
+	if err := c.BodyParser(&payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "invalid_request_body",
+		})
+	}
+
+	// validation for request body
+	if err := validation.Validate(payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "failed_request_body_validation",
+		})
+	}
+
+	payout, err := updatePayoutStatus(c.Context(), c.Params("payout_id"), payload.Status)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return c.Status(fiber.StatusNotFound).JSON(model.ErrorResponse{
+				Message: "payout not found",
+				Code:    "entity_not_found",
+			})
+		}
+		if err == ErrPayoutNotRequested {
+			return c.Status(fiber.StatusConflict).JSON(model.ErrorResponse{
+				Message: err.Error(),
+				Code:    "payout_already_processed",
+			})
+		}
+
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(model.DataResponse{
+		Message: "Payout updated successfully",
+		Data:    payoutToResponse(payout),
+	})
+}
+
+func updatePayoutStatus(ctx context.Context, payoutID, status string) (Payout, error) {
+	tx, err := TrxProvider.NewTransaction(ctx)
+	if err != nil {
+		return Payout{}, err
+	}
+	defer tx.Rollback()
+
+	payout, err := LedgerRepoImpl.GetPayoutByIDForUpdate(ctx, tx, payoutID)
+	if err != nil {
+		return payout, err
+	}
+	if payout.Status != PayoutStatusRequested {
+		return payout, ErrPayoutNotRequested
+	}
+
+	payout.Status = status
+	payout.UpdatedAt = time.Now()
+	err = LedgerRepoImpl.UpdatePayoutStatus(ctx, tx, payout.ID, payout.Status, payout.UpdatedAt)
+	if err != nil {
+		return payout, err
+	}
+
+	if payout.Status == PayoutStatusFailed {
+		err = recordPayoutReversal(ctx, tx, payout)
+		if err != nil {
+			return payout, err
+		}
+	}
+
+	return payout, tx.Commit()
+}
+
+// balancesToResponse pairs the available and pending amounts by currency. A seller without
+// any is shown a zero balance in the default currency.
+func balancesToResponse(available, pending []Balance) []BalanceResponse {
+	responses := []BalanceResponse{}
+	indexes := map[money.Currency]int{}
+
+	get := func(currency money.Currency) *BalanceResponse {
+		i, exist := indexes[currency]
+		if !exist {
+			i = len(responses)
+			indexes[currency] = i
+			responses = append(responses, BalanceResponse{
+				Available: money.New(0, currency),
+				Pending:   money.New(0, currency),
+				Owed:      money.New(0, currency),
+			})
+		}
+		return &responses[i]
+	}
+
+	for _, balance := range available {
+		if balance.Amount < 0 {
+			get(balance.Currency).Owed = money.New(-balance.Amount, balance.Currency)
+			continue
+		}
+		get(balance.Currency).Available = money.New(balance.Amount, balance.Currency)
+	}
+	for _, balance := range pending {
+		get(balance.Currency).Pending = money.New(balance.Amount, balance.Currency)
+	}
+
+	if len(responses) == 0 {
+		get(money.DefaultCurrency)
+	}
+
+	return responses
+}
+
+func payoutToResponse(payout Payout) PayoutResponse {
+	return PayoutResponse{
+		PayoutID:          payout.ID,
+		BankAccountID:     payout.BankAccountID,
+		BankName:          payout.BankName,
+		BankAccountName:   payout.BankAccountName,
+		BankAccountNumber: payout.BankAccountNumber,
+		Amount:            money.New(payout.Amount, payout.Currency),
+		Status:            payout.Status,
+		CreatedAt:         payout.CreatedAt,
+		UpdatedAt:         payout.UpdatedAt,
+	}
+}
@@ -0,0 +1,116 @@
+package ledger
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/ahmadnaufal/openidea-shopifyx/pkg/money"
+	"github.com/google/uuid"
+)
+
+// The record functions post the ledger side of a change. They must be called in the transaction
+// making the change, so the ledger never disagrees with the orders and payouts it's about.
+
+// RecordOrderCompleted credits the seller with the order's amount and debits the platform's fee from it
+func RecordOrderCompleted(ctx context.Context, tx *sql.Tx, order CompletedOrder) error {
+	return postTransaction(ctx, tx, []Entry{
+		newEntry(AccountOrderClearing, nil, EntryTypeOrderCredit, -order.Amount, order.Currency, order.OrderID),
+		newEntry(AccountSellerPayable, &order.SellerID, EntryTypeOrderCredit, order.Amount, order.Currency, order.OrderID),
+		newEntry(AccountSellerPayable, &order.SellerID, EntryTypePlatformFee, -order.Fee, order.Currency, order.OrderID),
+		newEntry(AccountPlatformRevenue, nil, EntryTypePlatformFee, order.Fee, order.Currency, order.OrderID),
+	})
+}
+
+// RecordRefund debits a refund of an already completed order from its seller, and gives them back the
+// fee taken on it. Refunds before the order is completed never reach the seller's balance, as the order
+// is credited without them.
+//
+// A seller who was already paid out may be left owing the refund, with a negative balance. Their next
+// orders repay it, and no payout can be made until they have.
+func RecordRefund(ctx context.Context, tx *sql.Tx, orderID, sellerID string, amount, fee money.Money) error {
+	// taken with the payouts' lock, so a payout can't spend the balance the refund is taken from
+	err := LedgerRepoImpl.LockSellerBalance(ctx, tx, sellerID)
+	if err != nil {
+		return err
+	}
+
+	err = postTransaction(ctx, tx, []Entry{
+		newEntry(AccountSellerPayable, &sellerID, EntryTypeRefund, -amount.Amount, amount.Currency, orderID),
+		newEntry(AccountOrderClearing, nil, EntryTypeRefund, amount.Amount, amount.Currency, orderID),
+		newEntry(AccountPlatformRevenue, nil, EntryTypePlatformFee, -fee.Amount, fee.Currency, orderID),
+		newEntry(AccountSellerPayable, &sellerID, EntryTypePlatformFee, fee.Amount, fee.Currency, orderID),
+	})
+	if err != nil {
+		return err
+	}
+
+	balance, err := LedgerRepoImpl.GetSellerBalance(ctx, tx, sellerID, amount.Currency)
+	if err != nil {
+		return err
+	}
+	if balance < 0 {
+		log.Printf("seller %s owes %s after the refund of order %s", sellerID, money.New(-balance, amount.Currency), orderID)
+	}
+
+	return nil
+}
+
+func recordPayout(ctx context.Context, tx *sql.Tx, payout Payout) error {
+	return postTransaction(ctx, tx, []Entry{
+		newEntry(AccountSellerPayable, &payout.UserID, EntryTypePayout, -payout.Amount, payout.Currency, payout.ID),
+		newEntry(AccountPayoutsInTransit, nil, EntryTypePayout, payout.Amount, payout.Currency, payout.ID),
+	})
+}
+
+// recordPayoutReversal gives the seller back a payout the bank couldn't make
+func recordPayoutReversal(ctx context.Context, tx *sql.Tx, payout Payout) error {
+	return postTransaction(ctx, tx, []Entry{
+		newEntry(AccountPayoutsInTransit, nil, EntryTypePayoutReversal, -payout.Amount, payout.Currency, payout.ID),
+		newEntry(AccountSellerPayable, &payout.UserID, EntryTypePayoutReversal, payout.Amount, payout.Currency, payout.ID),
+	})
+}
+
+func newEntry(account string, userID *string, entryType string, amount int64, currency money.Currency, referenceID string) Entry {
+	return Entry{
+		Account:     account,
+		UserID:      userID,
+		EntryType:   entryType,
+		Amount:      amount,
+		Currency:    currency,
+		ReferenceID: referenceID,
+	}
+}
+
+// postTransaction writes the entries as one ledger transaction, after checking they balance.
+// Entries of zero are left out, e.g. the fee of an order without one.
+func postTransaction(ctx context.Context, tx *sql.Tx, entries []Entry) error {
+	transactionID := uuid.NewString()
+	now := time.Now()
+
+	sums := map[money.Currency]int64{}
+	nonZeroEntries := []Entry{}
+	for _, entry := range entries {
+		sums[entry.Currency] += entry.Amount
+		if entry.Amount == 0 {
+			continue
+		}
+
+		entry.TransactionID = transactionID
+		entry.CreatedAt = now
+		nonZeroEntries = append(nonZeroEntries, entry)
+	}
+
+	for _, sum := range sums {
+		if sum != 0 {
+			return ErrUnbalancedTransaction
+		}
+	}
+
+	if len(nonZeroEntries) == 0 {
+		return nil
+	}
+
+	return LedgerRepoImpl.CreateEntries(ctx, tx, nonZeroEntries)
+}
@@ -0,0 +1,99 @@
+package ledger
+
+import (
+	"time"
+
+	"github.com/ahmadnaufal/openidea-shopifyx/pkg/money"
+	"github.com/pkg/errors"
+)
+
+// Accounts of the ledger. Every transaction's entries sum to zero per currency, so money is
+// only ever moved between accounts. Positive amounts credit an account, negative ones debit it.
+const (
+	// AccountSellerPayable is what the platform owes a seller, its balance is the seller's balance
+	AccountSellerPayable = "seller_payable"
+	// AccountOrderClearing holds buyers' payments until their orders are completed or refunded
+	AccountOrderClearing = "order_clearing"
+	// AccountPlatformRevenue collects the platform's fees
+	AccountPlatformRevenue = "platform_revenue"
+	// AccountPayoutsInTransit holds the money being transferred to sellers' bank accounts
+	AccountPayoutsInTransit = "payouts_in_transit"
+)
+
+const (
+	EntryTypeOrderCredit    = "order_credit"
+	EntryTypePlatformFee    = "platform_fee"
+	EntryTypeRefund         = "refund"
+	EntryTypePayout         = "payout"
+	EntryTypePayoutReversal = "payout_reversal"
+)
+
+const (
+	PayoutStatusRequested = "requested"
+	PayoutStatusPaid      = "paid"
+	PayoutStatusFailed    = "failed"
+)
+
+var (
+	ErrUnbalancedTransaction = errors.New("ledger transaction entries don't sum to zero")
+	ErrInsufficientBalance   = errors.New("payout amount exceeds the available balance")
+	ErrBalanceOwed           = errors.New("refunds must be repaid before a payout can be made")
+	ErrPayoutNotRequested    = errors.New("payout has already been processed")
+)
+
+type PayoutRequest struct {
+	BankAccountID string      `json:"bankAccountId" validate:"required"`
+	Amount        money.Money `json:"amount" validate:"required"`
+}
+
+type UpdatePayoutStatusRequest struct {
+	Status string `json:"status" validate:"oneof=paid failed"`
+}
+
+type ListEntriesRequest struct {
+	Limit  int `query:"limit"`
+	Offset int `query:"offset"`
+}
+
+type Entry struct {
+	ID            int64          `db:"id"`
+	TransactionID string         `db:"transaction_id"`
+	Account       string         `db:"account"`
+	UserID        *string        `db:"user_id"`
+	EntryType     string         `db:"entry_type"`
+	Amount        int64          `db:"amount"`
+	Currency      money.Currency `db:"currency"`
+	ReferenceID   string         `db:"reference_id"`
+	CreatedAt     time.Time      `db:"created_at"`
+}
+
+type Balance struct {
+	Currency money.Currency `db:"currency"`
+	Amount   int64          `db:"amount"`
+}
+
+// Payout is a seller's request to transfer their balance to one of their bank accounts.
+// The bank account is snapshotted, so editing it later doesn't change where the money went.
+type Payout struct {
+	ID                string         `db:"id"`
+	UserID            string         `db:"user_id"`
+	BankAccountID     string         `db:"bank_account_id"`
+	BankName          string         `db:"bank_name"`
+	BankAccountName   string         `db:"bank_account_name"`
+	BankAccountNumber string         `db:"bank_account_number"`
+	Amount            int64          `db:"amount"`
+	Currency          money.Currency `db:"currency"`
+	Status            string         `db:"status"`
+	CreatedAt         time.Time      `db:"created_at"`
+	UpdatedAt         time.Time      `db:"updated_at"`
+}
+
+// CompletedOrder is what the ledger needs to know about an order to credit its seller
+type CompletedOrder struct {
+	OrderID  string
+	SellerID string
+	Currency money.Currency
+	// Amount is what the buyer paid, less any refund, and Fee is the platform's part of it
+	Amount int64
+	Fee    int64
+}
@@ -0,0 +1,346 @@
+package ledger
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/ahmadnaufal/openidea-shopifyx/pkg/money"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+type LedgerRepo struct {
+	db *sqlx.DB
+}
+
+func NewLedgerRepo(db *sqlx.DB) LedgerRepo {
+	return LedgerRepo{db: db}
+}
+
+func (r LedgerRepo) CreateEntries(ctx context.Context, tx *sql.Tx, entries []Entry) error {
+	query := `
+		INSERT INTO ledger_entries
+			(
+				transaction_id,
+				account,
+				user_id,
+				entry_type,
+				amount,
+				currency,
+				reference_id,
+				created_at
+			)
+		VALUES
+			(
+				:transaction_id,
+				:account,
+				:user_id,
+				:entry_type,
+				:amount,
+				:currency,
+				:reference_id,
+				:created_at
+			)
+	`
+
+	updatedQuery, args, err := sqlx.Named(query, entries)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, sqlx.Rebind(sqlx.DOLLAR, updatedQuery), args...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// LockSellerBalance serializes the transactions spending the seller's balance until tx ends,
+// so two payouts can't both be checked against the same balance
+func (r LedgerRepo) LockSellerBalance(ctx context.Context, tx *sql.Tx, userID string) error {
+	_, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext('seller_balance:' || $1))`, userID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetSellerBalance returns the seller's balance in the currency, as seen by tx
+func (r LedgerRepo) GetSellerBalance(ctx context.Context, tx *sql.Tx, userID string, currency money.Currency) (int64, error) {
+	query := `
+		SELECT
+			COALESCE(SUM(amount), 0)
+		FROM
+			ledger_entries
+		WHERE
+			account = $1
+			AND user_id = $2
+			AND currency = $3
+	`
+
+	var balance int64
+	err := tx.QueryRowContext(ctx, query, AccountSellerPayable, userID, currency).Scan(&balance)
+	if err != nil {
+		return balance, err
+	}
+
+	return balance, nil
+}
+
+// GetSellerBalances returns the seller's balance in every currency they have one in
+func (r LedgerRepo) GetSellerBalances(ctx context.Context, userID string) ([]Balance, error) {
+	var balances []Balance
+
+	query := `
+		SELECT
+			currency,
+			SUM(amount) AS amount
+		FROM
+			ledger_entries
+		WHERE
+			account = $1
+			AND user_id = $2
+		GROUP BY
+			currency
+		ORDER BY
+			currency
+	`
+
+	err := r.db.SelectContext(ctx, &balances, query, AccountSellerPayable, userID)
+	if err != nil {
+		return balances, err
+	}
+
+	return balances, nil
+}
+
+// GetPendingAmounts sums the seller's orders that are paid but not completed yet, less their commission,
+// which will be credited to their balance once the buyers confirm they've received them
+func (r LedgerRepo) GetPendingAmounts(ctx context.Context, userID string) ([]Balance, error) {
+	var balances []Balance
+
+	query := `
+		SELECT
+			currency,
+			SUM(
+				total - refunded_amount
+				- CASE WHEN total = 0 THEN 0 ELSE ROUND(commission_amount::numeric * (total - refunded_amount) / total)::bigint END
+			) AS amount
+		FROM
+			orders
+		WHERE
+			seller_id = $1
+			AND status IN ('paid', 'shipped', 'partially_refunded')
+		GROUP BY
+			currency
+		ORDER BY
+			currency
+	`
+
+	err := r.db.SelectContext(ctx, &balances, query, userID)
+	if err != nil {
+		return balances, err
+	}
+
+	return balances, nil
+}
+
+func (r LedgerRepo) ListEntriesByUserID(ctx context.Context, userID string, req ListEntriesRequest) ([]Entry, int, error) {
+	var entries []Entry
+
+	var count int
+	err := r.db.GetContext(ctx, &count, `SELECT COUNT(*) FROM ledger_entries WHERE account = $1 AND user_id = $2`, AccountSellerPayable, userID)
+	if err != nil {
+		return entries, count, err
+	}
+
+	limit := req.Limit
+	if limit <= 0 {
+		limit = 10
+	}
+
+	offset := req.Offset
+	if offset < 0 {
+		offset = 0
+	}
+
+	query := `
+		SELECT
+			id,
+			transaction_id,
+			account,
+			user_id,
+			entry_type,
+			amount,
+			currency,
+			reference_id,
+			created_at
+		FROM
+			ledger_entries
+		WHERE
+			account = $1
+			AND user_id = $2
+		ORDER BY
+			created_at DESC, id DESC
+		LIMIT $3 OFFSET $4
+	`
+
+	err = r.db.SelectContext(ctx, &entries, query, AccountSellerPayable, userID, limit, offset)
+	if err != nil {
+		return entries, count, err
+	}
+
+	return entries, count, nil
+}
+
+func (r LedgerRepo) CreatePayout(ctx context.Context, tx *sql.Tx, payout Payout) error {
+	query := `
+		INSERT INTO payouts
+			(
+				id,
+				user_id,
+				bank_account_id,
+				bank_name,
+				bank_account_name,
+				bank_account_number,
+				amount,
+				currency,
+				status,
+				created_at,
+				updated_at
+			)
+		VALUES
+			(
+				:id,
+				:user_id,
+				:bank_account_id,
+				:bank_name,
+				:bank_account_name,
+				:bank_account_number,
+				:amount,
+				:currency,
+				:status,
+				:created_at,
+				:updated_at
+			)
+	`
+
+	updatedQuery, args, err := sqlx.Named(query, payout)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, sqlx.Rebind(sqlx.DOLLAR, updatedQuery), args...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r LedgerRepo) ListPayoutsByUserID(ctx context.Context, userID string) ([]Payout, error) {
+	var payouts []Payout
+
+	query := `
+		SELECT
+			id,
+			user_id,
+			bank_account_id,
+			bank_name,
+			bank_account_name,
+			bank_account_number,
+			amount,
+			currency,
+			status,
+			created_at,
+			updated_at
+		FROM
+			payouts
+		WHERE
+			user_id = $1
+		ORDER BY
+			created_at DESC
+	`
+
+	err := r.db.SelectContext(ctx, &payouts, query, userID)
+	if err != nil {
+		return payouts, err
+	}
+
+	return payouts, nil
+}
+
+// GetPayoutByIDForUpdate locks the payout so it is only processed once
+func (r LedgerRepo) GetPayoutByIDForUpdate(ctx context.Context, tx *sql.Tx, payoutID string) (Payout, error) {
+	var result Payout
+
+	query := `
+		SELECT
+			id,
+			user_id,
+			bank_account_id,
+			bank_name,
+			bank_account_name,
+			bank_account_number,
+			amount,
+			currency,
+			status,
+			created_at,
+			updated_at
+		FROM
+			payouts
+		WHERE
+			id = $1
+		LIMIT 1
+		FOR UPDATE
+	`
+
+	row := tx.QueryRowContext(ctx, query, payoutID)
+	err := row.Scan(
+		&result.ID,
+		&result.UserID,
+		&result.BankAccountID,
+		&result.BankName,
+		&result.BankAccountName,
+		&result.BankAccountNumber,
+		&result.Amount,
+		&result.Currency,
+		&result.Status,
+		&result.CreatedAt,
+		&result.UpdatedAt,
+	)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r LedgerRepo) UpdatePayoutStatus(ctx context.Context, tx *sql.Tx, payoutID, status string, updatedAt time.Time) error {
+	query := `
+		UPDATE payouts
+		SET
+			status = $1,
+			updated_at = $2
+		WHERE
+			id = $3
+	`
+
+	result, err := tx.ExecContext(ctx, query, status, updatedAt, payoutID)
+	if err != nil {
+		return err
+	}
+
+	affectedRows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affectedRows != 1 {
+		return errors.New("error affected row count is not equal to 1")
+	}
+
+	return nil
+}
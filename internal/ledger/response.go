@@ -0,0 +1,34 @@
+package ledger
+
+import (
+	"time"
+
+	"github.com/ahmadnaufal/openidea-shopifyx/pkg/money"
+)
+
+type BalanceResponse struct {
+	// Available can be paid out, Pending is from orders that aren't completed yet
+	Available money.Money `json:"available"`
+	Pending   money.Money `json:"pending"`
+	// Owed is what refunds took back beyond the balance, it's repaid from the next orders before any payout
+	Owed money.Money `json:"owed"`
+}
+
+type EntryResponse struct {
+	Type        string      `json:"type"`
+	Amount      money.Money `json:"amount"`
+	ReferenceID string      `json:"referenceId"`
+	CreatedAt   time.Time   `json:"createdAt"`
+}
+
+type PayoutResponse struct {
+	PayoutID          string      `json:"payoutId"`
+	BankAccountID     string      `json:"bankAccountId"`
+	BankName          string      `json:"bankName"`
+	BankAccountName   string      `json:"bankAccountName"`
+	BankAccountNumber string      `json:"bankAccountNumber"`
+	Amount            money.Money `json:"amount"`
+	Status            string      `json:"status"`
+	CreatedAt         time.Time   `json:"createdAt"`
+	UpdatedAt         time.Time   `json:"updatedAt"`
+}
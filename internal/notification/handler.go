@@ -0,0 +1,111 @@
+package notification
+
+import (
+	"database/sql"
+
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/config"
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/model"
+	"github.com/ahmadnaufal/openidea-shopifyx/pkg/jwt"
+	"github.com/gofiber/fiber/v2"
+)
+
+var (
+	NotificationRepoImpl *NotificationRepo
+	TrxProvider          *config.TransactionProvider
+)
+
+func RegisterRoute(r *fiber.App, jwtProvider jwt.JWTProvider) {
+	notificationGroup := r.Group("/v1/notification")
+	authMiddleware := jwtProvider.Middleware()
+	notificationGroup.Use(authMiddleware)
+
+	notificationGroup.Get("/", ListNotifications)
+	notificationGroup.Post("/:notification_id/read", MarkNotificationRead)
+}
+
+func ListNotifications(c *fiber.Ctx) error {
+	claims, err := jwt.GetLoggedInUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "forbidden",
+		})
+	}
+
+	var req ListNotificationsRequest
+	if err := c.QueryParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "invalid_request_body",
+		})
+	}
+
+	ctx := c.Context()
+	notifications, count, err := NotificationRepoImpl.ListNotifications(ctx, claims.UserID, req)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	responses := []NotificationResponse{}
+	for _, notification := range notifications {
+		responses = append(responses, notificationEntityToResponse(notification))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(model.DataResponse{
+		Message: "ok",
+		Data:    responses,
+		Meta: &model.ResponseMeta{
+			Limit:  req.Limit,
+			Offset: req.Offset,
+			Total:  count,
+		},
+	})
+}
+
+func MarkNotificationRead(c *fiber.Ctx) error {
+	notificationID := c.Params("notification_id")
+
+	claims, err := jwt.GetLoggedInUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "forbidden",
+		})
+	}
+
+	ctx := c.Context()
+	notification, err := NotificationRepoImpl.MarkNotificationRead(ctx, claims.UserID, notificationID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return c.Status(fiber.StatusNotFound).JSON(model.ErrorResponse{
+				Message: "notification not found",
+				Code:    "entity_not_found",
+			})
+		}
+
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(model.DataResponse{
+		Message: "ok",
+		Data:    notificationEntityToResponse(notification),
+	})
+}
+
+func notificationEntityToResponse(notification Notification) NotificationResponse {
+	return NotificationResponse{
+		ID:        notification.ID,
+		Type:      notification.Type,
+		Title:     notification.Title,
+		Body:      notification.Body,
+		ProductID: notification.ProductID,
+		ReadAt:    notification.ReadAt,
+		CreatedAt: notification.CreatedAt,
+	}
+}
@@ -0,0 +1,57 @@
+package notification
+
+import (
+	"time"
+)
+
+const (
+	EventTypePriceDrop   = "price_drop"
+	EventTypeBackInStock = "back_in_stock"
+
+	OutboxStatusPending   = "pending"
+	OutboxStatusProcessed = "processed"
+	OutboxStatusFailed    = "failed"
+)
+
+// OutboxEvent is a product change waiting to be turned into notifications for the product's watchers.
+// It's written in the same transaction as the change itself, so no change is lost or notified twice.
+type OutboxEvent struct {
+	ID            string     `db:"id"`
+	EventType     string     `db:"event_type"`
+	ProductID     string     `db:"product_id"`
+	SellerID      string     `db:"seller_id"`
+	Payload       []byte     `db:"payload"`
+	Status        string     `db:"status"`
+	Attempts      int        `db:"attempts"`
+	NextAttemptAt time.Time  `db:"next_attempt_at"`
+	LastError     *string    `db:"last_error"`
+	CreatedAt     time.Time  `db:"created_at"`
+	ProcessedAt   *time.Time `db:"processed_at"`
+}
+
+// ProductChangePayload describes the product at the time of the change. Prices are in minor units
+type ProductChangePayload struct {
+	ProductName string `json:"productName"`
+	Currency    string `json:"currency"`
+	OldPrice    int64  `json:"oldPrice"`
+	NewPrice    int64  `json:"newPrice"`
+	Stock       int    `json:"stock"`
+}
+
+type Notification struct {
+	ID            string     `db:"id"`
+	UserID        string     `db:"user_id"`
+	Type          string     `db:"type"`
+	Title         string     `db:"title"`
+	Body          string     `db:"body"`
+	ProductID     *string    `db:"product_id"`
+	OutboxEventID *string    `db:"outbox_event_id"`
+	ReadAt        *time.Time `db:"read_at"`
+	CreatedAt     time.Time  `db:"created_at"`
+}
+
+type ListNotificationsRequest struct {
+	UnreadOnly bool `query:"unreadOnly"`
+	Limit      int  `query:"limit"`
+	Offset     int  `query:"offset"`
+}
@@ -0,0 +1,333 @@
+package notification
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+type NotificationRepo struct {
+	db *sqlx.DB
+}
+
+func NewNotificationRepo(db *sqlx.DB) NotificationRepo {
+	return NotificationRepo{db: db}
+}
+
+func (r NotificationRepo) Subscribe(ctx context.Context, userID, productID string) error {
+	query := `
+		INSERT INTO product_subscriptions
+			(user_id, product_id)
+		VALUES
+			($1, $2)
+		ON CONFLICT DO NOTHING
+	`
+
+	_, err := r.db.ExecContext(ctx, query, userID, productID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r NotificationRepo) Unsubscribe(ctx context.Context, userID, productID string) error {
+	query := `
+		DELETE FROM
+			product_subscriptions
+		WHERE
+			user_id = $1
+			AND product_id = $2
+	`
+
+	_, err := r.db.ExecContext(ctx, query, userID, productID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// CreateOutboxEvent must be called in the same transaction as the product change it describes
+func (r NotificationRepo) CreateOutboxEvent(ctx context.Context, tx *sql.Tx, event OutboxEvent) error {
+	query := `
+		INSERT INTO notification_outbox
+			(id, event_type, product_id, seller_id, payload, status, attempts, next_attempt_at)
+		VALUES
+			(:id, :event_type, :product_id, :seller_id, :payload, :status, :attempts, :next_attempt_at)
+	`
+
+	updatedQuery, args, err := sqlx.Named(query, event)
+	if err != nil {
+		return err
+	}
+
+	if tx != nil {
+		_, err = tx.ExecContext(ctx, sqlx.Rebind(sqlx.DOLLAR, updatedQuery), args...)
+	} else {
+		_, err = r.db.ExecContext(ctx, sqlx.Rebind(sqlx.DOLLAR, updatedQuery), args...)
+	}
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ClaimNextOutboxEvent locks the oldest pending event that is due, skipping the ones other workers
+// already hold. It returns sql.ErrNoRows when there is nothing to process.
+func (r NotificationRepo) ClaimNextOutboxEvent(ctx context.Context, tx *sql.Tx) (OutboxEvent, error) {
+	var result OutboxEvent
+
+	query := `
+		SELECT
+			id,
+			event_type,
+			product_id,
+			seller_id,
+			payload,
+			status,
+			attempts,
+			next_attempt_at,
+			last_error,
+			created_at,
+			processed_at
+		FROM
+			notification_outbox
+		WHERE
+			status = $1
+			AND next_attempt_at <= NOW()
+		ORDER BY
+			next_attempt_at
+		LIMIT 1
+		FOR UPDATE SKIP LOCKED
+	`
+
+	row := tx.QueryRowContext(ctx, query, OutboxStatusPending)
+	err := row.Scan(
+		&result.ID,
+		&result.EventType,
+		&result.ProductID,
+		&result.SellerID,
+		&result.Payload,
+		&result.Status,
+		&result.Attempts,
+		&result.NextAttemptAt,
+		&result.LastError,
+		&result.CreatedAt,
+		&result.ProcessedAt,
+	)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r NotificationRepo) MarkOutboxEventProcessed(ctx context.Context, tx *sql.Tx, eventID string) error {
+	query := `
+		UPDATE notification_outbox
+		SET
+			status = $1,
+			attempts = attempts + 1,
+			last_error = NULL,
+			processed_at = NOW()
+		WHERE
+			id = $2
+	`
+
+	result, err := tx.ExecContext(ctx, query, OutboxStatusProcessed, eventID)
+	if err != nil {
+		return err
+	}
+
+	affectedRows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affectedRows != 1 {
+		return errors.New("error affected row count is not equal to 1")
+	}
+
+	return nil
+}
+
+// MarkOutboxEventFailed records a failed attempt. The event is retried at nextAttemptAt while status is
+// still pending, or given up on when status is failed.
+func (r NotificationRepo) MarkOutboxEventFailed(ctx context.Context, eventID, status, lastError string, nextAttemptAt time.Time) error {
+	query := `
+		UPDATE notification_outbox
+		SET
+			status = $1,
+			attempts = attempts + 1,
+			last_error = $2,
+			next_attempt_at = $3
+		WHERE
+			id = $4
+	`
+
+	result, err := r.db.ExecContext(ctx, query, status, lastError, nextAttemptAt, eventID)
+	if err != nil {
+		return err
+	}
+
+	affectedRows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affectedRows != 1 {
+		return errors.New("error affected row count is not equal to 1")
+	}
+
+	return nil
+}
+
+// GetWatcherIDs returns the users that favourited or subscribed to the product, other than its seller
+func (r NotificationRepo) GetWatcherIDs(ctx context.Context, tx *sql.Tx, productID, sellerID string) ([]string, error) {
+	query := `
+		SELECT user_id FROM favourites WHERE product_id = $1 AND user_id <> $2
+		UNION
+		SELECT user_id FROM product_subscriptions WHERE product_id = $1 AND user_id <> $2
+	`
+
+	rows, err := tx.QueryContext(ctx, query, productID, sellerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	userIDs := []string{}
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return userIDs, nil
+}
+
+// CreateNotifications skips users that were already notified of the same outbox event, so a retried
+// event doesn't notify anyone twice
+func (r NotificationRepo) CreateNotifications(ctx context.Context, tx *sql.Tx, notifications []Notification) error {
+	query := `
+		INSERT INTO notifications
+			(id, user_id, type, title, body, product_id, outbox_event_id)
+		VALUES
+			(:id, :user_id, :type, :title, :body, :product_id, :outbox_event_id)
+		ON CONFLICT (outbox_event_id, user_id) DO NOTHING
+	`
+
+	updatedQuery, args, err := sqlx.Named(query, notifications)
+	if err != nil {
+		return err
+	}
+
+	if tx != nil {
+		_, err = tx.ExecContext(ctx, sqlx.Rebind(sqlx.DOLLAR, updatedQuery), args...)
+	} else {
+		_, err = r.db.ExecContext(ctx, sqlx.Rebind(sqlx.DOLLAR, updatedQuery), args...)
+	}
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r NotificationRepo) ListNotifications(ctx context.Context, userID string, req ListNotificationsRequest) ([]Notification, int, error) {
+	var notifications []Notification
+
+	countQuery := `
+		SELECT
+			COUNT(*)
+		FROM
+			notifications
+		WHERE
+			user_id = $1
+			AND ($2 = FALSE OR read_at IS NULL)
+	`
+
+	var count int
+	err := r.db.GetContext(ctx, &count, countQuery, userID, req.UnreadOnly)
+	if err != nil {
+		return notifications, count, err
+	}
+
+	limit := req.Limit
+	if limit <= 0 {
+		limit = 10
+	}
+
+	offset := req.Offset
+	if offset < 0 {
+		offset = 0
+	}
+
+	query := `
+		SELECT
+			id,
+			user_id,
+			type,
+			title,
+			body,
+			product_id,
+			outbox_event_id,
+			read_at,
+			created_at
+		FROM
+			notifications
+		WHERE
+			user_id = $1
+			AND ($2 = FALSE OR read_at IS NULL)
+		ORDER BY
+			created_at DESC
+		LIMIT $3 OFFSET $4
+	`
+
+	err = r.db.SelectContext(ctx, &notifications, query, userID, req.UnreadOnly, limit, offset)
+	if err != nil {
+		return notifications, count, err
+	}
+
+	return notifications, count, nil
+}
+
+// MarkNotificationRead returns sql.ErrNoRows when the user has no such notification.
+// Reading an already read notification keeps its original read time.
+func (r NotificationRepo) MarkNotificationRead(ctx context.Context, userID, notificationID string) (Notification, error) {
+	var result Notification
+
+	query := `
+		UPDATE notifications
+		SET
+			read_at = COALESCE(read_at, NOW())
+		WHERE
+			id = $1
+			AND user_id = $2
+		RETURNING
+			id,
+			user_id,
+			type,
+			title,
+			body,
+			product_id,
+			outbox_event_id,
+			read_at,
+			created_at
+	`
+
+	err := r.db.GetContext(ctx, &result, query, notificationID, userID)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
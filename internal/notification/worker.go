@@ -0,0 +1,163 @@
+package notification
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ahmadnaufal/openidea-shopifyx/pkg/money"
+	"github.com/ahmadnaufal/openidea-shopifyx/pkg/retry"
+	"github.com/google/uuid"
+)
+
+const (
+	outboxBatchSize   = 100
+	outboxMaxAttempts = 8
+	outboxRetryBase   = 30 * time.Second
+	outboxRetryMax    = time.Hour
+)
+
+// RunOutboxWorker periodically turns pending outbox events into notifications. Failed events are retried
+// with exponential backoff. It blocks until ctx is cancelled, so it should be run in its own goroutine.
+func RunOutboxWorker(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		processed, err := processOutbox(ctx)
+		if err != nil {
+			log.Printf("error processing notification outbox: %v", err)
+		} else if processed > 0 {
+			log.Printf("processed %d notification outbox events", processed)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func processOutbox(ctx context.Context) (int, error) {
+	total := 0
+	for total < outboxBatchSize {
+		processed, err := processNextOutboxEvent(ctx)
+		if err != nil {
+			return total, err
+		}
+		if !processed {
+			return total, nil
+		}
+
+		total++
+	}
+
+	return total, nil
+}
+
+// processNextOutboxEvent handles a single event in its own transaction, so one failing event doesn't hold
+// back the others. It returns false when there are no due events left.
+func processNextOutboxEvent(ctx context.Context) (bool, error) {
+	tx, err := TrxProvider.NewTransaction(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	event, err := NotificationRepoImpl.ClaimNextOutboxEvent(ctx, tx)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	deliverErr := deliverOutboxEvent(ctx, tx, event)
+	if deliverErr == nil {
+		deliverErr = NotificationRepoImpl.MarkOutboxEventProcessed(ctx, tx, event.ID)
+	}
+	if deliverErr == nil {
+		deliverErr = tx.Commit()
+	}
+	if deliverErr == nil {
+		return true, nil
+	}
+
+	// release the event's lock before recording the failure outside of the transaction
+	tx.Rollback()
+
+	attempt := event.Attempts + 1
+	status := OutboxStatusPending
+	if attempt >= outboxMaxAttempts {
+		status = OutboxStatusFailed
+	}
+
+	log.Printf("error delivering notification outbox event %s (attempt %d): %v", event.ID, attempt, deliverErr)
+	err = NotificationRepoImpl.MarkOutboxEventFailed(ctx, event.ID, status, deliverErr.Error(), time.Now().Add(retry.Backoff(attempt, outboxRetryBase, outboxRetryMax)))
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func deliverOutboxEvent(ctx context.Context, tx *sql.Tx, event OutboxEvent) error {
+	var payload ProductChangePayload
+	err := json.Unmarshal(event.Payload, &payload)
+	if err != nil {
+		return err
+	}
+
+	title, body, err := buildNotificationMessage(event.EventType, payload)
+	if err != nil {
+		return err
+	}
+
+	watcherIDs, err := NotificationRepoImpl.GetWatcherIDs(ctx, tx, event.ProductID, event.SellerID)
+	if err != nil {
+		return err
+	}
+	if len(watcherIDs) == 0 {
+		return nil
+	}
+
+	notifications := []Notification{}
+	for _, watcherID := range watcherIDs {
+		notifications = append(notifications, Notification{
+			ID:            uuid.NewString(),
+			UserID:        watcherID,
+			Type:          event.EventType,
+			Title:         title,
+			Body:          body,
+			ProductID:     &event.ProductID,
+			OutboxEventID: &event.ID,
+		})
+	}
+
+	return NotificationRepoImpl.CreateNotifications(ctx, tx, notifications)
+}
+
+func buildNotificationMessage(eventType string, payload ProductChangePayload) (string, string, error) {
+	currency := money.Currency(payload.Currency)
+	newPrice := money.New(payload.NewPrice, currency)
+
+	switch eventType {
+	case EventTypePriceDrop:
+		oldPrice := money.New(payload.OldPrice, currency)
+		return fmt.Sprintf("Price drop on %s", payload.ProductName),
+			fmt.Sprintf("%s is now %s, down from %s", payload.ProductName, newPrice.Format(), oldPrice.Format()),
+			nil
+
+	case EventTypeBackInStock:
+		return fmt.Sprintf("%s is back in stock", payload.ProductName),
+			fmt.Sprintf("%d available now for %s", payload.Stock, newPrice.Format()),
+			nil
+	}
+
+	return "", "", fmt.Errorf("unknown notification event type %q", eventType)
+}
@@ -0,0 +1,310 @@
+package payment
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/admin"
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/config"
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/model"
+	"github.com/ahmadnaufal/openidea-shopifyx/pkg/jwt"
+	"github.com/ahmadnaufal/openidea-shopifyx/pkg/validation"
+	"github.com/gofiber/fiber/v2"
+	"github.com/pkg/errors"
+)
+
+var (
+	PaymentRepoImpl *PaymentRepo
+	TrxProvider     *config.TransactionProvider
+	Providers       map[string]Provider
+
+	// OrderPaidHandler moves the paid order forward in the same transaction as its payment.
+	// It is provided by the order's owner, so this package doesn't depend on it.
+	OrderPaidHandler func(ctx context.Context, tx *sql.Tx, orderID string) error
+	// OrderLockHandler locks the order in the given transaction. Orders are locked before their
+	// payments everywhere, so settling a payment can't deadlock with cancelling its order.
+	OrderLockHandler func(ctx context.Context, tx *sql.Tx, orderID string) error
+)
+
+func RegisterRoute(r *fiber.App, jwtProvider jwt.JWTProvider) {
+	paymentGroup := r.Group("/v1/payment")
+	authMiddleware := jwtProvider.Middleware()
+
+	// callbacks come from the providers, they are authenticated by their signature instead
+	paymentGroup.Post("/callback/:provider", HandleCallback)
+
+	if _, ok := Providers[ProviderMock]; ok {
+		paymentGroup.Post("/mock/:order_id", authMiddleware, SimulateMockPayment)
+	}
+
+	adminGroup := r.Group("/v1/admin/payments", authMiddleware, admin.Middleware())
+	adminGroup.Get("/refund-required", ListRefundRequiredPayments)
+	adminGroup.Post("/:payment_id/refunded", MarkPaymentRefunded)
+}
+
+type SimulateMockPaymentRequest struct {
+	Status string `json:"status" validate:"oneof=paid failed"`
+}
+
+func HandleCallback(c *fiber.Ctx) error {
+	provider, ok := Providers[c.Params("provider")]
+	if !ok {
+		return c.Status(fiber.StatusNotFound).JSON(model.ErrorResponse{
+			Message: ErrProviderNotFound.Error(),
+			Code:    "entity_not_found",
+		})
+	}
+
+	event, err := provider.VerifyCallback(c.Body(), func(key string) string {
+		return c.Get(key)
+	})
+	if err != nil {
+		if errors.Is(err, ErrCallbackNotSupported) {
+			return c.Status(fiber.StatusNotFound).JSON(model.ErrorResponse{
+				Message: err.Error(),
+				Code:    "entity_not_found",
+			})
+		}
+
+		if errors.Is(err, ErrInvalidSignature) {
+			return c.Status(fiber.StatusUnauthorized).JSON(model.ErrorResponse{
+				Message: err.Error(),
+				Code:    "invalid_signature",
+			})
+		}
+
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "invalid_request_body",
+		})
+	}
+
+	ctx := c.Context()
+	payment, err := applyCallbackEvent(ctx, provider.Name(), event)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return c.Status(fiber.StatusNotFound).JSON(model.ErrorResponse{
+				Message: "payment not found",
+				Code:    "entity_not_found",
+			})
+		}
+
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(model.DataResponse{
+		Message: "ok",
+		Data:    ToResponse(payment),
+	})
+}
+
+// applyCallbackEvent settles a pending payment. Providers may send the same callback more than
+// once, so callbacks for payments that are already settled are acknowledged without changes.
+// A payment made after its order was cancelled is kept for an admin to refund.
+func applyCallbackEvent(ctx context.Context, providerName string, event CallbackEvent) (Payment, error) {
+	tx, err := TrxProvider.NewTransaction(ctx)
+	if err != nil {
+		return Payment{}, err
+	}
+	defer tx.Rollback()
+
+	orderID, err := PaymentRepoImpl.GetPaymentOrderIDByExternalID(ctx, tx, providerName, event.ExternalID)
+	if err != nil {
+		return Payment{}, err
+	}
+
+	err = OrderLockHandler(ctx, tx, orderID)
+	if err != nil {
+		return Payment{}, err
+	}
+
+	payment, err := PaymentRepoImpl.GetPaymentByExternalIDForUpdate(ctx, tx, providerName, event.ExternalID)
+	if err != nil {
+		return Payment{}, err
+	}
+
+	if payment.Status == StatusExpired && event.Status == StatusPaid {
+		return flagPaidAfterCancel(ctx, tx, payment)
+	}
+
+	if payment.Status != StatusPending {
+		return payment, nil
+	}
+
+	payment.Status = event.Status
+	if event.Status == StatusPaid {
+		now := time.Now()
+		payment.PaidAt = &now
+	}
+
+	err = PaymentRepoImpl.UpdatePaymentStatus(ctx, tx, payment.ID, payment.Status, payment.PaidAt)
+	if err != nil {
+		return Payment{}, err
+	}
+
+	if payment.Status == StatusPaid {
+		err = OrderPaidHandler(ctx, tx, payment.OrderID)
+		if err != nil {
+			return Payment{}, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return Payment{}, err
+	}
+
+	return payment, nil
+}
+
+// flagPaidAfterCancel keeps a payment made for a cancelled order, so an admin can give it back to the buyer
+func flagPaidAfterCancel(ctx context.Context, tx *sql.Tx, payment Payment) (Payment, error) {
+	now := time.Now()
+	payment.Status = StatusRefundRequired
+	payment.PaidAt = &now
+
+	err := PaymentRepoImpl.UpdatePaymentStatus(ctx, tx, payment.ID, payment.Status, payment.PaidAt)
+	if err != nil {
+		return Payment{}, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return Payment{}, err
+	}
+
+	log.Printf("payment %s was paid after order %s was cancelled, it needs a refund", payment.ID, payment.OrderID)
+
+	return payment, nil
+}
+
+// ListRefundRequiredPayments lists the payments made for cancelled orders, which admins have to refund
+func ListRefundRequiredPayments(c *fiber.Ctx) error {
+	payments, err := PaymentRepoImpl.ListPaymentsByStatus(c.Context(), StatusRefundRequired)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	responses := make([]AdminPaymentResponse, len(payments))
+	for i, payment := range payments {
+		responses[i] = toAdminResponse(payment)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(model.DataResponse{
+		Message: "ok",
+		Data:    responses,
+	})
+}
+
+// MarkPaymentRefunded records that an admin gave the buyer back a payment made for a cancelled order
+func MarkPaymentRefunded(c *fiber.Ctx) error {
+	payment, err := PaymentRepoImpl.TransitionPaymentStatus(c.Context(), c.Params("payment_id"), StatusRefundRequired, StatusRefunded)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return c.Status(fiber.StatusNotFound).JSON(model.ErrorResponse{
+				Message: "payment waiting for a refund not found",
+				Code:    "entity_not_found",
+			})
+		}
+
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(model.DataResponse{
+		Message: "ok",
+		Data:    toAdminResponse(payment),
+	})
+}
+
+// SimulateMockPayment lets the buyer settle their mock payment in development, by sending
+// the signed callback the mock gateway would have sent
+func SimulateMockPayment(c *fiber.Ctx) error {
+	orderID := c.Params("order_id")
+
+	claims, err := jwt.GetLoggedInUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "forbidden",
+		})
+	}
+
+	var payload SimulateMockPaymentRequest
+	if err := c.BodyParser(&payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "invalid_request_body",
+		})
+	}
+
+	// validation for request body
+	if err := validation.Validate(payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "failed_request_body_validation",
+		})
+	}
+
+	ctx := c.Context()
+	payment, err := PaymentRepoImpl.GetPaymentByOrderID(ctx, orderID)
+	if err != nil && err != sql.ErrNoRows {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+	if err == sql.ErrNoRows || payment.UserID != claims.UserID || payment.Provider != ProviderMock {
+		return c.Status(fiber.StatusNotFound).JSON(model.ErrorResponse{
+			Message: "payment not found",
+			Code:    "entity_not_found",
+		})
+	}
+
+	body, err := json.Marshal(mockCallbackPayload{
+		ExternalID: payment.ExternalID,
+		Status:     payload.Status,
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	provider := Providers[ProviderMock]
+	signature := provider.(MockProvider).Sign(body)
+	event, err := provider.VerifyCallback(body, func(key string) string {
+		return signature
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	payment, err = applyCallbackEvent(ctx, ProviderMock, event)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(model.DataResponse{
+		Message: "ok",
+		Data:    ToResponse(payment),
+	})
+}
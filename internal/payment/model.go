@@ -0,0 +1,52 @@
+package payment
+
+import (
+	"time"
+
+	"github.com/ahmadnaufal/openidea-shopifyx/pkg/money"
+	"github.com/pkg/errors"
+)
+
+const (
+	ProviderManualTransfer = "manual_transfer"
+	ProviderMock           = "mock"
+)
+
+const (
+	StatusPending = "pending"
+	StatusPaid    = "paid"
+	StatusFailed  = "failed"
+	// the order was cancelled before the payment was made
+	StatusExpired = "expired"
+	// the buyer paid after the order was cancelled, an admin has to give the money back
+	StatusRefundRequired = "refund_required"
+	StatusRefunded       = "refunded"
+)
+
+var (
+	ErrProviderNotFound      = errors.New("payment provider not found")
+	ErrCallbackNotSupported  = errors.New("payment provider does not send callbacks")
+	ErrInvalidSignature      = errors.New("invalid callback signature")
+	ErrInvalidCallbackStatus = errors.New("invalid callback status")
+	ErrMissingCallbackSecret = errors.New("mock payment provider is enabled without a callback secret")
+)
+
+type Payment struct {
+	ID                   string         `db:"id"`
+	OrderID              string         `db:"order_id"`
+	UserID               string         `db:"user_id"`
+	Provider             string         `db:"provider"`
+	Status               string         `db:"status"`
+	Amount               int64          `db:"amount"`
+	Currency             money.Currency `db:"currency"`
+	ExternalID           string         `db:"external_id"`
+	VirtualAccountNumber *string        `db:"virtual_account_number"`
+	PaymentProofImageURL *string        `db:"payment_proof_image_url"`
+	PaidAt               *time.Time     `db:"paid_at"`
+	CreatedAt            time.Time      `db:"created_at"`
+}
+
+type mockCallbackPayload struct {
+	ExternalID string `json:"externalId"`
+	Status     string `json:"status"`
+}
@@ -0,0 +1,153 @@
+package payment
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ahmadnaufal/openidea-shopifyx/pkg/money"
+	"github.com/google/uuid"
+)
+
+type ChargeRequest struct {
+	PaymentID string
+	OrderID   string
+	Amount    money.Money
+	// PaymentProofImageURL is the transfer receipt, only used by the manual transfer provider
+	PaymentProofImageURL string
+}
+
+type Charge struct {
+	// ExternalID identifies the charge at the provider, callbacks refer to it
+	ExternalID           string
+	Status               string
+	VirtualAccountNumber *string
+}
+
+// CallbackEvent is a verified notification from the provider about a charge
+type CallbackEvent struct {
+	ExternalID string
+	Status     string
+}
+
+// Provider is a way for buyers to pay for their orders
+type Provider interface {
+	Name() string
+	// CreateCharge asks the provider to collect the amount. A charge may be paid straight away,
+	// or stay pending until the provider calls back.
+	CreateCharge(ctx context.Context, req ChargeRequest) (Charge, error)
+	// VerifyCallback checks the callback's signature and returns the event it carries
+	VerifyCallback(body []byte, header func(key string) string) (CallbackEvent, error)
+}
+
+// NewProviders builds the enabled providers, keyed by their name. The mock provider needs a callback
+// secret, without one anyone could sign its callbacks and mark orders as paid.
+func NewProviders(mockEnabled bool, mockCallbackSecret string) (map[string]Provider, error) {
+	providers := map[string]Provider{
+		ProviderManualTransfer: ManualTransferProvider{},
+	}
+
+	if mockEnabled {
+		if mockCallbackSecret == "" {
+			return providers, ErrMissingCallbackSecret
+		}
+		providers[ProviderMock] = NewMockProvider(mockCallbackSecret)
+	}
+
+	return providers, nil
+}
+
+// ManualTransferProvider is the original flow: the buyer transfers to the seller's bank account
+// and uploads the receipt, which is accepted as the payment
+type ManualTransferProvider struct{}
+
+func (p ManualTransferProvider) Name() string {
+	return ProviderManualTransfer
+}
+
+func (p ManualTransferProvider) CreateCharge(ctx context.Context, req ChargeRequest) (Charge, error) {
+	return Charge{
+		ExternalID: req.PaymentID,
+		Status:     StatusPaid,
+	}, nil
+}
+
+func (p ManualTransferProvider) VerifyCallback(body []byte, header func(key string) string) (CallbackEvent, error) {
+	return CallbackEvent{}, ErrCallbackNotSupported
+}
+
+// MockSignatureHeader carries the hex HMAC-SHA256 of the callback body
+const MockSignatureHeader = "X-Mock-Signature"
+
+// MockProvider behaves like a virtual account gateway without talking to one: charges stay
+// pending until a callback signed with the shared secret marks them as paid or failed
+type MockProvider struct {
+	secret string
+}
+
+func NewMockProvider(secret string) MockProvider {
+	return MockProvider{secret: secret}
+}
+
+func (p MockProvider) Name() string {
+	return ProviderMock
+}
+
+func (p MockProvider) CreateCharge(ctx context.Context, req ChargeRequest) (Charge, error) {
+	externalID := "mock_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+
+	return Charge{
+		ExternalID:           externalID,
+		Status:               StatusPending,
+		VirtualAccountNumber: mockVirtualAccountNumber(externalID),
+	}, nil
+}
+
+func (p MockProvider) VerifyCallback(body []byte, header func(key string) string) (CallbackEvent, error) {
+	if p.secret == "" {
+		return CallbackEvent{}, ErrInvalidSignature
+	}
+
+	expected := p.Sign(body)
+	if !hmac.Equal([]byte(expected), []byte(header(MockSignatureHeader))) {
+		return CallbackEvent{}, ErrInvalidSignature
+	}
+
+	var payload mockCallbackPayload
+	err := json.Unmarshal(body, &payload)
+	if err != nil {
+		return CallbackEvent{}, err
+	}
+
+	if payload.Status != StatusPaid && payload.Status != StatusFailed {
+		return CallbackEvent{}, ErrInvalidCallbackStatus
+	}
+
+	return CallbackEvent{
+		ExternalID: payload.ExternalID,
+		Status:     payload.Status,
+	}, nil
+}
+
+// Sign returns the signature the mock gateway would send along with the callback body
+func (p MockProvider) Sign(body []byte) string {
+	mac := hmac.New(sha256.New, []byte(p.secret))
+	mac.Write(body)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// mockVirtualAccountNumber derives a stable 16-digit account number from the charge
+func mockVirtualAccountNumber(externalID string) *string {
+	sum := sha256.Sum256([]byte(externalID))
+	number := new(big.Int).SetBytes(sum[:8])
+	number.Mod(number, big.NewInt(1e12))
+
+	virtualAccountNumber := fmt.Sprintf("8808%012d", number.Int64())
+	return &virtualAccountNumber
+}
@@ -0,0 +1,283 @@
+package payment
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+type PaymentRepo struct {
+	db *sqlx.DB
+}
+
+func NewPaymentRepo(db *sqlx.DB) PaymentRepo {
+	return PaymentRepo{db: db}
+}
+
+func (r PaymentRepo) CreatePayment(ctx context.Context, tx *sql.Tx, payment Payment) error {
+	query := `
+		INSERT INTO order_payments
+			(
+				id,
+				order_id,
+				user_id,
+				provider,
+				status,
+				amount,
+				currency,
+				external_id,
+				virtual_account_number,
+				payment_proof_image_url,
+				paid_at,
+				created_at
+			)
+		VALUES
+			(
+				:id,
+				:order_id,
+				:user_id,
+				:provider,
+				:status,
+				:amount,
+				:currency,
+				:external_id,
+				:virtual_account_number,
+				:payment_proof_image_url,
+				:paid_at,
+				:created_at
+			)
+	`
+
+	updatedQuery, args, err := sqlx.Named(query, payment)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, sqlx.Rebind(sqlx.DOLLAR, updatedQuery), args...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r PaymentRepo) GetPaymentByOrderID(ctx context.Context, orderID string) (Payment, error) {
+	var result Payment
+
+	query := `
+		SELECT
+			id,
+			order_id,
+			user_id,
+			provider,
+			status,
+			amount,
+			currency,
+			external_id,
+			virtual_account_number,
+			payment_proof_image_url,
+			paid_at,
+			created_at
+		FROM
+			order_payments
+		WHERE
+			order_id = $1
+		LIMIT 1
+	`
+
+	err := r.db.GetContext(ctx, &result, query, orderID)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// GetPaymentOrderIDByExternalID finds the order a callback is for, so the order can be locked before its payment
+func (r PaymentRepo) GetPaymentOrderIDByExternalID(ctx context.Context, tx *sql.Tx, provider, externalID string) (string, error) {
+	var orderID string
+
+	query := `
+		SELECT
+			order_id
+		FROM
+			order_payments
+		WHERE
+			provider = $1
+			AND external_id = $2
+		LIMIT 1
+	`
+
+	err := tx.QueryRowContext(ctx, query, provider, externalID).Scan(&orderID)
+	if err != nil {
+		return orderID, err
+	}
+
+	return orderID, nil
+}
+
+// ListPaymentsByStatus returns the payments in the status, oldest first
+func (r PaymentRepo) ListPaymentsByStatus(ctx context.Context, status string) ([]Payment, error) {
+	result := []Payment{}
+
+	query := `
+		SELECT
+			id,
+			order_id,
+			user_id,
+			provider,
+			status,
+			amount,
+			currency,
+			external_id,
+			virtual_account_number,
+			payment_proof_image_url,
+			paid_at,
+			created_at
+		FROM
+			order_payments
+		WHERE
+			status = $1
+		ORDER BY
+			created_at
+	`
+
+	err := r.db.SelectContext(ctx, &result, query, status)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// TransitionPaymentStatus moves the payment from one status to another. It returns sql.ErrNoRows
+// if the payment isn't in fromStatus.
+func (r PaymentRepo) TransitionPaymentStatus(ctx context.Context, paymentID, fromStatus, toStatus string) (Payment, error) {
+	var result Payment
+
+	query := `
+		UPDATE order_payments
+		SET
+			status = $1
+		WHERE
+			id = $2
+			AND status = $3
+		RETURNING
+			id,
+			order_id,
+			user_id,
+			provider,
+			status,
+			amount,
+			currency,
+			external_id,
+			virtual_account_number,
+			payment_proof_image_url,
+			paid_at,
+			created_at
+	`
+
+	err := r.db.GetContext(ctx, &result, query, toStatus, paymentID, fromStatus)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// GetPaymentByExternalIDForUpdate locks the payment so concurrent callbacks are applied one at a time
+func (r PaymentRepo) GetPaymentByExternalIDForUpdate(ctx context.Context, tx *sql.Tx, provider, externalID string) (Payment, error) {
+	var result Payment
+
+	query := `
+		SELECT
+			id,
+			order_id,
+			user_id,
+			provider,
+			status,
+			amount,
+			currency,
+			external_id,
+			virtual_account_number,
+			payment_proof_image_url,
+			paid_at,
+			created_at
+		FROM
+			order_payments
+		WHERE
+			provider = $1
+			AND external_id = $2
+		LIMIT 1
+		FOR UPDATE
+	`
+
+	row := tx.QueryRowContext(ctx, query, provider, externalID)
+	err := row.Scan(
+		&result.ID,
+		&result.OrderID,
+		&result.UserID,
+		&result.Provider,
+		&result.Status,
+		&result.Amount,
+		&result.Currency,
+		&result.ExternalID,
+		&result.VirtualAccountNumber,
+		&result.PaymentProofImageURL,
+		&result.PaidAt,
+		&result.CreatedAt,
+	)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r PaymentRepo) UpdatePaymentStatus(ctx context.Context, tx *sql.Tx, paymentID, status string, paidAt *time.Time) error {
+	query := `
+		UPDATE order_payments
+		SET
+			status = $1,
+			paid_at = $2
+		WHERE
+			id = $3
+	`
+
+	result, err := tx.ExecContext(ctx, query, status, paidAt, paymentID)
+	if err != nil {
+		return err
+	}
+
+	affectedRows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affectedRows != 1 {
+		return errors.New("error affected row count is not equal to 1")
+	}
+
+	return nil
+}
+
+// ExpirePayment marks the order's unsettled payment as expired, so late callbacks don't pay a cancelled order
+func (r PaymentRepo) ExpirePayment(ctx context.Context, tx *sql.Tx, orderID string) error {
+	query := `
+		UPDATE order_payments
+		SET
+			status = $1
+		WHERE
+			order_id = $2
+			AND status IN ($3, $4)
+	`
+
+	_, err := tx.ExecContext(ctx, query, StatusExpired, orderID, StatusPending, StatusFailed)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,45 @@
+package payment
+
+import (
+	"time"
+
+	"github.com/ahmadnaufal/openidea-shopifyx/pkg/money"
+)
+
+type PaymentResponse struct {
+	PaymentID            string      `json:"paymentId"`
+	Provider             string      `json:"provider"`
+	Status               string      `json:"status"`
+	Amount               money.Money `json:"amount"`
+	VirtualAccountNumber *string     `json:"virtualAccountNumber,omitempty"`
+	PaymentProofImageURL *string     `json:"paymentProofImageUrl,omitempty"`
+	PaidAt               *time.Time  `json:"paidAt,omitempty"`
+}
+
+// AdminPaymentResponse is a payment as admins see it, with the order and buyer it belongs to
+type AdminPaymentResponse struct {
+	PaymentResponse
+	OrderID string `json:"orderId"`
+	UserID  string `json:"userId"`
+}
+
+// ToResponse converts the payment to its API representation
+func ToResponse(payment Payment) PaymentResponse {
+	return PaymentResponse{
+		PaymentID:            payment.ID,
+		Provider:             payment.Provider,
+		Status:               payment.Status,
+		Amount:               money.New(payment.Amount, payment.Currency),
+		VirtualAccountNumber: payment.VirtualAccountNumber,
+		PaymentProofImageURL: payment.PaymentProofImageURL,
+		PaidAt:               payment.PaidAt,
+	}
+}
+
+func toAdminResponse(payment Payment) AdminPaymentResponse {
+	return AdminPaymentResponse{
+		PaymentResponse: ToResponse(payment),
+		OrderID:         payment.OrderID,
+		UserID:          payment.UserID,
+	}
+}
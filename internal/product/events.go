@@ -0,0 +1,100 @@
+package product
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/realtime"
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/webhook"
+)
+
+// FilterVisibleProductIDs keeps the products the viewer may watch: published ones, and their own
+func FilterVisibleProductIDs(ctx context.Context, viewerID string, productIDs []string) ([]string, error) {
+	return ProductRepoImpl.GetVisibleProductIDs(ctx, productIDs, viewerID)
+}
+
+// The emit functions announce a change to the realtime clients and the seller's webhooks. They must be
+// called in the transaction making the change, so nothing is announced if it is rolled back.
+
+func emitStockChanged(ctx context.Context, tx *sql.Tx, product Product) error {
+	err := realtime.Publish(ctx, tx, realtime.Event{
+		Type:      realtime.EventTypeStockChanged,
+		ProductID: product.ID,
+	}, realtime.StockChangedData{
+		ProductID: product.ID,
+		Stock:     product.Stock,
+	})
+	if err != nil {
+		return err
+	}
+
+	return WebhookRepoImpl.EnqueueEvent(ctx, tx, product.UserID, webhook.EventProductStockChanged, webhook.ProductStockChangedData{
+		ProductID: product.ID,
+		Stock:     product.Stock,
+	})
+}
+
+func emitProductDeleted(ctx context.Context, tx *sql.Tx, product Product) error {
+	return WebhookRepoImpl.EnqueueEvent(ctx, tx, product.UserID, webhook.EventProductDeleted, webhook.ProductDeletedData{
+		ProductID: product.ID,
+	})
+}
+
+func emitOrderCreated(ctx context.Context, tx *sql.Tx, order Order) error {
+	err := emitOrderStatusChanged(ctx, tx, order)
+	if err != nil {
+		return err
+	}
+
+	return WebhookRepoImpl.EnqueueEvent(ctx, tx, order.SellerID, webhook.EventOrderCreated, orderEntityToResponse(order))
+}
+
+// emitOrderStatusChanged pushes the order's status to its buyer and seller
+func emitOrderStatusChanged(ctx context.Context, tx *sql.Tx, order Order) error {
+	return realtime.Publish(ctx, tx, realtime.Event{
+		Type:    realtime.EventTypeOrderStatusChanged,
+		UserIDs: []string{order.UserID, order.SellerID},
+	}, realtime.OrderStatusChangedData{
+		OrderID:   order.ID,
+		ProductID: order.ProductID,
+		Status:    order.Status,
+	})
+}
+
+func emitOrderPaid(ctx context.Context, tx *sql.Tx, order Order) error {
+	return WebhookRepoImpl.EnqueueEvent(ctx, tx, order.SellerID, webhook.EventOrderPaid, webhook.OrderPaidData{
+		OrderID:   order.ID,
+		ProductID: order.ProductID,
+	})
+}
+
+// OrderCancelReasonPaymentExpired is sent when an order wasn't paid before its payment deadline
+const OrderCancelReasonPaymentExpired = "payment_expired"
+
+func emitOrderCancelled(ctx context.Context, tx *sql.Tx, order Order, reason string) error {
+	err := emitOrderStatusChanged(ctx, tx, order)
+	if err != nil {
+		return err
+	}
+
+	return WebhookRepoImpl.EnqueueEvent(ctx, tx, order.SellerID, webhook.EventOrderCancelled, webhook.OrderCancelledData{
+		OrderID:   order.ID,
+		ProductID: order.ProductID,
+		Reason:    reason,
+	})
+}
+
+func emitOrderRefunded(ctx context.Context, tx *sql.Tx, order Order, refund Refund) error {
+	err := emitOrderStatusChanged(ctx, tx, order)
+	if err != nil {
+		return err
+	}
+
+	return WebhookRepoImpl.EnqueueEvent(ctx, tx, order.SellerID, webhook.EventOrderRefunded, webhook.OrderRefundedData{
+		OrderID:          order.ID,
+		ProductID:        order.ProductID,
+		Amount:           refund.Amount,
+		Currency:         string(refund.Currency),
+		RestoredQuantity: refund.RestoredQuantity,
+	})
+}
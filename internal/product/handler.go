@@ -2,23 +2,53 @@ package product
 
 import (
 	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
+	"time"
 
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/address"
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/admin"
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/analytics"
 	bankaccount "github.com/ahmadnaufal/openidea-shopifyx/internal/bank_account"
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/commission"
 	"github.com/ahmadnaufal/openidea-shopifyx/internal/config"
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/coupon"
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/idempotency"
 	"github.com/ahmadnaufal/openidea-shopifyx/internal/model"
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/notification"
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/payment"
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/shipping"
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/tax"
 	"github.com/ahmadnaufal/openidea-shopifyx/internal/user"
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/webhook"
 	"github.com/ahmadnaufal/openidea-shopifyx/pkg/jwt"
+	"github.com/ahmadnaufal/openidea-shopifyx/pkg/money"
 	"github.com/ahmadnaufal/openidea-shopifyx/pkg/validation"
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
+	"github.com/pkg/errors"
 )
 
 var (
-	ProductRepoImpl     *ProductRepo
-	UserRepoImpl        *user.UserRepo
-	BankAccountRepoImpl *bankaccount.BankAccountRepo
-	TrxProvider         *config.TransactionProvider
+	ProductRepoImpl      *ProductRepo
+	UserRepoImpl         *user.UserRepo
+	BankAccountRepoImpl  *bankaccount.BankAccountRepo
+	AddressRepoImpl      *address.AddressRepo
+	CouponRepoImpl       *coupon.CouponRepo
+	ViewRecorderImpl     *analytics.ViewRecorder
+	NotificationRepoImpl *notification.NotificationRepo
+	WebhookRepoImpl      *webhook.WebhookRepo
+	ShippingProviderImpl shipping.RateProvider
+	PaymentRepoImpl      *payment.PaymentRepo
+	PaymentProviders     map[string]payment.Provider
+	TaxRepoImpl          *tax.TaxRepo
+	TrxProvider          *config.TransactionProvider
+
+	// TrashRetention is how long a soft-deleted product can be restored before being purged
+	TrashRetention time.Duration
+	// PaymentDeadline is how long a buyer has to pay for an order before it is cancelled
+	PaymentDeadline time.Duration
 )
 
 func RegisterRoute(r *fiber.App, jwtProvider jwt.JWTProvider) {
@@ -26,16 +56,45 @@ func RegisterRoute(r *fiber.App, jwtProvider jwt.JWTProvider) {
 
 	authMiddleware := jwtProvider.Middleware()
 	authPublicMiddleware := jwtProvider.MiddlewareWithPublic()
+	idempotencyMiddleware := idempotency.Middleware()
 
-	productGroup.Post("", authMiddleware, CreateProduct)
+	productGroup.Post("", authMiddleware, idempotencyMiddleware, CreateProduct)
+	productGroup.Post("/bulk", authMiddleware, BulkUpdateProducts)
+	productGroup.Get("/trash", authMiddleware, ListTrashedProducts)
+	productGroup.Get("/favourites", authMiddleware, ListFavourites)
+	productGroup.Post("/:product_id/restore", authMiddleware, RestoreProduct)
 	productGroup.Patch("/:product_id", authMiddleware, UpdateProduct)
 	productGroup.Delete("/:product_id", authMiddleware, DeleteProduct)
 	productGroup.Post("/:product_id/stock", authMiddleware, UpdateProductStock)
-	productGroup.Post("/:product_id/buy", authMiddleware, BuyProduct)
+	productGroup.Put("/:product_id/sale", authMiddleware, SetProductSale)
+	productGroup.Delete("/:product_id/sale", authMiddleware, RemoveProductSale)
+	productGroup.Get("/:product_id/shipping-quote", authMiddleware, QuoteShipping)
+	productGroup.Post("/:product_id/buy", authMiddleware, idempotencyMiddleware, BuyProduct)
+	productGroup.Post("/:product_id/favourite", authMiddleware, AddFavourite)
+	productGroup.Delete("/:product_id/favourite", authMiddleware, RemoveFavourite)
+	productGroup.Post("/:product_id/subscription", authMiddleware, SubscribeProduct)
+	productGroup.Delete("/:product_id/subscription", authMiddleware, UnsubscribeProduct)
 
 	// endpoints that can be public
 	productGroup.Get("", authPublicMiddleware, ListProducts)
-	productGroup.Get("/:product_id", GetProduct)
+	productGroup.Get("/trending", authPublicMiddleware, ListTrendingProducts)
+	productGroup.Get("/:product_id", authPublicMiddleware, GetProduct)
+
+	orderGroup := r.Group("/v1/order")
+	orderGroup.Get("", authMiddleware, ListOrders)
+	orderGroup.Get("/:order_id", authMiddleware, GetOrder)
+	orderGroup.Post("/:order_id/ship", authMiddleware, ShipOrder)
+	orderGroup.Post("/:order_id/complete", authMiddleware, CompleteOrder)
+	orderGroup.Get("/:order_id/invoice", authMiddleware, GetInvoice)
+	orderGroup.Post("/:order_id/dispute", authMiddleware, OpenDispute)
+	orderGroup.Get("/:order_id/dispute", authMiddleware, GetDispute)
+	orderGroup.Post("/:order_id/dispute/messages", authMiddleware, SendDisputeMessage)
+	orderGroup.Post("/:order_id/dispute/proposal", authMiddleware, ProposeRefund)
+	orderGroup.Post("/:order_id/dispute/accept", authMiddleware, AcceptRefund)
+
+	adminGroup := r.Group("/v1/admin", authMiddleware, admin.Middleware())
+	adminGroup.Get("/disputes", ListDisputes)
+	adminGroup.Post("/disputes/:dispute_id/resolve", ResolveDispute)
 }
 
 func CreateProduct(c *fiber.Ctx) error {
@@ -64,6 +123,13 @@ func CreateProduct(c *fiber.Ctx) error {
 		})
 	}
 
+	if err := validateSchedule(payload.PublishAt, payload.UnpublishAt); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "failed_request_body_validation",
+		})
+	}
+
 	ctx := c.Context()
 	userID := claims.UserID
 	product, err := saveProductAndTags(ctx, userID, payload)
@@ -76,17 +142,7 @@ func CreateProduct(c *fiber.Ctx) error {
 
 	return c.Status(fiber.StatusOK).JSON(model.DataResponse{
 		Message: "Product created successfully",
-		Data: ProductResponse{
-			ProductID:     product.ID,
-			Name:          product.Name,
-			Price:         product.Price,
-			ImageURL:      product.ImageURL,
-			Stock:         product.Stock,
-			Condition:     product.Condition,
-			Tags:          payload.Tags,
-			IsPurchasable: product.IsPurchasable,
-			PurchaseCount: 0,
-		},
+		Data:    productEntityToResponse(product, payload.Tags, 0),
 	})
 }
 
@@ -97,16 +153,31 @@ func saveProductAndTags(ctx context.Context, userID string, payload CreateProduc
 	}
 	defer tx.Rollback()
 
+	// keep listings live immediately unless a status is explicitly requested
+	status := payload.Status
+	if status == "" {
+		status = ProductStatusActive
+	}
+
 	productID := uuid.NewString()
 	product := Product{
 		ID:            productID,
 		UserID:        userID,
 		Name:          payload.Name,
-		Price:         payload.Price,
+		Price:         payload.Price.Round().Amount,
+		Currency:      payload.Price.Currency,
 		ImageURL:      payload.ImageURL,
 		Stock:         payload.Stock,
+		WeightGrams:   payload.WeightGrams,
+		Category:      normalizeCategory(payload.Category),
 		Condition:     payload.Condition,
 		IsPurchasable: *payload.IsPurchasable,
+		Status:        status,
+		PublishAt:     payload.PublishAt,
+		UnpublishAt:   payload.UnpublishAt,
+	}
+	if product.WeightGrams == 0 {
+		product.WeightGrams = DefaultWeightGrams
 	}
 	err = ProductRepoImpl.CreateProduct(ctx, tx, product)
 	if err != nil {
@@ -187,6 +258,21 @@ func UpdateProduct(c *fiber.Ctx) error {
 		})
 	}
 
+	// omitted publishing fields keep their current value, so the schedule is checked as it will be saved
+	publishAt, unpublishAt := product.PublishAt, product.UnpublishAt
+	if payload.PublishAt != nil {
+		publishAt = payload.PublishAt
+	}
+	if payload.UnpublishAt != nil {
+		unpublishAt = payload.UnpublishAt
+	}
+	if err := validateSchedule(publishAt, unpublishAt); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "failed_request_body_validation",
+		})
+	}
+
 	product, err = updateProductAndTags(ctx, product, payload)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
@@ -197,17 +283,7 @@ func UpdateProduct(c *fiber.Ctx) error {
 
 	return c.Status(fiber.StatusOK).JSON(model.DataResponse{
 		Message: "Product updated successfully",
-		Data: ProductResponse{
-			ProductID:     product.ID,
-			Name:          product.Name,
-			Price:         product.Price,
-			ImageURL:      product.ImageURL,
-			Stock:         product.Stock,
-			Condition:     product.Condition,
-			Tags:          payload.Tags,
-			IsPurchasable: product.IsPurchasable,
-			PurchaseCount: 0,
-		},
+		Data:    productEntityToResponse(product, payload.Tags, 0),
 	})
 }
 
@@ -218,16 +294,50 @@ func updateProductAndTags(ctx context.Context, product Product, payload UpdatePr
 	}
 	defer tx.Rollback()
 
+	previousProduct := product
+
 	product.Name = payload.Name
-	product.Price = payload.Price
+	product.Price = payload.Price.Round().Amount
+	product.Currency = payload.Price.Currency
 	product.ImageURL = payload.ImageURL
 	product.Condition = payload.Condition
+	if payload.WeightGrams != 0 {
+		product.WeightGrams = payload.WeightGrams
+	}
+	product.Category = normalizeCategory(payload.Category)
 	product.IsPurchasable = *payload.IsPurchasable
+	// the publishing fields are only changed when given, like the status
+	if payload.PublishAt != nil {
+		product.PublishAt = payload.PublishAt
+	}
+	if payload.UnpublishAt != nil {
+		product.UnpublishAt = payload.UnpublishAt
+	}
+	if payload.Status != "" {
+		product.Status = payload.Status
+	}
 	err = ProductRepoImpl.UpdateProduct(ctx, tx, product)
 	if err != nil {
 		return Product{}, err
 	}
 
+	// the sale price is in the old currency's minor units, so it can't carry over to the new currency
+	if product.Currency != previousProduct.Currency && product.SalePrice != nil {
+		product.SalePrice = nil
+		product.SaleStartsAt = nil
+		product.SaleEndsAt = nil
+
+		err = ProductRepoImpl.UpdateProductSale(ctx, tx, product)
+		if err != nil {
+			return Product{}, err
+		}
+	}
+
+	err = enqueueProductChangeEvents(ctx, tx, previousProduct, product)
+	if err != nil {
+		return Product{}, err
+	}
+
 	// product tag updates: get existing tags for the product
 	productToTagMap, err := ProductRepoImpl.BulkGetProductTags(ctx, []string{product.ID})
 	if err != nil {
@@ -322,7 +432,7 @@ func DeleteProduct(c *fiber.Ctx) error {
 		})
 	}
 
-	err = deleteProductAndTags(ctx, productID)
+	err = deleteProduct(ctx, product)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
 			Message: "something wrong with the server. Please contact admin",
@@ -335,30 +445,24 @@ func DeleteProduct(c *fiber.Ctx) error {
 	})
 }
 
-func deleteProductAndTags(ctx context.Context, productID string) error {
+func deleteProduct(ctx context.Context, product Product) error {
 	tx, err := TrxProvider.NewTransaction(ctx)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	err = ProductRepoImpl.DeleteProduct(ctx, tx, productID)
+	err = ProductRepoImpl.DeleteProduct(ctx, tx, product.ID)
 	if err != nil {
 		return err
 	}
 
-	// delete all product tags associated with this product
-	err = ProductRepoImpl.DeleteProductTagsByProductID(ctx, tx, productID)
+	err = emitProductDeleted(ctx, tx, product)
 	if err != nil {
 		return err
 	}
 
-	err = tx.Commit()
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return tx.Commit()
 }
 
 func ListProducts(c *fiber.Ctx) error {
@@ -370,6 +474,13 @@ func ListProducts(c *fiber.Ctx) error {
 		})
 	}
 
+	if req.Currency != "" && !money.Currency(req.Currency).IsSupported() {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: "unsupported currency",
+			Code:    "invalid_request_body",
+		})
+	}
+
 	if req.UserOnly {
 		claims, err := jwt.GetLoggedInUser(c)
 		if err != nil {
@@ -382,6 +493,11 @@ func ListProducts(c *fiber.Ctx) error {
 		req.UserID = claims.UserID
 	}
 
+	// logged in users can also see their own unpublished products
+	if claims, err := jwt.GetLoggedInUser(c); err == nil {
+		req.ViewerID = claims.UserID
+	}
+
 	ctx := c.Context()
 	products, count, err := ProductRepoImpl.ListProducts(ctx, req)
 	if err != nil {
@@ -428,16 +544,14 @@ func ListProducts(c *fiber.Ctx) error {
 			tags = append(tags, tag.Tag)
 		}
 
-		responses = append(responses, ProductResponse{
-			ProductID:     product.ID,
-			Name:          product.Name,
-			Price:         product.Price,
-			ImageURL:      product.ImageURL,
-			Stock:         product.Stock,
-			Condition:     product.Condition,
-			Tags:          tags,
-			IsPurchasable: product.IsPurchasable,
-			PurchaseCount: purchaseCountMap[product.ID],
+		responses = append(responses, productEntityToResponse(product, tags, purchaseCountMap[product.ID]))
+	}
+
+	err = populateFavourites(ctx, req.ViewerID, products, responses)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
 		})
 	}
 
@@ -455,9 +569,15 @@ func ListProducts(c *fiber.Ctx) error {
 func GetProduct(c *fiber.Ctx) error {
 	productID := c.Params("product_id")
 
+	// logged in users can also see their own unpublished products
+	viewerID := ""
+	if claims, err := jwt.GetLoggedInUser(c); err == nil {
+		viewerID = claims.UserID
+	}
+
 	ctx := c.Context()
 
-	product, err := ProductRepoImpl.GetProductByID(ctx, productID)
+	product, err := ProductRepoImpl.GetVisibleProductByID(ctx, productID, viewerID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return c.Status(fiber.StatusNotFound).JSON(model.ErrorResponse{
@@ -505,11 +625,18 @@ func GetProduct(c *fiber.Ctx) error {
 	}
 	bankAccountResponses := []BankAccountResponse{}
 	for _, bankAccount := range bankAccounts {
+		// buyers can't pay into unverified accounts, so they aren't offered
+		if !bankAccount.IsVerified() {
+			continue
+		}
+
 		bankAccountResponses = append(bankAccountResponses, BankAccountResponse{
 			BankAccountID:     bankAccount.ID,
+			BankCode:          bankAccount.BankCode,
 			BankName:          bankAccount.BankName,
 			BankAccountName:   bankAccount.BankAccountName,
 			BankAccountNumber: bankAccount.BankAccountNumber,
+			IsDefault:         bankAccount.IsDefault,
 		})
 	}
 
@@ -530,20 +657,29 @@ func GetProduct(c *fiber.Ctx) error {
 		})
 	}
 
+	productResponses := []ProductResponse{productEntityToResponse(product, strTags, purchaseCountMap[product.ID])}
+	err = populateFavourites(ctx, viewerID, []Product{product}, productResponses)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	// count the view for the seller's analytics, the owner viewing their own product doesn't count
+	if viewerID != product.UserID {
+		ViewRecorderImpl.Record(analytics.ProductView{
+			ProductID: product.ID,
+			SellerID:  product.UserID,
+			ViewerKey: getViewerKey(c, viewerID),
+			ViewedAt:  time.Now(),
+		})
+	}
+
 	return c.Status(fiber.StatusOK).JSON(model.DataResponse{
 		Message: "ok",
 		Data: ProductDetailResponse{
-			Product: ProductResponse{
-				ProductID:     product.ID,
-				Name:          product.Name,
-				Price:         product.Price,
-				ImageURL:      product.ImageURL,
-				Stock:         product.Stock,
-				Condition:     product.Condition,
-				Tags:          strTags,
-				IsPurchasable: product.IsPurchasable,
-				PurchaseCount: purchaseCountMap[product.ID],
-			},
+			Product: productResponses[0],
 			Seller: ProductDetailSellerResponse{
 				Name:             productUser.Name,
 				ProductSoldTotal: userProductPurchaseCount,
@@ -605,8 +741,7 @@ func UpdateProductStock(c *fiber.Ctx) error {
 		})
 	}
 
-	// update product stock
-	err = ProductRepoImpl.UpdateProductStock(ctx, nil, productID, payload.Stock)
+	product, err = updateProductStock(ctx, product, payload.Stock)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
 			Message: "something wrong with the server. Please contact admin",
@@ -616,15 +751,101 @@ func UpdateProductStock(c *fiber.Ctx) error {
 
 	return c.Status(fiber.StatusOK).JSON(model.DataResponse{
 		Message: "ok",
-		Data: ProductResponse{
-			ProductID:     product.ID,
-			Name:          product.Name,
-			Price:         product.Price,
-			ImageURL:      product.ImageURL,
-			Stock:         product.Stock,
-			Condition:     product.Condition,
-			IsPurchasable: product.IsPurchasable,
-			PurchaseCount: 0,
-		},
+		Data:    productEntityToResponse(product, nil, 0),
 	})
 }
+
+func updateProductStock(ctx context.Context, product Product, stock int) (Product, error) {
+	tx, err := TrxProvider.NewTransaction(ctx)
+	if err != nil {
+		return Product{}, err
+	}
+	defer tx.Rollback()
+
+	previousProduct := product
+	product.Stock = stock
+
+	err = ProductRepoImpl.UpdateProductStock(ctx, tx, product.ID, stock)
+	if err != nil {
+		return Product{}, err
+	}
+
+	err = enqueueProductChangeEvents(ctx, tx, previousProduct, product)
+	if err != nil {
+		return Product{}, err
+	}
+
+	err = emitStockChanged(ctx, tx, product)
+	if err != nil {
+		return Product{}, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return Product{}, err
+	}
+
+	return product, nil
+}
+
+// getViewerKey identifies a viewer for view deduplication: logged in users by their ID, and
+// anonymous users by a hash of their address and user agent so raw IPs aren't stored
+func getViewerKey(c *fiber.Ctx, viewerID string) string {
+	if viewerID != "" {
+		return "user:" + viewerID
+	}
+
+	hash := sha256.Sum256([]byte(c.IP() + "|" + c.Get(fiber.HeaderUserAgent)))
+	return "anon:" + hex.EncodeToString(hash[:16])
+}
+
+// normalizeCategory stores categories the way commission rules match them, and a missing one as NULL
+func normalizeCategory(category string) *string {
+	category = commission.NormalizeCategory(category)
+	if category == "" {
+		return nil
+	}
+
+	return &category
+}
+
+func validateSchedule(publishAt, unpublishAt *time.Time) error {
+	if publishAt != nil && unpublishAt != nil && !unpublishAt.After(*publishAt) {
+		return errors.New("unpublishAt must be after publishAt")
+	}
+
+	return nil
+}
+
+func productEntityToResponse(product Product, tags []string, purchaseCount int) ProductResponse {
+	now := time.Now()
+
+	var sale *ProductSaleResponse
+	if product.SalePrice != nil && product.SaleStartsAt != nil && product.SaleEndsAt != nil {
+		sale = &ProductSaleResponse{
+			SalePrice: money.New(*product.SalePrice, product.Currency),
+			StartsAt:  *product.SaleStartsAt,
+			EndsAt:    *product.SaleEndsAt,
+			IsActive:  product.IsOnSale(now),
+		}
+	}
+
+	return ProductResponse{
+		ProductID:      product.ID,
+		Name:           product.Name,
+		Price:          product.OriginalPrice(),
+		EffectivePrice: product.EffectivePrice(now),
+		Sale:           sale,
+		ImageURL:       product.ImageURL,
+		Stock:          product.Stock,
+		WeightGrams:    product.WeightGrams,
+		Category:       product.Category,
+		Condition:      product.Condition,
+		Tags:           tags,
+		IsPurchasable:  product.IsPurchasable,
+		PurchaseCount:  purchaseCount,
+		Status:         product.Status,
+		PublishAt:      product.PublishAt,
+		UnpublishAt:    product.UnpublishAt,
+	}
+}
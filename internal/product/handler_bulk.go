@@ -0,0 +1,265 @@
+package product
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/model"
+	"github.com/ahmadnaufal/openidea-shopifyx/pkg/jwt"
+	"github.com/ahmadnaufal/openidea-shopifyx/pkg/validation"
+	"github.com/gofiber/fiber/v2"
+	"github.com/pkg/errors"
+)
+
+const (
+	BulkOperationSetPurchasable   = "set_purchasable"
+	BulkOperationSetUnpurchasable = "set_unpurchasable"
+	BulkOperationAdjustPrice      = "adjust_price"
+	BulkOperationAddTag           = "add_tag"
+	BulkOperationRemoveTag        = "remove_tag"
+	BulkOperationDelete           = "delete"
+)
+
+var errBulkPriceNotPositive = errors.New("adjusted price must be greater than zero")
+
+func BulkUpdateProducts(c *fiber.Ctx) error {
+	var payload BulkProductRequest
+
+	claims, err := jwt.GetLoggedInUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "forbidden",
+		})
+	}
+
+	if err := c.BodyParser(&payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "invalid_request_body",
+		})
+	}
+
+	// validation for request body
+	if err := validation.Validate(payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "failed_request_body_validation",
+		})
+	}
+
+	ctx := c.Context()
+
+	// deduplicate the product IDs so each one is only processed once
+	productIDs := []string{}
+	seenIDs := map[string]bool{}
+	for _, productID := range payload.ProductIDs {
+		if seenIDs[productID] {
+			continue
+		}
+		seenIDs[productID] = true
+		productIDs = append(productIDs, productID)
+	}
+
+	productMap, err := ProductRepoImpl.GetProductsByIDs(ctx, productIDs)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	// check existence & ownership of every product before touching any of them
+	results := make([]BulkProductResultResponse, len(productIDs))
+	hasFailure := false
+	for i, productID := range productIDs {
+		results[i] = BulkProductResultResponse{ProductID: productID, Success: true}
+
+		product, ok := productMap[productID]
+		if !ok {
+			results[i] = BulkProductResultResponse{
+				ProductID: productID,
+				Code:      "entity_not_found",
+				Message:   "product not found",
+			}
+			hasFailure = true
+			continue
+		}
+
+		if product.UserID != claims.UserID {
+			results[i] = BulkProductResultResponse{
+				ProductID: productID,
+				Code:      "update_product_forbidden",
+				Message:   "cannot update a product that is owned by another user",
+			}
+			hasFailure = true
+		}
+	}
+
+	if hasFailure {
+		// the operation is atomic: mark the valid ones as skipped since nothing was applied
+		for i := range results {
+			if results[i].Success {
+				results[i] = BulkProductResultResponse{
+					ProductID: results[i].ProductID,
+					Code:      "skipped",
+					Message:   "not applied because other products in the request failed",
+				}
+			}
+		}
+
+		return c.Status(fiber.StatusBadRequest).JSON(model.DataResponse{
+			Message: "bulk operation was not applied",
+			Data:    results,
+		})
+	}
+
+	err = applyBulkOperation(ctx, productIDs, payload)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return c.Status(fiber.StatusNotFound).JSON(model.ErrorResponse{
+				Message: "product not found",
+				Code:    "entity_not_found",
+			})
+		}
+		if err == errBulkPriceNotPositive {
+			return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+				Message: err.Error(),
+				Code:    "invalid_price",
+			})
+		}
+
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(model.DataResponse{
+		Message: "Bulk operation applied successfully",
+		Data:    results,
+	})
+}
+
+// applyBulkOperation applies the operation to all the products in one transaction. The products are
+// read again under lock, so concurrent purchases or edits aren't reverted. It returns sql.ErrNoRows
+// if one of them was deleted in the meantime.
+func applyBulkOperation(ctx context.Context, productIDs []string, payload BulkProductRequest) error {
+	tx, err := TrxProvider.NewTransaction(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	productMap, err := ProductRepoImpl.LockProductsByIDs(ctx, tx, productIDs)
+	if err != nil {
+		return err
+	}
+
+	products := make([]Product, len(productIDs))
+	for i, productID := range productIDs {
+		product, ok := productMap[productID]
+		if !ok {
+			return sql.ErrNoRows
+		}
+		products[i] = product
+	}
+
+	switch payload.Operation {
+	case BulkOperationSetPurchasable, BulkOperationSetUnpurchasable, BulkOperationAdjustPrice:
+		for _, product := range products {
+			previousProduct := product
+
+			switch payload.Operation {
+			case BulkOperationSetPurchasable:
+				product.IsPurchasable = true
+			case BulkOperationSetUnpurchasable:
+				product.IsPurchasable = false
+			case BulkOperationAdjustPrice:
+				product.Price = product.OriginalPrice().Percentage(int64(100 + payload.PricePercentage)).Amount
+				if product.Price <= 0 {
+					return errBulkPriceNotPositive
+				}
+			}
+
+			err = ProductRepoImpl.UpdateProduct(ctx, tx, product)
+			if err != nil {
+				return err
+			}
+
+			// a sale is only a sale below the price, a cut that reaches the sale price ends it
+			if product.SalePrice != nil && *product.SalePrice >= product.Price {
+				product.SalePrice = nil
+				product.SaleStartsAt = nil
+				product.SaleEndsAt = nil
+
+				err = ProductRepoImpl.UpdateProductSale(ctx, tx, product)
+				if err != nil {
+					return err
+				}
+			}
+
+			err = enqueueProductChangeEvents(ctx, tx, previousProduct, product)
+			if err != nil {
+				return err
+			}
+		}
+
+	case BulkOperationAddTag, BulkOperationRemoveTag:
+		productToTagMap, err := ProductRepoImpl.BulkGetProductTagsInTx(ctx, tx, productIDs)
+		if err != nil {
+			return err
+		}
+
+		newProductTags := []ProductTag{}
+		deletedProductTags := []ProductTag{}
+		for _, productID := range productIDs {
+			var existingTag *ProductTag
+			for _, tag := range productToTagMap[productID] {
+				if tag.Tag == payload.Tag {
+					existingTag = &tag
+					break
+				}
+			}
+
+			if payload.Operation == BulkOperationAddTag && existingTag == nil {
+				newProductTags = append(newProductTags, ProductTag{
+					ProductID: productID,
+					Tag:       payload.Tag,
+				})
+			}
+			if payload.Operation == BulkOperationRemoveTag && existingTag != nil {
+				deletedProductTags = append(deletedProductTags, *existingTag)
+			}
+		}
+
+		if len(newProductTags) > 0 {
+			err = ProductRepoImpl.CreateProductTags(ctx, tx, newProductTags)
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(deletedProductTags) > 0 {
+			err = ProductRepoImpl.DeleteProductTags(ctx, tx, deletedProductTags)
+			if err != nil {
+				return err
+			}
+		}
+
+	case BulkOperationDelete:
+		for _, product := range products {
+			err = ProductRepoImpl.DeleteProduct(ctx, tx, product.ID)
+			if err != nil {
+				return err
+			}
+
+			err = emitProductDeleted(ctx, tx, product)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,750 @@
+package product
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/admin"
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/ledger"
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/model"
+	"github.com/ahmadnaufal/openidea-shopifyx/pkg/jwt"
+	"github.com/ahmadnaufal/openidea-shopifyx/pkg/money"
+	"github.com/ahmadnaufal/openidea-shopifyx/pkg/validation"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+const (
+	disputeRoleBuyer  = "buyer"
+	disputeRoleSeller = "seller"
+	disputeRoleAdmin  = "admin"
+)
+
+var (
+	errDisputeExists        = errors.New("order has already been disputed")
+	errDisputeNotOpen       = errors.New("dispute has already been resolved")
+	errDisputeChanged       = errors.New("refund proposal has changed, please review it again")
+	errOrderNotDisputable   = errors.New("only paid, shipped or completed orders can be disputed")
+	errNoRefundProposal     = errors.New("there is no refund proposal to accept")
+	errOwnRefundProposal    = errors.New("a refund proposal must be accepted by the other party")
+	errRefundExceedsTotal   = errors.New("refund amount cannot exceed the order total")
+	errDisputeForbiddenRole = errors.New("only the buyer can open a dispute")
+)
+
+// getDisputeRole returns the user's part in the dispute, or an empty string if they have none
+func getDisputeRole(dispute Dispute, userID string) string {
+	switch userID {
+	case dispute.BuyerID:
+		return disputeRoleBuyer
+	case dispute.SellerID:
+		return disputeRoleSeller
+	}
+
+	if admin.IsAdmin(userID) {
+		return disputeRoleAdmin
+	}
+
+	return ""
+}
+
+// getParticipatingOrder returns the order if the user is its buyer or seller, and sql.ErrNoRows otherwise
+// so other users can't tell whether the order exists
+func getParticipatingOrder(ctx context.Context, orderID, userID string) (Order, error) {
+	order, err := ProductRepoImpl.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return Order{}, err
+	}
+
+	if order.UserID != userID && order.SellerID != userID && !admin.IsAdmin(userID) {
+		return Order{}, sql.ErrNoRows
+	}
+
+	return order, nil
+}
+
+func OpenDispute(c *fiber.Ctx) error {
+	var payload OpenDisputeRequest
+
+	claims, err := jwt.GetLoggedInUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "forbidden",
+		})
+	}
+
+	if err := c.BodyParser(&payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "invalid_request_body",
+		})
+	}
+
+	// validation for request body
+	if err := validation.Validate(payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "failed_request_body_validation",
+		})
+	}
+
+	ctx := c.Context()
+	order, err := getParticipatingOrder(ctx, c.Params("order_id"), claims.UserID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return c.Status(fiber.StatusNotFound).JSON(model.ErrorResponse{
+				Message: "order not found",
+				Code:    "entity_not_found",
+			})
+		}
+
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	if order.UserID != claims.UserID {
+		return c.Status(fiber.StatusForbidden).JSON(model.ErrorResponse{
+			Message: errDisputeForbiddenRole.Error(),
+			Code:    "dispute_forbidden",
+		})
+	}
+
+	if order.Status != OrderStatusPaid && order.Status != OrderStatusShipped && order.Status != OrderStatusCompleted {
+		return c.Status(fiber.StatusConflict).JSON(model.ErrorResponse{
+			Message: errOrderNotDisputable.Error(),
+			Code:    "order_not_disputable",
+		})
+	}
+
+	now := time.Now()
+	dispute := Dispute{
+		ID:        uuid.NewString(),
+		OrderID:   order.ID,
+		BuyerID:   order.UserID,
+		SellerID:  order.SellerID,
+		Reason:    payload.Reason,
+		Status:    DisputeStatusOpen,
+		CreatedAt: now,
+	}
+	message := DisputeMessage{
+		ID:                uuid.NewString(),
+		DisputeID:         dispute.ID,
+		SenderID:          claims.UserID,
+		Message:           payload.Message,
+		EvidenceImageURLs: payload.EvidenceImageURLs,
+		CreatedAt:         now,
+	}
+
+	err = createDispute(ctx, dispute, message)
+	if err != nil {
+		if errors.Is(err, errDisputeExists) {
+			return c.Status(fiber.StatusConflict).JSON(model.ErrorResponse{
+				Message: err.Error(),
+				Code:    "dispute_exists",
+			})
+		}
+
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(model.DataResponse{
+		Message: "Dispute opened successfully",
+		Data:    disputeEntityToResponse(dispute, order, []DisputeMessage{message}),
+	})
+}
+
+func createDispute(ctx context.Context, dispute Dispute, message DisputeMessage) error {
+	tx, err := TrxProvider.NewTransaction(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = ProductRepoImpl.CreateDispute(ctx, tx, dispute)
+	if err != nil {
+		return err
+	}
+
+	err = ProductRepoImpl.CreateDisputeMessage(ctx, tx, message)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func GetDispute(c *fiber.Ctx) error {
+	claims, err := jwt.GetLoggedInUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "forbidden",
+		})
+	}
+
+	ctx := c.Context()
+	order, dispute, err := getOrderDispute(ctx, c.Params("order_id"), claims.UserID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return c.Status(fiber.StatusNotFound).JSON(model.ErrorResponse{
+				Message: "dispute not found",
+				Code:    "entity_not_found",
+			})
+		}
+
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	messages, err := ProductRepoImpl.ListDisputeMessages(ctx, dispute.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(model.DataResponse{
+		Message: "ok",
+		Data:    disputeEntityToResponse(dispute, order, messages),
+	})
+}
+
+// getOrderDispute returns the order's dispute if the user takes part in it, and sql.ErrNoRows otherwise
+func getOrderDispute(ctx context.Context, orderID, userID string) (Order, Dispute, error) {
+	order, err := getParticipatingOrder(ctx, orderID, userID)
+	if err != nil {
+		return Order{}, Dispute{}, err
+	}
+
+	dispute, err := ProductRepoImpl.GetDisputeByOrderID(ctx, order.ID)
+	if err != nil {
+		return Order{}, Dispute{}, err
+	}
+
+	return order, dispute, nil
+}
+
+func SendDisputeMessage(c *fiber.Ctx) error {
+	var payload DisputeMessageRequest
+
+	claims, err := jwt.GetLoggedInUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "forbidden",
+		})
+	}
+
+	if err := c.BodyParser(&payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "invalid_request_body",
+		})
+	}
+
+	// validation for request body
+	if err := validation.Validate(payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "failed_request_body_validation",
+		})
+	}
+
+	ctx := c.Context()
+	_, dispute, err := getOrderDispute(ctx, c.Params("order_id"), claims.UserID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return c.Status(fiber.StatusNotFound).JSON(model.ErrorResponse{
+				Message: "dispute not found",
+				Code:    "entity_not_found",
+			})
+		}
+
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	if dispute.Status != DisputeStatusOpen {
+		return c.Status(fiber.StatusConflict).JSON(model.ErrorResponse{
+			Message: errDisputeNotOpen.Error(),
+			Code:    "dispute_not_open",
+		})
+	}
+
+	message := DisputeMessage{
+		ID:                uuid.NewString(),
+		DisputeID:         dispute.ID,
+		SenderID:          claims.UserID,
+		Message:           payload.Message,
+		EvidenceImageURLs: payload.EvidenceImageURLs,
+		CreatedAt:         time.Now(),
+	}
+
+	err = ProductRepoImpl.CreateDisputeMessage(ctx, nil, message)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(model.DataResponse{
+		Message: "Message sent successfully",
+		Data:    disputeMessageEntityToResponse(dispute, message),
+	})
+}
+
+func ProposeRefund(c *fiber.Ctx) error {
+	var payload RefundProposalRequest
+
+	claims, err := jwt.GetLoggedInUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "forbidden",
+		})
+	}
+
+	if err := c.BodyParser(&payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "invalid_request_body",
+		})
+	}
+
+	// validation for request body
+	if err := validation.Validate(payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "failed_request_body_validation",
+		})
+	}
+
+	ctx := c.Context()
+	order, dispute, err := getOrderDispute(ctx, c.Params("order_id"), claims.UserID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return c.Status(fiber.StatusNotFound).JSON(model.ErrorResponse{
+				Message: "dispute not found",
+				Code:    "entity_not_found",
+			})
+		}
+
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	// admins don't negotiate, they resolve the dispute through the admin endpoint
+	role := getDisputeRole(dispute, claims.UserID)
+	if role != disputeRoleBuyer && role != disputeRoleSeller {
+		return c.Status(fiber.StatusForbidden).JSON(model.ErrorResponse{
+			Message: "only the buyer or the seller can propose a refund",
+			Code:    "dispute_forbidden",
+		})
+	}
+
+	if payload.Amount > order.Total-order.RefundedAmount {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: errRefundExceedsTotal.Error(),
+			Code:    "failed_request_body_validation",
+		})
+	}
+
+	err = ProductRepoImpl.ProposeRefund(ctx, nil, dispute.ID, payload.Amount, payload.RestoreStock, claims.UserID)
+	if err != nil {
+		if errors.Is(err, errDisputeNotOpen) {
+			return c.Status(fiber.StatusConflict).JSON(model.ErrorResponse{
+				Message: err.Error(),
+				Code:    "dispute_not_open",
+			})
+		}
+
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	dispute.ProposedRefundAmount = &payload.Amount
+	dispute.ProposedRestoreStock = payload.RestoreStock
+	dispute.ProposedBy = &claims.UserID
+
+	return c.Status(fiber.StatusOK).JSON(model.DataResponse{
+		Message: "Refund proposed successfully",
+		Data:    disputeEntityToResponse(dispute, order, nil),
+	})
+}
+
+func AcceptRefund(c *fiber.Ctx) error {
+	claims, err := jwt.GetLoggedInUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "forbidden",
+		})
+	}
+
+	ctx := c.Context()
+	order, dispute, err := getOrderDispute(ctx, c.Params("order_id"), claims.UserID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return c.Status(fiber.StatusNotFound).JSON(model.ErrorResponse{
+				Message: "dispute not found",
+				Code:    "entity_not_found",
+			})
+		}
+
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	role := getDisputeRole(dispute, claims.UserID)
+	if role != disputeRoleBuyer && role != disputeRoleSeller {
+		return c.Status(fiber.StatusForbidden).JSON(model.ErrorResponse{
+			Message: "only the buyer or the seller can accept a refund",
+			Code:    "dispute_forbidden",
+		})
+	}
+
+	if dispute.Status != DisputeStatusOpen {
+		return c.Status(fiber.StatusConflict).JSON(model.ErrorResponse{
+			Message: errDisputeNotOpen.Error(),
+			Code:    "dispute_not_open",
+		})
+	}
+
+	if dispute.ProposedBy == nil {
+		return c.Status(fiber.StatusConflict).JSON(model.ErrorResponse{
+			Message: errNoRefundProposal.Error(),
+			Code:    "no_refund_proposal",
+		})
+	}
+
+	if *dispute.ProposedBy == claims.UserID {
+		return c.Status(fiber.StatusForbidden).JSON(model.ErrorResponse{
+			Message: errOwnRefundProposal.Error(),
+			Code:    "dispute_forbidden",
+		})
+	}
+
+	now := time.Now()
+	order, err = resolveDisputeWithRefund(ctx, order, dispute, *dispute.ProposedRefundAmount, dispute.ProposedRestoreStock, func(tx *sql.Tx) error {
+		return ProductRepoImpl.AcceptRefundProposal(ctx, tx, dispute, claims.UserID, now)
+	})
+	if err != nil {
+		if errors.Is(err, errDisputeChanged) {
+			return c.Status(fiber.StatusConflict).JSON(model.ErrorResponse{
+				Message: err.Error(),
+				Code:    "dispute_changed",
+			})
+		}
+
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	resolution := DisputeResolutionRefunded
+	dispute.Status = DisputeStatusResolved
+	dispute.Resolution = &resolution
+	dispute.ResolvedBy = &claims.UserID
+	dispute.ResolvedAt = &now
+
+	return c.Status(fiber.StatusOK).JSON(model.DataResponse{
+		Message: "Refund accepted successfully",
+		Data:    disputeEntityToResponse(dispute, order, nil),
+	})
+}
+
+// resolveDisputeWithRefund closes the dispute with resolve, and refunds the order in the same transaction
+func resolveDisputeWithRefund(ctx context.Context, order Order, dispute Dispute, amount int64, restoreStock bool, resolve func(tx *sql.Tx) error) (Order, error) {
+	tx, err := TrxProvider.NewTransaction(ctx)
+	if err != nil {
+		return Order{}, err
+	}
+	defer tx.Rollback()
+
+	err = resolve(tx)
+	if err != nil {
+		return Order{}, err
+	}
+
+	if amount > 0 {
+		order, err = refundOrder(ctx, tx, order, dispute, amount, restoreStock)
+		if err != nil {
+			return Order{}, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return Order{}, err
+	}
+
+	return order, nil
+}
+
+// refundOrder records the refund on the order, and puts its items back in stock if asked to
+func refundOrder(ctx context.Context, tx *sql.Tx, order Order, dispute Dispute, amount int64, restoreStock bool) (Order, error) {
+	refund := Refund{
+		ID:        uuid.NewString(),
+		OrderID:   order.ID,
+		DisputeID: dispute.ID,
+		Amount:    amount,
+		Currency:  order.Currency,
+		CreatedAt: time.Now(),
+	}
+	if restoreStock {
+		refund.RestoredQuantity = order.Quantity
+	}
+
+	err := ProductRepoImpl.CreateRefund(ctx, tx, refund)
+	if err != nil {
+		return Order{}, err
+	}
+
+	refundedOrder, err := ProductRepoImpl.AddOrderRefund(ctx, tx, order.ID, amount)
+	if err != nil {
+		return Order{}, err
+	}
+
+	order.RefundedAmount = refundedOrder.RefundedAmount
+	order.Status = refundedOrder.Status
+	order.CompletedAt = refundedOrder.CompletedAt
+
+	// the seller was already credited with a completed order, so its refund is taken back from them,
+	// less the commission the platform gives back
+	if order.CompletedAt != nil {
+		err = ledger.RecordRefund(ctx, tx, order.ID, order.SellerID, money.New(amount, order.Currency), order.CommissionOn(amount))
+		if err != nil {
+			return Order{}, err
+		}
+	}
+
+	if restoreStock {
+		err = returnOrderStock(ctx, tx, order)
+		if err != nil {
+			return Order{}, err
+		}
+	}
+
+	err = emitOrderRefunded(ctx, tx, order, refund)
+	if err != nil {
+		return Order{}, err
+	}
+
+	return order, nil
+}
+
+func ListDisputes(c *fiber.Ctx) error {
+	var req ListDisputesRequest
+	if err := c.QueryParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "invalid_request_body",
+		})
+	}
+
+	if err := validation.Validate(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "failed_request_body_validation",
+		})
+	}
+
+	ctx := c.Context()
+	disputes, count, err := ProductRepoImpl.ListDisputes(ctx, req)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	responses := []DisputeResponse{}
+	for _, dispute := range disputes {
+		order, err := ProductRepoImpl.GetOrderByID(ctx, dispute.OrderID)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+				Message: "something wrong with the server. Please contact admin",
+				Code:    "internal_server_error",
+			})
+		}
+
+		responses = append(responses, disputeEntityToResponse(dispute, order, nil))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(model.DataResponse{
+		Message: "ok",
+		Data:    responses,
+		Meta: &model.ResponseMeta{
+			Limit:  req.Limit,
+			Offset: req.Offset,
+			Total:  count,
+		},
+	})
+}
+
+// ResolveDispute lets an admin settle a dispute the parties couldn't agree on, with a refund or without one
+func ResolveDispute(c *fiber.Ctx) error {
+	var payload ResolveDisputeRequest
+
+	claims, err := jwt.GetLoggedInUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "forbidden",
+		})
+	}
+
+	if err := c.BodyParser(&payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "invalid_request_body",
+		})
+	}
+
+	// validation for request body
+	if err := validation.Validate(payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "failed_request_body_validation",
+		})
+	}
+
+	ctx := c.Context()
+	dispute, err := ProductRepoImpl.GetDisputeByID(ctx, c.Params("dispute_id"))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return c.Status(fiber.StatusNotFound).JSON(model.ErrorResponse{
+				Message: "dispute not found",
+				Code:    "entity_not_found",
+			})
+		}
+
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	order, err := ProductRepoImpl.GetOrderByID(ctx, dispute.OrderID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	if payload.RefundAmount > order.Total-order.RefundedAmount {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: errRefundExceedsTotal.Error(),
+			Code:    "failed_request_body_validation",
+		})
+	}
+
+	now := time.Now()
+	resolution := DisputeResolutionRejected
+	if payload.RefundAmount > 0 {
+		resolution = DisputeResolutionRefunded
+	}
+
+	// the admin's decision is kept in the dispute's thread so both parties can read it
+	note := DisputeMessage{
+		ID:                uuid.NewString(),
+		DisputeID:         dispute.ID,
+		SenderID:          claims.UserID,
+		Message:           payload.Note,
+		EvidenceImageURLs: []string{},
+		CreatedAt:         now,
+	}
+
+	order, err = resolveDisputeWithRefund(ctx, order, dispute, payload.RefundAmount, payload.RestoreStock, func(tx *sql.Tx) error {
+		err := ProductRepoImpl.ResolveDispute(ctx, tx, dispute.ID, resolution, claims.UserID, now)
+		if err != nil {
+			return err
+		}
+
+		return ProductRepoImpl.CreateDisputeMessage(ctx, tx, note)
+	})
+	if err != nil {
+		if errors.Is(err, errDisputeNotOpen) {
+			return c.Status(fiber.StatusConflict).JSON(model.ErrorResponse{
+				Message: err.Error(),
+				Code:    "dispute_not_open",
+			})
+		}
+
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	dispute.Status = DisputeStatusResolved
+	dispute.Resolution = &resolution
+	dispute.ResolvedBy = &claims.UserID
+	dispute.ResolvedAt = &now
+
+	return c.Status(fiber.StatusOK).JSON(model.DataResponse{
+		Message: "Dispute resolved successfully",
+		Data:    disputeEntityToResponse(dispute, order, nil),
+	})
+}
+
+func disputeEntityToResponse(dispute Dispute, order Order, messages []DisputeMessage) DisputeResponse {
+	response := DisputeResponse{
+		DisputeID:  dispute.ID,
+		OrderID:    dispute.OrderID,
+		Reason:     dispute.Reason,
+		Status:     dispute.Status,
+		Resolution: dispute.Resolution,
+		CreatedAt:  dispute.CreatedAt,
+		ResolvedAt: dispute.ResolvedAt,
+	}
+
+	if dispute.ProposedBy != nil && dispute.ProposedRefundAmount != nil {
+		response.Proposal = &RefundProposalResponse{
+			Amount:       money.New(*dispute.ProposedRefundAmount, order.Currency),
+			RestoreStock: dispute.ProposedRestoreStock,
+			ProposedBy:   getDisputeRole(dispute, *dispute.ProposedBy),
+		}
+	}
+
+	for _, message := range messages {
+		response.Messages = append(response.Messages, disputeMessageEntityToResponse(dispute, message))
+	}
+
+	return response
+}
+
+func disputeMessageEntityToResponse(dispute Dispute, message DisputeMessage) DisputeMessageResponse {
+	evidenceImageURLs := []string(message.EvidenceImageURLs)
+	if evidenceImageURLs == nil {
+		evidenceImageURLs = []string{}
+	}
+
+	return DisputeMessageResponse{
+		SenderRole:        getDisputeRole(dispute, message.SenderID),
+		Message:           message.Message,
+		EvidenceImageURLs: evidenceImageURLs,
+		CreatedAt:         message.CreatedAt,
+	}
+}
@@ -0,0 +1,202 @@
+package product
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/model"
+	"github.com/ahmadnaufal/openidea-shopifyx/pkg/jwt"
+	"github.com/gofiber/fiber/v2"
+)
+
+func AddFavourite(c *fiber.Ctx) error {
+	productID := c.Params("product_id")
+
+	claims, err := jwt.GetLoggedInUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "forbidden",
+		})
+	}
+
+	ctx := c.Context()
+
+	// only products the user can currently see can be favourited
+	_, err = ProductRepoImpl.GetVisibleProductByID(ctx, productID, claims.UserID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return c.Status(fiber.StatusNotFound).JSON(model.ErrorResponse{
+				Message: "product not found",
+				Code:    "entity_not_found",
+			})
+		}
+
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	// favouriting an already favourited product is a no-op
+	err = ProductRepoImpl.AddFavourite(ctx, claims.UserID, productID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(model.DataResponse{
+		Message: "Product added to favourites",
+	})
+}
+
+func RemoveFavourite(c *fiber.Ctx) error {
+	productID := c.Params("product_id")
+
+	claims, err := jwt.GetLoggedInUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "forbidden",
+		})
+	}
+
+	ctx := c.Context()
+
+	err = ProductRepoImpl.RemoveFavourite(ctx, claims.UserID, productID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(model.DataResponse{
+		Message: "Product removed from favourites",
+	})
+}
+
+func ListFavourites(c *fiber.Ctx) error {
+	claims, err := jwt.GetLoggedInUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "forbidden",
+		})
+	}
+
+	var req ListFavouritesRequest
+	if err := c.QueryParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "invalid_request_body",
+		})
+	}
+
+	ctx := c.Context()
+	products, count, err := ProductRepoImpl.ListFavouritedProducts(ctx, claims.UserID, req)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	productIDs := []string{}
+	for _, product := range products {
+		productIDs = append(productIDs, product.ID)
+	}
+
+	productTagsMap := map[string][]ProductTag{}
+	purchaseCountMap := map[string]int{}
+	if len(productIDs) > 0 {
+		productTagsMap, err = ProductRepoImpl.BulkGetProductTags(ctx, productIDs)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+				Message: "something wrong with the server. Please contact admin",
+				Code:    "internal_server_error",
+			})
+		}
+
+		purchaseCountMap, err = ProductRepoImpl.GetPurchaseCountByProductIDs(ctx, productIDs)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+				Message: "something wrong with the server. Please contact admin",
+				Code:    "internal_server_error",
+			})
+		}
+	}
+
+	responses := []ProductResponse{}
+	for _, product := range products {
+		tags := []string{}
+		for _, tag := range productTagsMap[product.ID] {
+			tags = append(tags, tag.Tag)
+		}
+
+		responses = append(responses, productEntityToResponse(product, tags, purchaseCountMap[product.ID]))
+	}
+
+	err = populateFavourites(ctx, claims.UserID, products, responses)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(model.DataResponse{
+		Message: "ok",
+		Data:    responses,
+		Meta: &model.ResponseMeta{
+			Limit:  req.Limit,
+			Offset: req.Offset,
+			Total:  count,
+		},
+	})
+}
+
+// populateFavourites fills in the favourite fields of the responses for a logged in viewer:
+// whether they favourited each product, and the favourite count of the products they own.
+// responses must be in the same order as products. Anonymous viewers get neither.
+func populateFavourites(ctx context.Context, viewerID string, products []Product, responses []ProductResponse) error {
+	if viewerID == "" || len(products) == 0 {
+		return nil
+	}
+
+	productIDs := []string{}
+	ownedProductIDs := []string{}
+	for _, product := range products {
+		productIDs = append(productIDs, product.ID)
+		if product.UserID == viewerID {
+			ownedProductIDs = append(ownedProductIDs, product.ID)
+		}
+	}
+
+	favouritedMap, err := ProductRepoImpl.GetFavouritedProductIDs(ctx, viewerID, productIDs)
+	if err != nil {
+		return err
+	}
+
+	favouriteCountMap := map[string]int{}
+	if len(ownedProductIDs) > 0 {
+		favouriteCountMap, err = ProductRepoImpl.GetFavouriteCountByProductIDs(ctx, ownedProductIDs)
+		if err != nil {
+			return err
+		}
+	}
+
+	for i, product := range products {
+		isFavourited := favouritedMap[product.ID]
+		responses[i].IsFavourited = &isFavourited
+
+		if product.UserID == viewerID {
+			favouriteCount := favouriteCountMap[product.ID]
+			responses[i].FavouriteCount = &favouriteCount
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,168 @@
+package product
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/invoice"
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/model"
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/payment"
+	"github.com/ahmadnaufal/openidea-shopifyx/pkg/jwt"
+	"github.com/ahmadnaufal/openidea-shopifyx/pkg/money"
+	"github.com/gofiber/fiber/v2"
+	"github.com/pkg/errors"
+)
+
+var errOrderNotInvoiceable = errors.New("only paid orders can be invoiced")
+
+// GetInvoice renders the order's invoice as a PDF for its buyer or seller. The invoice is numbered
+// on the first request, and keeps its number on the next ones.
+func GetInvoice(c *fiber.Ctx) error {
+	claims, err := jwt.GetLoggedInUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "forbidden",
+		})
+	}
+
+	ctx := c.Context()
+	order, err := getParticipatingOrder(ctx, c.Params("order_id"), claims.UserID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return c.Status(fiber.StatusNotFound).JSON(model.ErrorResponse{
+				Message: "order not found",
+				Code:    "entity_not_found",
+			})
+		}
+
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	if order.Status == OrderStatusPendingPayment || order.Status == OrderStatusCancelled {
+		return c.Status(fiber.StatusConflict).JSON(model.ErrorResponse{
+			Message: errOrderNotInvoiceable.Error(),
+			Code:    "order_not_invoiceable",
+		})
+	}
+
+	orderInvoice, err := invoice.IssueInvoice(ctx, order.ID, order.SellerID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	document, err := buildInvoiceDocument(ctx, order, orderInvoice)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	pdf, err := invoice.Render(document)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	c.Set(fiber.HeaderContentType, "application/pdf")
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`inline; filename="%s.pdf"`, orderInvoice.Number()))
+	return c.Status(fiber.StatusOK).Send(pdf)
+}
+
+// buildInvoiceDocument fills the invoice from the order's snapshot, so it shows the prices the buyer paid
+func buildInvoiceDocument(ctx context.Context, order Order, orderInvoice invoice.Invoice) (invoice.Document, error) {
+	seller, err := UserRepoImpl.GetUserByID(ctx, order.SellerID)
+	if err != nil {
+		return invoice.Document{}, err
+	}
+
+	buyer, err := UserRepoImpl.GetUserByID(ctx, order.UserID)
+	if err != nil {
+		return invoice.Document{}, err
+	}
+
+	document := invoice.Document{
+		Number:    orderInvoice.Number(),
+		IssuedAt:  orderInvoice.IssuedAt,
+		OrderID:   order.ID,
+		OrderedAt: order.CreatedAt,
+		Seller: invoice.Party{
+			Name:     seller.Name,
+			Username: seller.Username,
+		},
+		Buyer: invoice.Party{
+			Name:     buyer.Name,
+			Username: buyer.Username,
+		},
+		Items: []invoice.Item{
+			{
+				Description: fmt.Sprintf("%s (%s)", order.ProductName, order.ProductCondition),
+				Quantity:    order.Quantity,
+				UnitPrice:   money.New(order.UnitPrice, order.Currency),
+				Amount:      money.New(order.Subtotal, order.Currency),
+			},
+		},
+		Subtotal:        money.New(order.Subtotal, order.Currency),
+		Discount:        money.New(order.DiscountAmount, order.Currency),
+		CouponCode:      order.CouponCode,
+		ShippingService: order.ShippingService,
+		ShippingCost:    money.New(order.ShippingCost, order.Currency),
+		Total:           money.New(order.Total, order.Currency),
+		Refunded:        money.New(order.RefundedAmount, order.Currency),
+		// orders made before payments were recorded were all paid by manual transfer
+		PaymentMethod: payment.ProviderManualTransfer,
+		PaymentStatus: payment.StatusPaid,
+	}
+
+	if order.ShippingRecipientName != nil {
+		document.Buyer.Address = []string{
+			fmt.Sprintf("%s (%s)", *order.ShippingRecipientName, *order.ShippingPhone),
+			*order.ShippingAddressLine,
+			fmt.Sprintf("%s, %s %s", *order.ShippingCity, *order.ShippingProvince, *order.ShippingPostalCode),
+			*order.ShippingCountry,
+		}
+	}
+
+	taxLines, err := getOrderTaxLines(ctx, order.ID)
+	if err != nil {
+		return invoice.Document{}, err
+	}
+	for _, line := range taxLines[order.ID] {
+		document.Taxes = append(document.Taxes, invoice.Tax{
+			Name:            line.Name,
+			RateBasisPoints: line.RateBasisPoints,
+			Inclusive:       line.Inclusive,
+			Amount:          money.New(line.Amount, line.Currency),
+		})
+	}
+
+	orderPayment, err := PaymentRepoImpl.GetPaymentByOrderID(ctx, order.ID)
+	if err != nil && err != sql.ErrNoRows {
+		return invoice.Document{}, err
+	}
+	if err == nil {
+		document.PaymentMethod = orderPayment.Provider
+		document.PaymentStatus = orderPayment.Status
+	}
+
+	// the invoice shows the account the buyer paid into, even if the seller changed it since
+	if order.BankName != "" {
+		document.BankAccount = &invoice.BankAccount{
+			BankName:          order.BankName,
+			BankAccountName:   order.BankAccountName,
+			BankAccountNumber: order.BankAccountNumber,
+		}
+	}
+
+	return document, nil
+}
@@ -2,9 +2,21 @@ package product
 
 import (
 	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/address"
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/commission"
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/coupon"
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/ledger"
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/payment"
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/shipping"
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/tax"
 
 	"github.com/ahmadnaufal/openidea-shopifyx/internal/model"
 	"github.com/ahmadnaufal/openidea-shopifyx/pkg/jwt"
+	"github.com/ahmadnaufal/openidea-shopifyx/pkg/money"
 	"github.com/ahmadnaufal/openidea-shopifyx/pkg/validation"
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
@@ -39,78 +51,684 @@ func BuyProduct(c *fiber.Ctx) error {
 	}
 
 	ctx := c.Context()
-	order, err := validateAndCreateOrder(ctx, productID, claims.UserID, payload)
+	order, orderPayment, err := validateAndCreateOrder(ctx, productID, claims.UserID, payload)
 	if err != nil {
+		if errors.Is(err, errPaymentMethodNotFound) {
+			return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+				Message: err.Error(),
+				Code:    "invalid_payment_method",
+			})
+		}
+
+		if errors.Is(err, errAddressNotFound) {
+			return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+				Message: err.Error(),
+				Code:    "invalid_address",
+			})
+		}
+
+		if errors.Is(err, errStockNotEnough) {
+			return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+				Message: err.Error(),
+				Code:    "insufficient_stock",
+			})
+		}
+
+		if errors.Is(err, errBankAccountUnverified) {
+			return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+				Message: err.Error(),
+				Code:    "invalid_bank_account",
+			})
+		}
+
+		if errors.Is(err, errShippingServiceRequired) || errors.Is(err, errShippingServiceNotFound) {
+			return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+				Message: err.Error(),
+				Code:    "invalid_shipping_service",
+			})
+		}
+
+		if isCouponError(err) {
+			return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+				Message: err.Error(),
+				Code:    "invalid_coupon",
+			})
+		}
+
+		if errors.Is(err, money.ErrOverflow) {
+			return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+				Message: "order total is too large",
+				Code:    "invalid_quantity",
+			})
+		}
+
 		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
 			Message: "something wrong with the server. Please contact admin",
 			Code:    "internal_server_error",
 		})
 	}
 
+	response := orderEntityToResponse(order)
+	paymentResponse := payment.ToResponse(orderPayment)
+	response.Payment = &paymentResponse
+
 	return c.Status(fiber.StatusOK).JSON(model.DataResponse{
 		Message: "success",
-		Data: OrderResponse{
-			ID:                   order.ID,
-			ProductID:            order.ProductID,
-			BankAccountID:        order.BankAccountID,
-			PaymentProofImageURL: order.PaymentProofImageURL,
-			Quantity:             order.Quantity,
-		},
+		Data:    response,
 	})
 }
 
-func validateAndCreateOrder(ctx context.Context, productID, userID string, payload BuyProductRequest) (Order, error) {
+var (
+	errCouponNotFound  = errors.New("coupon not found")
+	errAddressNotFound = errors.New("address not found")
+
+	errPaymentMethodNotFound = errors.New("payment method is not available")
+	errBankAccountUnverified = errors.New("bank account is not verified")
+	errStockNotEnough        = errors.New("stock cannot be reduced")
+)
+
+func isCouponError(err error) bool {
+	return errors.Is(err, errCouponNotFound) ||
+		errors.Is(err, coupon.ErrCouponExpired) ||
+		errors.Is(err, coupon.ErrCouponUsageExceeded) ||
+		errors.Is(err, coupon.ErrCouponNotApplicable) ||
+		errors.Is(err, coupon.ErrCurrencyMismatch)
+}
+
+func validateAndCreateOrder(ctx context.Context, productID, userID string, payload BuyProductRequest) (Order, payment.Payment, error) {
+	paymentMethod := payload.PaymentMethod
+	if paymentMethod == "" {
+		paymentMethod = payment.ProviderManualTransfer
+	}
+	paymentProvider, ok := PaymentProviders[paymentMethod]
+	if !ok {
+		return Order{}, payment.Payment{}, errPaymentMethodNotFound
+	}
+
 	// check for bank account existence
 	bankAccount, err := BankAccountRepoImpl.GetBankAccountByID(ctx, payload.BankAccountID)
 	if err != nil {
-		return Order{}, err
+		return Order{}, payment.Payment{}, err
 	}
 
-	// check for product existence
-	product, err := ProductRepoImpl.GetProductByID(ctx, productID)
+	// check for product existence, unpublished products cannot be bought
+	product, err := ProductRepoImpl.GetVisibleProductByID(ctx, productID, userID)
 	if err != nil {
-		return Order{}, err
+		return Order{}, payment.Payment{}, err
+	}
+
+	// the order can only be shipped to one of the buyer's own addresses
+	shippingAddress, err := AddressRepoImpl.GetAddressByID(ctx, payload.AddressID)
+	if err != nil && err != sql.ErrNoRows {
+		return Order{}, payment.Payment{}, err
+	}
+	if err == sql.ErrNoRows || shippingAddress.UserID != userID {
+		return Order{}, payment.Payment{}, errAddressNotFound
 	}
 
 	// return 400 for bank account & product incompatibility
 	if bankAccount.UserID != product.UserID {
-		return Order{}, errors.New("stock not available")
+		return Order{}, payment.Payment{}, errors.New("stock not available")
+	}
+
+	// buyers can only pay into accounts the bank confirmed belong to the seller
+	if !bankAccount.IsVerified() {
+		return Order{}, payment.Payment{}, errBankAccountUnverified
 	}
 
 	// return 400 if user tries to buy his/her own product
 	if product.UserID == userID {
-		return Order{}, errors.New("user cannot buy his/her own product")
+		return Order{}, payment.Payment{}, errors.New("user cannot buy his/her own product")
 	}
 
-	// check for product stock existence
+	// check for product stock existence, it is only reserved in the transaction below
 	if product.Stock < payload.Quantity {
-		return Order{}, errors.New("stock cannot be reduced")
+		return Order{}, payment.Payment{}, errStockNotEnough
+	}
+
+	// the shipping cost is quoted before the transaction, as providers may be slow or remote
+	shippingRate, err := selectShippingRate(ctx, product, shippingAddress, payload.Quantity, payload.ShippingService)
+	if err != nil {
+		return Order{}, payment.Payment{}, err
+	}
+
+	category := ""
+	if product.Category != nil {
+		category = *product.Category
+	}
+	commissionRule, err := commission.ResolveRule(ctx, product.UserID, category)
+	if err != nil {
+		return Order{}, payment.Payment{}, err
+	}
+
+	taxRules, err := TaxRepoImpl.ListActiveRules(ctx)
+	if err != nil {
+		return Order{}, payment.Payment{}, err
 	}
 
 	tx, err := TrxProvider.NewTransaction(ctx)
 	if err != nil {
-		return Order{}, err
+		return Order{}, payment.Payment{}, err
 	}
 	defer tx.Rollback()
 
-	// create the order
+	// reserve the stock first, relative to what is left now, so concurrent purchases and returned
+	// stock are never written over, and nothing is charged for stock that's gone
+	stock, err := ProductRepoImpl.DecrementProductStock(ctx, tx, productID, payload.Quantity)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return Order{}, payment.Payment{}, errStockNotEnough
+		}
+		return Order{}, payment.Payment{}, err
+	}
+	product.Stock = stock
+
+	// create the order, snapshotting the product as it is at the time of purchase
+	now := time.Now()
+	unitPrice := product.EffectivePrice(now)
+	subtotal, err := unitPrice.Multiply(int64(payload.Quantity))
+	if err != nil {
+		return Order{}, payment.Payment{}, err
+	}
+
 	orderID := uuid.NewString()
 	order := Order{
 		ID:                   orderID,
 		UserID:               userID,
+		SellerID:             product.UserID,
 		ProductID:            product.ID,
 		BankAccountID:        bankAccount.ID,
+		BankCode:             bankAccount.BankCode,
+		BankName:             bankAccount.BankName,
+		BankAccountName:      bankAccount.BankAccountName,
+		BankAccountNumber:    bankAccount.BankAccountNumber,
 		PaymentProofImageURL: payload.PaymentProofImageURL,
 		Quantity:             payload.Quantity,
+		Status:               OrderStatusPendingPayment,
+		ProductName:          product.Name,
+		ProductImageURL:      product.ImageURL,
+		ProductCondition:     product.Condition,
+		// snapshot the address too, so editing or deleting it later doesn't change where the order goes
+		ShippingRecipientName: &shippingAddress.RecipientName,
+		ShippingPhone:         &shippingAddress.Phone,
+		ShippingAddressLine:   &shippingAddress.AddressLine,
+		ShippingCity:          &shippingAddress.City,
+		ShippingProvince:      &shippingAddress.Province,
+		ShippingPostalCode:    &shippingAddress.PostalCode,
+		ShippingCountry:       &shippingAddress.Country,
+		Currency:              unitPrice.Currency,
+		UnitPrice:             unitPrice.Amount,
+		Subtotal:              subtotal.Amount,
+		CreatedAt:             now,
+	}
+
+	// apply the seller's coupon, if any, to the price after sale
+	discount := money.New(0, unitPrice.Currency)
+	if payload.CouponCode != "" {
+		appliedCoupon, err := CouponRepoImpl.GetCouponByCodeForUpdate(ctx, tx, product.UserID, strings.ToUpper(payload.CouponCode))
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return Order{}, payment.Payment{}, errCouponNotFound
+			}
+			return Order{}, payment.Payment{}, err
+		}
+
+		err = appliedCoupon.CheckApplicable(product.ID, now)
+		if err != nil {
+			return Order{}, payment.Payment{}, err
+		}
+
+		discount, err = appliedCoupon.Discount(subtotal)
+		if err != nil {
+			return Order{}, payment.Payment{}, err
+		}
+
+		err = CouponRepoImpl.IncrementCouponUsage(ctx, tx, appliedCoupon.ID)
+		if err != nil {
+			return Order{}, payment.Payment{}, err
+		}
+
+		order.CouponID = &appliedCoupon.ID
+		order.CouponCode = &appliedCoupon.Code
+	}
+	order.DiscountAmount = discount.Amount
+
+	// coupons only discount the items, shipping is charged in full
+	shippingCost := money.New(0, unitPrice.Currency)
+	if shippingRate != nil {
+		order.ShippingService = &shippingRate.Service
+		shippingCost = shippingRate.Cost
+	}
+	order.ShippingCost = shippingCost.Amount
+
+	// the items are taxed after their discount, exclusive taxes are added to the total
+	discountedSubtotal, err := subtotal.Sub(discount)
+	if err != nil {
+		return Order{}, payment.Payment{}, err
+	}
+	taxes := tax.Calculate(taxRules, discountedSubtotal, category)
+	for i := range taxes.Lines {
+		taxes.Lines[i].ID = uuid.NewString()
+		taxes.Lines[i].OrderID = order.ID
+		taxes.Lines[i].CreatedAt = now
+	}
+	order.TaxLines = taxes.Lines
+	taxAmount, err := taxes.Inclusive.Add(taxes.Exclusive)
+	if err != nil {
+		return Order{}, payment.Payment{}, err
+	}
+	order.TaxAmount = taxAmount.Amount
+
+	total, err := discountedSubtotal.Add(taxes.Exclusive)
+	if err != nil {
+		return Order{}, payment.Payment{}, err
+	}
+	total, err = total.Add(shippingCost)
+	if err != nil {
+		return Order{}, payment.Payment{}, err
+	}
+	order.Total = total.Amount
+
+	// the commission is only taken from the items before tax, shipping is passed on to the courier
+	if commissionRule.ID != "" {
+		order.CommissionRuleID = &commissionRule.ID
+	}
+	order.CommissionRateBasisPoints = commissionRule.RateBasisPoints
+	commissionAmount, err := commissionRule.Calculate(taxes.Net)
+	if err != nil {
+		return Order{}, payment.Payment{}, err
 	}
+	order.CommissionAmount = commissionAmount.Amount
+
+	// charge the buyer, some providers (like a manual transfer) are paid straight away
+	orderPayment := payment.Payment{
+		ID:        uuid.NewString(),
+		OrderID:   order.ID,
+		UserID:    userID,
+		Provider:  paymentProvider.Name(),
+		Amount:    order.Total,
+		Currency:  order.Currency,
+		CreatedAt: now,
+	}
+	if payload.PaymentProofImageURL != "" {
+		orderPayment.PaymentProofImageURL = &payload.PaymentProofImageURL
+	}
+
+	charge, err := paymentProvider.CreateCharge(ctx, payment.ChargeRequest{
+		PaymentID:            orderPayment.ID,
+		OrderID:              order.ID,
+		Amount:               money.New(order.Total, order.Currency),
+		PaymentProofImageURL: payload.PaymentProofImageURL,
+	})
+	if err != nil {
+		return Order{}, payment.Payment{}, err
+	}
+
+	orderPayment.ExternalID = charge.ExternalID
+	orderPayment.Status = charge.Status
+	orderPayment.VirtualAccountNumber = charge.VirtualAccountNumber
+	if charge.Status == payment.StatusPaid {
+		orderPayment.PaidAt = &now
+		order.Status = OrderStatusPaid
+	} else {
+		// the stock is held for the buyer until the deadline, then the order is cancelled
+		paymentDeadline := now.Add(PaymentDeadline)
+		order.PaymentDeadline = &paymentDeadline
+	}
+
 	err = ProductRepoImpl.CreateOrder(ctx, tx, order)
+	if err != nil {
+		return Order{}, payment.Payment{}, err
+	}
+
+	err = TaxRepoImpl.CreateOrderTaxLines(ctx, tx, order.TaxLines)
+	if err != nil {
+		return Order{}, payment.Payment{}, err
+	}
+
+	err = PaymentRepoImpl.CreatePayment(ctx, tx, orderPayment)
+	if err != nil {
+		return Order{}, payment.Payment{}, err
+	}
+
+	err = emitStockChanged(ctx, tx, product)
+	if err != nil {
+		return Order{}, payment.Payment{}, err
+	}
+
+	err = emitOrderCreated(ctx, tx, order)
+	if err != nil {
+		return Order{}, payment.Payment{}, err
+	}
+
+	if order.Status == OrderStatusPaid {
+		err = emitOrderPaid(ctx, tx, order)
+		if err != nil {
+			return Order{}, payment.Payment{}, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return Order{}, payment.Payment{}, err
+	}
+
+	return order, orderPayment, nil
+}
+
+func ListOrders(c *fiber.Ctx) error {
+	claims, err := jwt.GetLoggedInUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "forbidden",
+		})
+	}
+
+	var req ListOrdersRequest
+	if err := c.QueryParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "invalid_request_body",
+		})
+	}
+
+	if err := validation.Validate(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "failed_request_body_validation",
+		})
+	}
+
+	ctx := c.Context()
+	orders, count, err := ProductRepoImpl.ListOrders(ctx, claims.UserID, req)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	orderIDs := make([]string, len(orders))
+	for i, order := range orders {
+		orderIDs[i] = order.ID
+	}
+
+	taxLines, err := getOrderTaxLines(ctx, orderIDs...)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	responses := make([]OrderResponse, len(orders))
+	for i, order := range orders {
+		order.TaxLines = taxLines[order.ID]
+		responses[i] = orderEntityToResponse(order)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(model.DataResponse{
+		Message: "ok",
+		Data:    responses,
+		Meta: &model.ResponseMeta{
+			Limit:  req.Limit,
+			Offset: req.Offset,
+			Total:  count,
+		},
+	})
+}
+
+func GetOrder(c *fiber.Ctx) error {
+	orderID := c.Params("order_id")
+
+	claims, err := jwt.GetLoggedInUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "forbidden",
+		})
+	}
+
+	ctx := c.Context()
+	order, err := ProductRepoImpl.GetOrderByID(ctx, orderID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return c.Status(fiber.StatusNotFound).JSON(model.ErrorResponse{
+				Message: "order not found",
+				Code:    "entity_not_found",
+			})
+		}
+
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	// only the buyer and the seller can see the order
+	if order.UserID != claims.UserID && order.SellerID != claims.UserID {
+		return c.Status(fiber.StatusNotFound).JSON(model.ErrorResponse{
+			Message: "order not found",
+			Code:    "entity_not_found",
+		})
+	}
+
+	taxLines, err := getOrderTaxLines(ctx, order.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+	order.TaxLines = taxLines[order.ID]
+
+	response := orderEntityToResponse(order)
+
+	// orders made before payments were recorded don't have one
+	orderPayment, err := PaymentRepoImpl.GetPaymentByOrderID(ctx, order.ID)
+	if err != nil && err != sql.ErrNoRows {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+	if err == nil {
+		paymentResponse := payment.ToResponse(orderPayment)
+		response.Payment = &paymentResponse
+	}
+
+	return c.Status(fiber.StatusOK).JSON(model.DataResponse{
+		Message: "ok",
+		Data:    response,
+	})
+}
+
+// getOrderTaxLines returns the tax lines of the orders by their ID
+func getOrderTaxLines(ctx context.Context, orderIDs ...string) (map[string][]tax.Line, error) {
+	lines, err := TaxRepoImpl.ListOrderTaxLines(ctx, orderIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	linesByOrderID := map[string][]tax.Line{}
+	for _, line := range lines {
+		linesByOrderID[line.OrderID] = append(linesByOrderID[line.OrderID], line)
+	}
+
+	return linesByOrderID, nil
+}
+
+func orderEntityToResponse(order Order) OrderResponse {
+	response := OrderResponse{
+		ID:            order.ID,
+		ProductID:     order.ProductID,
+		BankAccountID: order.BankAccountID,
+		BankAccount: OrderBankAccountResponse{
+			BankCode:          order.BankCode,
+			BankName:          order.BankName,
+			BankAccountName:   order.BankAccountName,
+			BankAccountNumber: order.BankAccountNumber,
+		},
+		PaymentProofImageURL: order.PaymentProofImageURL,
+		Quantity:             order.Quantity,
+		Status:               order.Status,
+		PaymentDeadline:      order.PaymentDeadline,
+		ProductName:          order.ProductName,
+		ProductImageURL:      order.ProductImageURL,
+		ProductCondition:     order.ProductCondition,
+		UnitPrice:            money.New(order.UnitPrice, order.Currency),
+		Subtotal:             money.New(order.Subtotal, order.Currency),
+		CouponCode:           order.CouponCode,
+		Discount:             money.New(order.DiscountAmount, order.Currency),
+		ShippingService:      order.ShippingService,
+		ShippingCost:         money.New(order.ShippingCost, order.Currency),
+		Tax:                  money.New(order.TaxAmount, order.Currency),
+		Taxes:                tax.LinesToResponse(order.TaxLines),
+		Refunded:             money.New(order.RefundedAmount, order.Currency),
+		Total:                money.New(order.Total, order.Currency),
+		CompletedAt:          order.CompletedAt,
+		CreatedAt:            order.CreatedAt,
+	}
+
+	if order.ShippingRecipientName != nil {
+		response.ShippingAddress = &OrderAddressResponse{
+			RecipientName: *order.ShippingRecipientName,
+			Phone:         *order.ShippingPhone,
+			AddressLine:   *order.ShippingAddressLine,
+			City:          *order.ShippingCity,
+			Province:      *order.ShippingProvince,
+			PostalCode:    *order.ShippingPostalCode,
+			Country:       *order.ShippingCountry,
+		}
+	}
+
+	if order.ShippedAt != nil {
+		response.Shipment = &OrderShipmentResponse{
+			Courier:        *order.Courier,
+			TrackingNumber: *order.TrackingNumber,
+			ShippedAt:      *order.ShippedAt,
+		}
+	}
+
+	return response
+}
+
+// ShipOrder marks a paid order as shipped, with the courier and tracking number the buyer can follow
+func ShipOrder(c *fiber.Ctx) error {
+	orderID := c.Params("order_id")
+
+	claims, err := jwt.GetLoggedInUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "forbidden",
+		})
+	}
+
+	var payload ShipOrderRequest
+	if err := c.BodyParser(&payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "invalid_request_body",
+		})
+	}
+
+	// validation for request body
+	if err := validation.Validate(payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "failed_request_body_validation",
+		})
+	}
+
+	ctx := c.Context()
+	order, err := ProductRepoImpl.GetOrderByID(ctx, orderID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return c.Status(fiber.StatusNotFound).JSON(model.ErrorResponse{
+				Message: "order not found",
+				Code:    "entity_not_found",
+			})
+		}
+
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	// only the seller can ship the order, and the buyer can't see other users' orders at all
+	if order.SellerID != claims.UserID {
+		if order.UserID == claims.UserID {
+			return c.Status(fiber.StatusForbidden).JSON(model.ErrorResponse{
+				Message: "only the seller can ship an order",
+				Code:    "ship_order_forbidden",
+			})
+		}
+
+		return c.Status(fiber.StatusNotFound).JSON(model.ErrorResponse{
+			Message: "order not found",
+			Code:    "entity_not_found",
+		})
+	}
+
+	taxLines, err := getOrderTaxLines(ctx, order.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+	order.TaxLines = taxLines[order.ID]
+
+	order, err = shipOrder(ctx, order, payload)
+	if err != nil {
+		if errors.Is(err, errOrderNotShippable) {
+			return c.Status(fiber.StatusConflict).JSON(model.ErrorResponse{
+				Message: err.Error(),
+				Code:    "order_not_shippable",
+			})
+		}
+
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(model.DataResponse{
+		Message: "Order shipped successfully",
+		Data:    orderEntityToResponse(order),
+	})
+}
+
+var errOrderNotShippable = errors.New("only paid orders that haven't been shipped or fully refunded can be shipped")
+
+func shipOrder(ctx context.Context, order Order, payload ShipOrderRequest) (Order, error) {
+	// a partial refund settles a dispute, the rest of the order still has to be shipped
+	if order.Status != OrderStatusPaid && order.Status != OrderStatusPartiallyRefunded {
+		return Order{}, errOrderNotShippable
+	}
+
+	tx, err := TrxProvider.NewTransaction(ctx)
 	if err != nil {
 		return Order{}, err
 	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	order.Status = OrderStatusShipped
+	order.Courier = &payload.Courier
+	order.TrackingNumber = &payload.TrackingNumber
+	order.ShippedAt = &now
 
-	// decrement order stock
-	product.Stock -= payload.Quantity
-	err = ProductRepoImpl.UpdateProductStock(ctx, tx, productID, product.Stock)
+	err = ProductRepoImpl.ShipOrder(ctx, tx, order)
+	if err != nil {
+		return Order{}, err
+	}
+
+	err = emitOrderStatusChanged(ctx, tx, order)
 	if err != nil {
 		return Order{}, err
 	}
@@ -122,3 +740,278 @@ func validateAndCreateOrder(ctx context.Context, productID, userID string, paylo
 
 	return order, nil
 }
+
+// CompleteOrder lets the buyer confirm they've received a shipped order, which credits its seller
+func CompleteOrder(c *fiber.Ctx) error {
+	orderID := c.Params("order_id")
+
+	claims, err := jwt.GetLoggedInUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "forbidden",
+		})
+	}
+
+	ctx := c.Context()
+	order, err := ProductRepoImpl.GetOrderByID(ctx, orderID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return c.Status(fiber.StatusNotFound).JSON(model.ErrorResponse{
+				Message: "order not found",
+				Code:    "entity_not_found",
+			})
+		}
+
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	// only the buyer can complete the order, and the seller can't complete it on their behalf
+	if order.UserID != claims.UserID {
+		if order.SellerID == claims.UserID {
+			return c.Status(fiber.StatusForbidden).JSON(model.ErrorResponse{
+				Message: "only the buyer can complete an order",
+				Code:    "complete_order_forbidden",
+			})
+		}
+
+		return c.Status(fiber.StatusNotFound).JSON(model.ErrorResponse{
+			Message: "order not found",
+			Code:    "entity_not_found",
+		})
+	}
+
+	taxLines, err := getOrderTaxLines(ctx, order.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+	order.TaxLines = taxLines[order.ID]
+
+	order, err = completeOrder(ctx, order)
+	if err != nil {
+		if errors.Is(err, errOrderNotCompletable) {
+			return c.Status(fiber.StatusConflict).JSON(model.ErrorResponse{
+				Message: err.Error(),
+				Code:    "order_not_completable",
+			})
+		}
+
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(model.DataResponse{
+		Message: "Order completed successfully",
+		Data:    orderEntityToResponse(order),
+	})
+}
+
+var errOrderNotCompletable = errors.New("only shipped orders that haven't been completed or fully refunded can be completed")
+
+// completeOrder completes the order and credits its seller with what the buyer paid, less any refund
+// and the platform's commission
+func completeOrder(ctx context.Context, order Order) (Order, error) {
+	tx, err := TrxProvider.NewTransaction(ctx)
+	if err != nil {
+		return Order{}, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	completedOrder, err := ProductRepoImpl.CompleteOrder(ctx, tx, order.ID, now)
+	if err != nil {
+		return Order{}, err
+	}
+
+	order.Status = OrderStatusCompleted
+	order.CompletedAt = &now
+	order.RefundedAmount = completedOrder.RefundedAmount
+
+	// the commission of what was refunded before completion isn't charged
+	amount := completedOrder.Total - completedOrder.RefundedAmount
+	err = ledger.RecordOrderCompleted(ctx, tx, ledger.CompletedOrder{
+		OrderID:  order.ID,
+		SellerID: completedOrder.SellerID,
+		Currency: completedOrder.Currency,
+		Amount:   amount,
+		Fee:      completedOrder.CommissionOn(amount).Amount,
+	})
+	if err != nil {
+		return Order{}, err
+	}
+
+	err = emitOrderStatusChanged(ctx, tx, order)
+	if err != nil {
+		return Order{}, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return Order{}, err
+	}
+
+	return order, nil
+}
+
+var (
+	errShippingServiceRequired = errors.New("shipping service is required")
+	errShippingServiceNotFound = errors.New("shipping service is not available for this address")
+)
+
+// selectShippingRate picks the chosen shipping service out of the rates quoted for the parcel, or the
+// only one quoted if none was chosen. It returns nil when the seller doesn't charge for shipping, and errShippingServiceNotFound when the
+// seller's rates don't cover the parcel.
+func selectShippingRate(ctx context.Context, product Product, shippingAddress address.Address, quantity int, service string) (*shipping.Rate, error) {
+	rates, err := quoteShippingRates(ctx, product, shippingAddress, quantity)
+	if err != nil {
+		if errors.Is(err, shipping.ErrNotDeliverable) {
+			return nil, errShippingServiceNotFound
+		}
+		return nil, err
+	}
+
+	// there's nothing to choose from when only one service is offered
+	if service == "" {
+		switch len(rates) {
+		case 0:
+			return nil, nil
+		case 1:
+			return &rates[0], nil
+		default:
+			return nil, errShippingServiceRequired
+		}
+	}
+
+	for _, rate := range rates {
+		if rate.Service == service {
+			return &rate, nil
+		}
+	}
+
+	return nil, errShippingServiceNotFound
+}
+
+func quoteShippingRates(ctx context.Context, product Product, shippingAddress address.Address, quantity int) ([]shipping.Rate, error) {
+	return ShippingProviderImpl.Quote(ctx, shipping.QuoteRequest{
+		SellerID:          product.UserID,
+		DestinationRegion: shippingAddress.Province,
+		WeightGrams:       product.WeightGrams * quantity,
+		Currency:          product.Currency,
+	})
+}
+
+// QuoteShipping lists the shipping services, and their costs, for sending the product to one of the buyer's addresses
+func QuoteShipping(c *fiber.Ctx) error {
+	productID := c.Params("product_id")
+
+	claims, err := jwt.GetLoggedInUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "forbidden",
+		})
+	}
+
+	var req ShippingQuoteRequest
+	if err := c.QueryParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "invalid_request_body",
+		})
+	}
+
+	if err := validation.Validate(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "failed_request_body_validation",
+		})
+	}
+
+	if req.Quantity == 0 {
+		req.Quantity = 1
+	}
+
+	ctx := c.Context()
+	product, err := ProductRepoImpl.GetVisibleProductByID(ctx, productID, claims.UserID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return c.Status(fiber.StatusNotFound).JSON(model.ErrorResponse{
+				Message: "product not found",
+				Code:    "entity_not_found",
+			})
+		}
+
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	shippingAddress, err := AddressRepoImpl.GetAddressByID(ctx, req.AddressID)
+	if err != nil && err != sql.ErrNoRows {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+	if err == sql.ErrNoRows || shippingAddress.UserID != claims.UserID {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: errAddressNotFound.Error(),
+			Code:    "invalid_address",
+		})
+	}
+
+	rates, err := quoteShippingRates(ctx, product, shippingAddress, req.Quantity)
+	if err != nil {
+		if errors.Is(err, shipping.ErrNotDeliverable) {
+			return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+				Message: err.Error(),
+				Code:    "invalid_shipping_service",
+			})
+		}
+
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(model.DataResponse{
+		Message: "ok",
+		Data:    shipping.RatesToResponse(rates),
+	})
+}
+
+var errOrderNotPayable = errors.New("only orders pending payment can be paid")
+
+// LockOrder locks the order in the given transaction, before its payment is locked
+func LockOrder(ctx context.Context, tx *sql.Tx, orderID string) error {
+	return ProductRepoImpl.LockOrder(ctx, tx, orderID)
+}
+
+// MarkOrderPaid moves an order pending payment to paid once its payment is settled. It runs in the
+// payment's transaction, so the payment and the order are never out of sync.
+func MarkOrderPaid(ctx context.Context, tx *sql.Tx, orderID string) error {
+	order, err := ProductRepoImpl.UpdateOrderStatus(ctx, tx, orderID, OrderStatusPendingPayment, OrderStatusPaid)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return errOrderNotPayable
+		}
+		return err
+	}
+
+	err = emitOrderStatusChanged(ctx, tx, order)
+	if err != nil {
+		return err
+	}
+
+	return emitOrderPaid(ctx, tx, order)
+}
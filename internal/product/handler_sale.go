@@ -0,0 +1,147 @@
+package product
+
+import (
+	"database/sql"
+
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/model"
+	"github.com/ahmadnaufal/openidea-shopifyx/pkg/jwt"
+	"github.com/ahmadnaufal/openidea-shopifyx/pkg/validation"
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetProductSale(c *fiber.Ctx) error {
+	productID := c.Params("product_id")
+
+	claims, err := jwt.GetLoggedInUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "forbidden",
+		})
+	}
+
+	var payload ProductSaleRequest
+	if err := c.BodyParser(&payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "invalid_request_body",
+		})
+	}
+
+	// validation for request body
+	if err := validation.Validate(payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "failed_request_body_validation",
+		})
+	}
+
+	ctx := c.Context()
+
+	product, err := ProductRepoImpl.GetProductByID(ctx, productID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return c.Status(fiber.StatusNotFound).JSON(model.ErrorResponse{
+				Message: "product not found",
+				Code:    "entity_not_found",
+			})
+		}
+
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	// check product ownership
+	if product.UserID != claims.UserID {
+		return c.Status(fiber.StatusForbidden).JSON(model.ErrorResponse{
+			Message: "cannot update a product that is owned by another user",
+			Code:    "update_product_forbidden",
+		})
+	}
+
+	if payload.SalePrice.Currency != product.Currency {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: "sale price currency must be the same as the product price currency",
+			Code:    "failed_request_body_validation",
+		})
+	}
+
+	if payload.SalePrice.Amount >= product.Price {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: "sale price must be lower than the product price",
+			Code:    "failed_request_body_validation",
+		})
+	}
+
+	salePrice := payload.SalePrice.Round().Amount
+	product.SalePrice = &salePrice
+	product.SaleStartsAt = &payload.StartsAt
+	product.SaleEndsAt = &payload.EndsAt
+	err = ProductRepoImpl.UpdateProductSale(ctx, nil, product)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(model.DataResponse{
+		Message: "Product sale updated successfully",
+		Data:    productEntityToResponse(product, nil, 0),
+	})
+}
+
+func RemoveProductSale(c *fiber.Ctx) error {
+	productID := c.Params("product_id")
+
+	claims, err := jwt.GetLoggedInUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "forbidden",
+		})
+	}
+
+	ctx := c.Context()
+
+	product, err := ProductRepoImpl.GetProductByID(ctx, productID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return c.Status(fiber.StatusNotFound).JSON(model.ErrorResponse{
+				Message: "product not found",
+				Code:    "entity_not_found",
+			})
+		}
+
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	// check product ownership
+	if product.UserID != claims.UserID {
+		return c.Status(fiber.StatusForbidden).JSON(model.ErrorResponse{
+			Message: "cannot update a product that is owned by another user",
+			Code:    "update_product_forbidden",
+		})
+	}
+
+	product.SalePrice = nil
+	product.SaleStartsAt = nil
+	product.SaleEndsAt = nil
+	err = ProductRepoImpl.UpdateProductSale(ctx, nil, product)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(model.DataResponse{
+		Message: "Product sale removed successfully",
+		Data:    productEntityToResponse(product, nil, 0),
+	})
+}
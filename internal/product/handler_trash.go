@@ -0,0 +1,138 @@
+package product
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/model"
+	"github.com/ahmadnaufal/openidea-shopifyx/pkg/jwt"
+	"github.com/gofiber/fiber/v2"
+)
+
+func ListTrashedProducts(c *fiber.Ctx) error {
+	claims, err := jwt.GetLoggedInUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "forbidden",
+		})
+	}
+
+	ctx := c.Context()
+
+	// only list products that are still within the retention window
+	products, err := ProductRepoImpl.ListDeletedProductsByUserID(ctx, claims.UserID, time.Now().Add(-TrashRetention))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	productIDs := []string{}
+	for _, product := range products {
+		productIDs = append(productIDs, product.ID)
+	}
+
+	productTagsMap := map[string][]ProductTag{}
+	if len(productIDs) > 0 {
+		productTagsMap, err = ProductRepoImpl.BulkGetProductTags(ctx, productIDs)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+				Message: "something wrong with the server. Please contact admin",
+				Code:    "internal_server_error",
+			})
+		}
+	}
+
+	responses := []TrashedProductResponse{}
+	for _, product := range products {
+		tags := []string{}
+		for _, tag := range productTagsMap[product.ID] {
+			tags = append(tags, tag.Tag)
+		}
+
+		responses = append(responses, TrashedProductResponse{
+			Product:         productEntityToResponse(product, tags, 0),
+			DeletedAt:       *product.DeletedAt,
+			RestorableUntil: product.DeletedAt.Add(TrashRetention),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(model.DataResponse{
+		Message: "ok",
+		Data:    responses,
+	})
+}
+
+func RestoreProduct(c *fiber.Ctx) error {
+	productID := c.Params("product_id")
+
+	claims, err := jwt.GetLoggedInUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "forbidden",
+		})
+	}
+
+	ctx := c.Context()
+
+	product, err := ProductRepoImpl.GetDeletedProductByID(ctx, productID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return c.Status(fiber.StatusNotFound).JSON(model.ErrorResponse{
+				Message: "deleted product not found",
+				Code:    "entity_not_found",
+			})
+		}
+
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	// check product ownership
+	if product.UserID != claims.UserID {
+		return c.Status(fiber.StatusForbidden).JSON(model.ErrorResponse{
+			Message: "cannot restore a product that is owned by another user",
+			Code:    "update_product_forbidden",
+		})
+	}
+
+	// check whether the product is still within the retention window
+	deletedAfter := time.Now().Add(-TrashRetention)
+	if product.DeletedAt.Before(deletedAfter) {
+		return c.Status(fiber.StatusGone).JSON(model.ErrorResponse{
+			Message: "product can no longer be restored",
+			Code:    "restore_window_expired",
+		})
+	}
+
+	err = ProductRepoImpl.RestoreProduct(ctx, nil, productID, deletedAfter)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	tagsMap, err := ProductRepoImpl.BulkGetProductTags(ctx, []string{productID})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	tags := []string{}
+	for _, tag := range tagsMap[productID] {
+		tags = append(tags, tag.Tag)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(model.DataResponse{
+		Message: "Product restored successfully",
+		Data:    productEntityToResponse(product, tags, 0),
+	})
+}
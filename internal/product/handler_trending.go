@@ -0,0 +1,88 @@
+package product
+
+import (
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/model"
+	"github.com/ahmadnaufal/openidea-shopifyx/pkg/jwt"
+	"github.com/ahmadnaufal/openidea-shopifyx/pkg/validation"
+	"github.com/gofiber/fiber/v2"
+)
+
+func ListTrendingProducts(c *fiber.Ctx) error {
+	var req ListTrendingProductsRequest
+	if err := c.QueryParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "invalid_request_body",
+		})
+	}
+
+	if err := validation.Validate(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "failed_request_body_validation",
+		})
+	}
+
+	// logged in users can also see their own unpublished products
+	viewerID := ""
+	if claims, err := jwt.GetLoggedInUser(c); err == nil {
+		viewerID = claims.UserID
+	}
+
+	ctx := c.Context()
+	products, err := ProductRepoImpl.ListTrendingProducts(ctx, req, viewerID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	productIDs := []string{}
+	for _, product := range products {
+		productIDs = append(productIDs, product.ID)
+	}
+
+	productTagsMap := map[string][]ProductTag{}
+	purchaseCountMap := map[string]int{}
+	if len(productIDs) > 0 {
+		productTagsMap, err = ProductRepoImpl.BulkGetProductTags(ctx, productIDs)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+				Message: "something wrong with the server. Please contact admin",
+				Code:    "internal_server_error",
+			})
+		}
+
+		purchaseCountMap, err = ProductRepoImpl.GetPurchaseCountByProductIDs(ctx, productIDs)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+				Message: "something wrong with the server. Please contact admin",
+				Code:    "internal_server_error",
+			})
+		}
+	}
+
+	responses := []ProductResponse{}
+	for _, product := range products {
+		tags := []string{}
+		for _, tag := range productTagsMap[product.ID] {
+			tags = append(tags, tag.Tag)
+		}
+
+		responses = append(responses, productEntityToResponse(product, tags, purchaseCountMap[product.ID]))
+	}
+
+	err = populateFavourites(ctx, viewerID, products, responses)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(model.DataResponse{
+		Message: "ok",
+		Data:    responses,
+	})
+}
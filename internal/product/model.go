@@ -1,34 +1,113 @@
 package product
 
-import "time"
+import (
+	"time"
+
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/tax"
+	"github.com/ahmadnaufal/openidea-shopifyx/pkg/money"
+	"github.com/lib/pq"
+)
+
+const (
+	ProductStatusDraft    = "draft"
+	ProductStatusActive   = "active"
+	ProductStatusArchived = "archived"
+)
+
+// DefaultWeightGrams is the shipping weight of a product whose seller didn't set one
+const DefaultWeightGrams = 1000
+
+const (
+	// orders paid through a gateway wait for its callback, manual transfers are paid up front with a payment proof
+	OrderStatusPendingPayment = "pending_payment"
+	OrderStatusPaid           = "paid"
+	OrderStatusShipped        = "shipped"
+	// orders that weren't paid before their payment deadline
+	OrderStatusCancelled = "cancelled"
+	// orders refunded through a dispute, in full or in part
+	OrderStatusRefunded          = "refunded"
+	OrderStatusPartiallyRefunded = "partially_refunded"
+	// orders the buyer confirmed receiving, their seller is credited with them
+	OrderStatusCompleted = "completed"
+)
+
+const (
+	DisputeStatusOpen     = "open"
+	DisputeStatusResolved = "resolved"
+)
+
+const (
+	DisputeResolutionRefunded = "refunded"
+	DisputeResolutionRejected = "rejected"
+)
 
 type CreateProductRequest struct {
-	Name          string   `json:"name" validate:"required,min=5,max=60"`
-	Price         int      `json:"price" validate:"required,gte=0"`
-	ImageURL      string   `json:"imageUrl" validate:"required,url"`
-	Stock         int      `json:"stock" validate:"required,gte=0"`
-	Condition     string   `json:"condition" validate:"oneof=new second"`
-	Tags          []string `json:"tags" validate:"required,min=0"`
-	IsPurchasable *bool    `json:"isPurchasable" validate:"required"`
+	Name          string      `json:"name" validate:"required,min=5,max=60"`
+	Price         money.Money `json:"price" validate:"required,positive_amount"`
+	ImageURL      string      `json:"imageUrl" validate:"required,url"`
+	Stock         int         `json:"stock" validate:"required,gte=0"`
+	WeightGrams   int         `json:"weightGrams" validate:"omitempty,gte=1,lte=100000"`
+	Category      string      `json:"category" validate:"omitempty,max=50"`
+	Condition     string      `json:"condition" validate:"oneof=new second"`
+	Tags          []string    `json:"tags" validate:"required,min=0"`
+	IsPurchasable *bool       `json:"isPurchasable" validate:"required"`
+	Status        string      `json:"status" validate:"omitempty,oneof=draft active archived"`
+	PublishAt     *time.Time  `json:"publishAt"`
+	UnpublishAt   *time.Time  `json:"unpublishAt"`
 }
 
 type UpdateProductRequest struct {
-	Name          string   `json:"name" validate:"required,min=5,max=60"`
-	Price         int      `json:"price" validate:"required,gte=0"`
-	ImageURL      string   `json:"imageUrl" validate:"required,url"`
-	Condition     string   `json:"condition" validate:"oneof=new second"`
-	Tags          []string `json:"tags" validate:"required,min=0"`
-	IsPurchasable *bool    `json:"isPurchasable" validate:"required"`
+	Name          string      `json:"name" validate:"required,min=5,max=60"`
+	Price         money.Money `json:"price" validate:"required,positive_amount"`
+	ImageURL      string      `json:"imageUrl" validate:"required,url"`
+	WeightGrams   int         `json:"weightGrams" validate:"omitempty,gte=1,lte=100000"`
+	Category      string      `json:"category" validate:"omitempty,max=50"`
+	Condition     string      `json:"condition" validate:"oneof=new second"`
+	Tags          []string    `json:"tags" validate:"required,min=0"`
+	IsPurchasable *bool       `json:"isPurchasable" validate:"required"`
+	Status        string      `json:"status" validate:"omitempty,oneof=draft active archived"`
+	PublishAt     *time.Time  `json:"publishAt"`
+	UnpublishAt   *time.Time  `json:"unpublishAt"`
 }
 
 type UpdateProductStockRequest struct {
 	Stock int `json:"stock" validate:"required,gte=0"`
 }
 
+type ProductSaleRequest struct {
+	SalePrice money.Money `json:"salePrice" validate:"required,positive_amount"`
+	StartsAt  time.Time   `json:"startsAt" validate:"required"`
+	EndsAt    time.Time   `json:"endsAt" validate:"required,gtfield=StartsAt"`
+}
+
 type BuyProductRequest struct {
-	BankAccountID        string `json:"bankAccountId" validate:"required"`
-	PaymentProofImageURL string `json:"paymentProofImageUrl" validate:"required,url"`
+	BankAccountID string `json:"bankAccountId" validate:"required"`
+	// PaymentMethod is the payment provider, defaults to a manual transfer with a payment proof
+	PaymentMethod        string `json:"paymentMethod" validate:"omitempty,oneof=manual_transfer mock"`
+	PaymentProofImageURL string `json:"paymentProofImageUrl" validate:"required_unless=PaymentMethod mock,omitempty,url"`
 	Quantity             int    `json:"quantity" validate:"required,gte=1"`
+	CouponCode           string `json:"couponCode" validate:"omitempty,alphanum,max=32"`
+	// AddressID is one of the buyer's addresses to ship the order to
+	AddressID string `json:"addressId" validate:"required"`
+	// ShippingService is one of the services quoted for the address, required when more than one is quoted
+	ShippingService string `json:"shippingService" validate:"omitempty,max=30"`
+}
+
+type ShippingQuoteRequest struct {
+	AddressID string `query:"addressId" validate:"required"`
+	Quantity  int    `query:"quantity" validate:"omitempty,gte=1"`
+}
+
+type ShipOrderRequest struct {
+	Courier        string `json:"courier" validate:"required,min=2,max=30"`
+	TrackingNumber string `json:"trackingNumber" validate:"required,alphanum,min=5,max=40"`
+}
+
+type BulkProductRequest struct {
+	ProductIDs      []string `json:"productIds" validate:"required,min=1,max=100,dive,required"`
+	Operation       string   `json:"operation" validate:"oneof=set_purchasable set_unpurchasable adjust_price add_tag remove_tag delete"`
+	PricePercentage int      `json:"pricePercentage" validate:"required_if=Operation adjust_price,gt=-100,lte=1000"`
+	Tag             string   `json:"tag" validate:"required_if=Operation add_tag,required_if=Operation remove_tag,max=32"`
 }
 
 type ListProductsRequest struct {
@@ -38,26 +117,72 @@ type ListProductsRequest struct {
 	Tags           []string `query:"tags"`
 	Condition      string   `query:"condition"`
 	ShowEmptyStock bool     `query:"showEmptyStock"`
-	MaxPrice       int      `query:"maxPrice"`
-	MinPrice       int      `query:"minPrice"`
-	SortBy         string   `query:"sortBy"`
-	OrderBy        string   `query:"orderBy"`
-	Search         string   `query:"search"`
+	MaxPrice       int64    `query:"maxPrice"`
+	MinPrice       int64    `query:"minPrice"`
+	// Currency of the price filters, as the amounts are in the currency's minor units
+	Currency string `query:"currency"`
+	SortBy   string `query:"sortBy"`
+	OrderBy  string `query:"orderBy"`
+	Search   string `query:"search"`
 
 	// UserID to store userID when userOnly flag is enabled
 	UserID string
+	// ViewerID to store the logged in user, if any, so they can also see their own unpublished products
+	ViewerID string
 }
 
 type Product struct {
-	ID            string    `db:"id"`
-	UserID        string    `db:"user_id"`
-	Name          string    `db:"name"`
-	Price         int       `db:"price"`
-	ImageURL      string    `db:"image_url"`
-	Stock         int       `db:"stock"`
-	Condition     string    `db:"condition"`
-	IsPurchasable bool      `db:"is_purchasable"`
-	CreatedAt     time.Time `db:"created_at"`
+	ID            string         `db:"id"`
+	UserID        string         `db:"user_id"`
+	Name          string         `db:"name"`
+	Price         int64          `db:"price"`
+	Currency      money.Currency `db:"currency"`
+	ImageURL      string         `db:"image_url"`
+	Stock         int            `db:"stock"`
+	WeightGrams   int            `db:"weight_grams"`
+	Category      *string        `db:"category"`
+	Condition     string         `db:"condition"`
+	IsPurchasable bool           `db:"is_purchasable"`
+	Status        string         `db:"status"`
+	PublishAt     *time.Time     `db:"publish_at"`
+	UnpublishAt   *time.Time     `db:"unpublish_at"`
+	SalePrice     *int64         `db:"sale_price"`
+	SaleStartsAt  *time.Time     `db:"sale_starts_at"`
+	SaleEndsAt    *time.Time     `db:"sale_ends_at"`
+	CreatedAt     time.Time      `db:"created_at"`
+	DeletedAt     *time.Time     `db:"deleted_at"`
+}
+
+// IsOnSale checks whether the product has a sale price that is active at the given time
+func (p Product) IsOnSale(now time.Time) bool {
+	if p.SalePrice == nil || p.SaleStartsAt == nil || p.SaleEndsAt == nil {
+		return false
+	}
+
+	return !now.Before(*p.SaleStartsAt) && now.Before(*p.SaleEndsAt) && *p.SalePrice < p.Price
+}
+
+// IsPublished checks whether the product is visible to buyers at the given time, the same as visibleProductCondition
+func (p Product) IsPublished(now time.Time) bool {
+	if p.Status != ProductStatusActive || p.DeletedAt != nil {
+		return false
+	}
+
+	return (p.PublishAt == nil || !p.PublishAt.After(now)) && (p.UnpublishAt == nil || p.UnpublishAt.After(now))
+}
+
+// OriginalPrice returns the listed price of one item, without any sale applied
+func (p Product) OriginalPrice() money.Money {
+	return money.New(p.Price, p.Currency)
+}
+
+// EffectivePrice returns the price a buyer pays for one item at the given time
+func (p Product) EffectivePrice(now time.Time) money.Money {
+	if p.IsOnSale(now) {
+		return money.New(*p.SalePrice, p.Currency)
+	}
+
+	return p.OriginalPrice()
 }
 
 type ProductTag struct {
@@ -66,11 +191,163 @@ type ProductTag struct {
 	Tag       string `db:"tag"`
 }
 
+type ListTrendingProductsRequest struct {
+	Tag       string `query:"tag"`
+	Condition string `query:"condition" validate:"omitempty,oneof=new second"`
+	Limit     int    `query:"limit" validate:"omitempty,gte=1,lte=50"`
+}
+
+type ListFavouritesRequest struct {
+	Limit  int `query:"limit"`
+	Offset int `query:"offset"`
+}
+
+type ListOrdersRequest struct {
+	// As selects whose orders are listed: the ones bought by the user (buyer) or sold by the user (seller)
+	As     string `query:"as" validate:"omitempty,oneof=buyer seller"`
+	Limit  int    `query:"limit"`
+	Offset int    `query:"offset"`
+}
+
 type Order struct {
-	ID                   string `db:"id"`
-	UserID               string `db:"user_id"`
-	ProductID            string `db:"product_id"`
-	BankAccountID        string `db:"bank_account_id"`
-	PaymentProofImageURL string `db:"payment_proof_image_url"`
-	Quantity             int    `db:"quantity"`
+	ID                   string  `db:"id"`
+	UserID               string  `db:"user_id"`
+	SellerID             string  `db:"seller_id"`
+	ProductID            string  `db:"product_id"`
+	BankAccountID        string  `db:"bank_account_id"`
+	PaymentProofImageURL string  `db:"payment_proof_image_url"`
+	Quantity             int     `db:"quantity"`
+	CouponID             *string `db:"coupon_id"`
+	CouponCode           *string `db:"coupon_code"`
+	Status               string  `db:"status"`
+	// PaymentDeadline is when an unpaid order gets cancelled, empty for orders paid up front
+	PaymentDeadline *time.Time `db:"payment_deadline"`
+
+	// snapshot of the seller's bank account, so the buyer still knows where to pay if it's edited or deleted
+	BankCode          *string `db:"bank_code"`
+	BankName          string  `db:"bank_name"`
+	BankAccountName   string  `db:"bank_account_name"`
+	BankAccountNumber string  `db:"bank_account_number"`
+
+	// snapshot of the product at the time of purchase, so later product edits don't rewrite history
+	ProductName      string `db:"product_name"`
+	ProductImageURL  string `db:"product_image_url"`
+	ProductCondition string `db:"product_condition"`
+
+	// snapshot of the buyer's address, empty for orders made before addresses existed
+	ShippingRecipientName *string `db:"shipping_recipient_name"`
+	ShippingPhone         *string `db:"shipping_phone"`
+	ShippingAddressLine   *string `db:"shipping_address_line"`
+	ShippingCity          *string `db:"shipping_city"`
+	ShippingProvince      *string `db:"shipping_province"`
+	ShippingPostalCode    *string `db:"shipping_postal_code"`
+	ShippingCountry       *string `db:"shipping_country"`
+
+	// the shipping service the buyer chose, empty when the seller doesn't charge for shipping
+	ShippingService *string `db:"shipping_service"`
+
+	// set by the seller once the order is shipped
+	Courier        *string    `db:"courier"`
+	TrackingNumber *string    `db:"tracking_number"`
+	ShippedAt      *time.Time `db:"shipped_at"`
+
+	// set once the buyer confirms receiving the order
+	CompletedAt *time.Time `db:"completed_at"`
+
+	// amounts are in the currency's minor units
+	Currency       money.Currency `db:"currency"`
+	UnitPrice      int64          `db:"unit_price"`
+	Subtotal       int64          `db:"subtotal"`
+	DiscountAmount int64          `db:"discount_amount"`
+	ShippingCost   int64          `db:"shipping_cost"`
+	Total          int64          `db:"total"`
+	RefundedAmount int64          `db:"refunded_amount"`
+
+	// the platform's commission on the items, worked out with the rule in force when the order was made
+	CommissionRuleID          *string `db:"commission_rule_id"`
+	CommissionRateBasisPoints int64   `db:"commission_rate_basis_points"`
+	CommissionAmount          int64   `db:"commission_amount"`
+
+	// TaxAmount is the sum of the order's taxes, inclusive or not, whose lines are loaded into TaxLines
+	TaxAmount int64      `db:"tax_amount"`
+	TaxLines  []tax.Line `db:"-"`
+
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// CommissionOn returns the order's commission on amount out of its total, e.g. the commission
+// the platform gives back for a partial refund
+func (o Order) CommissionOn(amount int64) money.Money {
+	if o.Total == 0 {
+		return money.New(0, o.Currency)
+	}
+
+	return money.New(o.CommissionAmount, o.Currency).Fraction(amount, o.Total)
+}
+
+type OpenDisputeRequest struct {
+	Reason string `json:"reason" validate:"oneof=not_received not_as_described damaged other"`
+	DisputeMessageRequest
+}
+
+type DisputeMessageRequest struct {
+	Message string `json:"message" validate:"required,max=2000"`
+	// EvidenceImageURLs are images uploaded beforehand through the image upload endpoint
+	EvidenceImageURLs []string `json:"evidenceImageUrls" validate:"max=5,dive,url"`
+}
+
+type RefundProposalRequest struct {
+	// Amount is in the order currency's minor units, up to the order's total
+	Amount       int64 `json:"amount" validate:"required,gt=0"`
+	RestoreStock bool  `json:"restoreStock"`
+}
+
+type ResolveDisputeRequest struct {
+	// RefundAmount is in the order currency's minor units, 0 rejects the dispute
+	RefundAmount int64  `json:"refundAmount" validate:"gte=0"`
+	RestoreStock bool   `json:"restoreStock"`
+	Note         string `json:"note" validate:"required,max=2000"`
+}
+
+type ListDisputesRequest struct {
+	Status string `query:"status" validate:"omitempty,oneof=open resolved"`
+	Limit  int    `query:"limit"`
+	Offset int    `query:"offset"`
+}
+
+// Dispute is a buyer's complaint about an order. Either party can propose a refund, which
+// resolves the dispute once the other party accepts it, unless an admin resolves it first.
+type Dispute struct {
+	ID                   string     `db:"id"`
+	OrderID              string     `db:"order_id"`
+	BuyerID              string     `db:"buyer_id"`
+	SellerID             string     `db:"seller_id"`
+	Reason               string     `db:"reason"`
+	Status               string     `db:"status"`
+	ProposedRefundAmount *int64     `db:"proposed_refund_amount"`
+	ProposedRestoreStock bool       `db:"proposed_restore_stock"`
+	ProposedBy           *string    `db:"proposed_by"`
+	Resolution           *string    `db:"resolution"`
+	ResolvedBy           *string    `db:"resolved_by"`
+	CreatedAt            time.Time  `db:"created_at"`
+	ResolvedAt           *time.Time `db:"resolved_at"`
+}
+
+type DisputeMessage struct {
+	ID                string         `db:"id"`
+	DisputeID         string         `db:"dispute_id"`
+	SenderID          string         `db:"sender_id"`
+	Message           string         `db:"message"`
+	EvidenceImageURLs pq.StringArray `db:"evidence_image_urls"`
+	CreatedAt         time.Time      `db:"created_at"`
+}
+
+type Refund struct {
+	ID               string         `db:"id"`
+	OrderID          string         `db:"order_id"`
+	DisputeID        string         `db:"dispute_id"`
+	Amount           int64          `db:"amount"`
+	Currency         money.Currency `db:"currency"`
+	RestoredQuantity int            `db:"restored_quantity"`
+	CreatedAt        time.Time      `db:"created_at"`
 }
@@ -0,0 +1,66 @@
+package product
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/notification"
+	"github.com/google/uuid"
+)
+
+// enqueueProductChangeEvents writes outbox events for the changes between before and after that watchers
+// should be notified of: a lower effective price, or stock coming back. It must be called in the same
+// transaction as the update, so the events are only sent if the update is committed.
+func enqueueProductChangeEvents(ctx context.Context, tx *sql.Tx, before, after Product) error {
+	now := time.Now()
+
+	// nobody can buy the product anyway, so there's nothing worth notifying about
+	if !after.IsPublished(now) || !after.IsPurchasable {
+		return nil
+	}
+
+	oldPrice := before.EffectivePrice(now)
+	newPrice := after.EffectivePrice(now)
+
+	eventTypes := []string{}
+	if oldPrice.Currency == newPrice.Currency && newPrice.Amount < oldPrice.Amount && after.Stock > 0 {
+		eventTypes = append(eventTypes, notification.EventTypePriceDrop)
+	}
+	if before.Stock == 0 && after.Stock > 0 {
+		eventTypes = append(eventTypes, notification.EventTypeBackInStock)
+	}
+
+	if len(eventTypes) == 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(notification.ProductChangePayload{
+		ProductName: after.Name,
+		Currency:    string(newPrice.Currency),
+		OldPrice:    oldPrice.Amount,
+		NewPrice:    newPrice.Amount,
+		Stock:       after.Stock,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, eventType := range eventTypes {
+		err = NotificationRepoImpl.CreateOutboxEvent(ctx, tx, notification.OutboxEvent{
+			ID:            uuid.NewString(),
+			EventType:     eventType,
+			ProductID:     after.ID,
+			SellerID:      after.UserID,
+			Payload:       payload,
+			Status:        notification.OutboxStatusPending,
+			NextAttemptAt: now,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
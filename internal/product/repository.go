@@ -5,11 +5,21 @@ import (
 	"database/sql"
 	"fmt"
 	"strings"
+	"time"
 
+	"github.com/ahmadnaufal/openidea-shopifyx/pkg/money"
 	"github.com/jmoiron/sqlx"
 	"github.com/pkg/errors"
 )
 
+// visibleProductCondition filters products down to the ones that are currently published:
+// active, and within the publishAt/unpublishAt schedule if one is set
+const visibleProductCondition = `(
+	p.status = 'active'
+	AND (p.publish_at IS NULL OR p.publish_at <= NOW())
+	AND (p.unpublish_at IS NULL OR p.unpublish_at > NOW())
+)`
+
 type ProductRepo struct {
 	db *sqlx.DB
 }
@@ -26,10 +36,16 @@ func (r ProductRepo) CreateProduct(ctx context.Context, tx *sql.Tx, product Prod
 				user_id,
 				name,
 				price,
+				currency,
 				image_url,
 				stock,
+				weight_grams,
+				category,
 				condition,
-				is_purchasable
+				is_purchasable,
+				status,
+				publish_at,
+				unpublish_at
 			)
 		VALUES
 			(
@@ -37,10 +53,16 @@ func (r ProductRepo) CreateProduct(ctx context.Context, tx *sql.Tx, product Prod
 				:user_id,
 				:name,
 				:price,
+				:currency,
 				:image_url,
 				:stock,
+				:weight_grams,
+				:category,
 				:condition,
-				:is_purchasable
+				:is_purchasable,
+				:status,
+				:publish_at,
+				:unpublish_at
 			)
 	`
 
@@ -101,9 +123,54 @@ func (r ProductRepo) UpdateProduct(ctx context.Context, tx *sql.Tx, product Prod
 		SET
 			name = :name,
 			price = :price,
+			currency = :currency,
 			image_url = :image_url,
 			condition = :condition,
+			weight_grams = :weight_grams,
+			category = :category,
 			is_purchasable = :is_purchasable,
+			status = :status,
+			publish_at = :publish_at,
+			unpublish_at = :unpublish_at,
+			updated_at = NOW()
+		WHERE
+			id = :id
+			AND deleted_at IS NULL
+	`
+
+	updatedQuery, args, err := sqlx.Named(query, product)
+	if err != nil {
+		return err
+	}
+
+	var result sql.Result
+	if tx != nil {
+		result, err = tx.ExecContext(ctx, sqlx.Rebind(sqlx.DOLLAR, updatedQuery), args...)
+	} else {
+		result, err = r.db.ExecContext(ctx, sqlx.Rebind(sqlx.DOLLAR, updatedQuery), args...)
+	}
+	if err != nil {
+		return err
+	}
+
+	affectedRows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affectedRows != 1 {
+		return errors.New("error affected row count is not equal to 1")
+	}
+
+	return nil
+}
+
+func (r ProductRepo) UpdateProductSale(ctx context.Context, tx *sql.Tx, product Product) error {
+	query := `
+		UPDATE products
+		SET
+			sale_price = :sale_price,
+			sale_starts_at = :sale_starts_at,
+			sale_ends_at = :sale_ends_at,
 			updated_at = NOW()
 		WHERE
 			id = :id
@@ -145,10 +212,19 @@ func (r ProductRepo) GetProductByID(ctx context.Context, id string) (Product, er
 			user_id,
 			name,
 			price,
+			currency,
 			image_url,
 			stock,
+			weight_grams,
+			category,
 			condition,
-			is_purchasable
+			is_purchasable,
+			status,
+			publish_at,
+			unpublish_at,
+			sale_price,
+			sale_starts_at,
+			sale_ends_at
 		FROM
 			products
 		WHERE
@@ -165,6 +241,124 @@ func (r ProductRepo) GetProductByID(ctx context.Context, id string) (Product, er
 	return result, nil
 }
 
+// GetVisibleProductByID fetches a product only if it is published, unless viewerID is the owner of the product
+func (r ProductRepo) GetVisibleProductByID(ctx context.Context, id, viewerID string) (Product, error) {
+	var result Product
+
+	query := fmt.Sprintf(`
+		SELECT
+			p.id,
+			p.user_id,
+			p.name,
+			p.price,
+			p.currency,
+			p.image_url,
+			p.stock,
+			p.weight_grams,
+			p.category,
+			p.condition,
+			p.is_purchasable,
+			p.status,
+			p.publish_at,
+			p.unpublish_at,
+			p.sale_price,
+			p.sale_starts_at,
+			p.sale_ends_at
+		FROM
+			products p
+		WHERE
+			p.id = $1
+			AND p.deleted_at IS NULL
+			AND (p.user_id = $2 OR %s)
+		LIMIT 1
+	`, visibleProductCondition)
+
+	err := r.db.GetContext(ctx, &result, query, id, viewerID)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// GetVisibleProductIDs keeps the products the viewer is allowed to see, with the same check as GetVisibleProductByID
+func (r ProductRepo) GetVisibleProductIDs(ctx context.Context, ids []string, viewerID string) ([]string, error) {
+	result := []string{}
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	query := fmt.Sprintf(`
+		SELECT
+			p.id
+		FROM
+			products p
+		WHERE
+			p.id IN (?)
+			AND p.deleted_at IS NULL
+			AND (p.user_id = ? OR %s)
+	`, visibleProductCondition)
+
+	updatedQuery, args, err := sqlx.In(query, ids, viewerID)
+	if err != nil {
+		return result, err
+	}
+
+	err = r.db.SelectContext(ctx, &result, sqlx.Rebind(sqlx.DOLLAR, updatedQuery), args...)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r ProductRepo) GetProductsByIDs(ctx context.Context, ids []string) (map[string]Product, error) {
+	var result []Product
+
+	query := `
+		SELECT
+			id,
+			user_id,
+			name,
+			price,
+			currency,
+			image_url,
+			stock,
+			weight_grams,
+			category,
+			condition,
+			is_purchasable,
+			status,
+			publish_at,
+			unpublish_at,
+			sale_price,
+			sale_starts_at,
+			sale_ends_at
+		FROM
+			products
+		WHERE
+			id IN (?)
+			AND deleted_at IS NULL
+	`
+
+	updatedQuery, args, err := sqlx.In(query, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.db.SelectContext(ctx, &result, r.db.Rebind(updatedQuery), args...)
+	if err != nil {
+		return nil, err
+	}
+
+	productMap := map[string]Product{}
+	for _, product := range result {
+		productMap[product.ID] = product
+	}
+
+	return productMap, nil
+}
+
 func (r ProductRepo) BulkGetProductTags(ctx context.Context, productIDs []string) (map[string][]ProductTag, error) {
 	var result []ProductTag
 
@@ -191,14 +385,61 @@ func (r ProductRepo) BulkGetProductTags(ctx context.Context, productIDs []string
 		return nil, err
 	}
 
-	// group the fetched tags by each of product IDs
+	return groupProductTags(result), nil
+}
+
+// BulkGetProductTagsInTx reads the products' tags in the transaction, so tags written by
+// it, or by others that held the products' locks before it, are seen
+func (r ProductRepo) BulkGetProductTagsInTx(ctx context.Context, tx *sql.Tx, productIDs []string) (map[string][]ProductTag, error) {
+	query := `
+		SELECT
+			id,
+			product_id,
+			tag
+		FROM
+			product_tags
+		WHERE
+			product_id IN (?)
+		ORDER BY
+			product_id ASC, tag ASC
+	`
+
+	updatedQuery, args, err := sqlx.In(query, productIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.QueryContext(ctx, sqlx.Rebind(sqlx.DOLLAR, updatedQuery), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []ProductTag{}
+	for rows.Next() {
+		var tag ProductTag
+		err = rows.Scan(&tag.ID, &tag.ProductID, &tag.Tag)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, tag)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return groupProductTags(result), nil
+}
+
+// groupProductTags groups the fetched tags by each of product IDs
+func groupProductTags(tags []ProductTag) map[string][]ProductTag {
 	productIDToTagMap := map[string][]ProductTag{}
-	for _, tag := range result {
+	for _, tag := range tags {
 		productID := tag.ProductID
 		productIDToTagMap[productID] = append(productIDToTagMap[productID], tag)
 	}
 
-	return productIDToTagMap, nil
+	return productIDToTagMap
 }
 
 func (r ProductRepo) DeleteProductTags(ctx context.Context, tx *sql.Tx, tags []ProductTag) error {
@@ -227,15 +468,24 @@ func (r ProductRepo) DeleteProductTags(ctx context.Context, tx *sql.Tx, tags []P
 	return nil
 }
 
-func (r ProductRepo) DeleteProductTagsByProductID(ctx context.Context, tx *sql.Tx, productID string) error {
+func (r ProductRepo) DeleteProduct(ctx context.Context, tx *sql.Tx, productID string) error {
 	query := `
-		DELETE FROM
-			product_tags
+		UPDATE
+			products
+		SET
+			updated_at = NOW(),
+			deleted_at = NOW()
 		WHERE
-			product_id = $1
+			id = $1
+			AND deleted_at IS NULL
 	`
 
-	_, err := tx.ExecContext(ctx, query, productID)
+	var err error
+	if tx != nil {
+		_, err = tx.ExecContext(ctx, query, productID)
+	} else {
+		_, err = r.db.ExecContext(ctx, query, productID)
+	}
 	if err != nil {
 		return err
 	}
@@ -243,25 +493,197 @@ func (r ProductRepo) DeleteProductTagsByProductID(ctx context.Context, tx *sql.T
 	return nil
 }
 
-func (r ProductRepo) DeleteProduct(ctx context.Context, tx *sql.Tx, productID string) error {
+func (r ProductRepo) GetDeletedProductByID(ctx context.Context, id string) (Product, error) {
+	var result Product
+
+	query := `
+		SELECT
+			id,
+			user_id,
+			name,
+			price,
+			currency,
+			image_url,
+			stock,
+			weight_grams,
+			category,
+			condition,
+			is_purchasable,
+			status,
+			publish_at,
+			unpublish_at,
+			sale_price,
+			sale_starts_at,
+			sale_ends_at,
+			deleted_at
+		FROM
+			products
+		WHERE
+			id = $1
+			AND deleted_at IS NOT NULL
+		LIMIT 1
+	`
+
+	err := r.db.GetContext(ctx, &result, query, id)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r ProductRepo) ListDeletedProductsByUserID(ctx context.Context, userID string, deletedAfter time.Time) ([]Product, error) {
+	var result []Product
+
+	query := `
+		SELECT
+			id,
+			user_id,
+			name,
+			price,
+			currency,
+			image_url,
+			stock,
+			weight_grams,
+			category,
+			condition,
+			is_purchasable,
+			status,
+			publish_at,
+			unpublish_at,
+			sale_price,
+			sale_starts_at,
+			sale_ends_at,
+			created_at,
+			deleted_at
+		FROM
+			products
+		WHERE
+			user_id = $1
+			AND deleted_at IS NOT NULL
+			AND deleted_at > $2
+		ORDER BY
+			deleted_at DESC
+	`
+
+	err := r.db.SelectContext(ctx, &result, query, userID, deletedAfter)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r ProductRepo) RestoreProduct(ctx context.Context, tx *sql.Tx, productID string, deletedAfter time.Time) error {
 	query := `
 		UPDATE
 			products
 		SET
 			updated_at = NOW(),
-			deleted_at = NOW()
+			deleted_at = NULL
 		WHERE
 			id = $1
+			AND deleted_at IS NOT NULL
+			AND deleted_at > $2
 	`
 
-	_, err := tx.ExecContext(ctx, query, productID)
+	var (
+		result sql.Result
+		err    error
+	)
+
+	if tx != nil {
+		result, err = tx.ExecContext(ctx, query, productID, deletedAfter)
+	} else {
+		result, err = r.db.ExecContext(ctx, query, productID, deletedAfter)
+	}
 	if err != nil {
 		return err
 	}
 
+	affectedRows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affectedRows != 1 {
+		return errors.New("error affected row count is not equal to 1")
+	}
+
 	return nil
 }
 
+// PurgeDeletedProducts hard-deletes up to limit products (and the tags, favourites, views and conversations about them) that were soft-deleted
+// before deletedBefore. Products that are still referenced by orders are kept.
+func (r ProductRepo) PurgeDeletedProducts(ctx context.Context, tx *sql.Tx, deletedBefore time.Time, limit int) (int, error) {
+	var productIDs []string
+
+	// lock the rows first so a concurrent restore can't sneak in between deleting tags and products
+	selectQuery := `
+		SELECT
+			p.id
+		FROM
+			products p
+		WHERE
+			p.deleted_at IS NOT NULL
+			AND p.deleted_at < $1
+			AND NOT EXISTS (
+				SELECT 1 FROM orders o WHERE o.product_id = p.id
+			)
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED
+	`
+
+	rows, err := tx.QueryContext(ctx, selectQuery, deletedBefore, limit)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var productID string
+		if err := rows.Scan(&productID); err != nil {
+			return 0, err
+		}
+		productIDs = append(productIDs, productID)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	if len(productIDs) == 0 {
+		return 0, nil
+	}
+
+	// the rows about the products have no foreign keys to cascade, so they are cleaned up here. Notifications
+	// the users already got are kept, only without the product.
+	cleanupQueries := []string{
+		`DELETE FROM product_tags WHERE product_id IN (?)`,
+		`DELETE FROM favourites WHERE product_id IN (?)`,
+		`DELETE FROM product_subscriptions WHERE product_id IN (?)`,
+		`DELETE FROM product_daily_views WHERE product_id IN (?)`,
+		`DELETE FROM product_view_dedups WHERE product_id IN (?)`,
+		`DELETE FROM conversation_messages WHERE conversation_id IN (SELECT id FROM conversations WHERE product_id IN (?))`,
+		`DELETE FROM conversations WHERE product_id IN (?)`,
+		`DELETE FROM notification_outbox WHERE product_id IN (?)`,
+		`UPDATE notifications SET product_id = NULL WHERE product_id IN (?)`,
+		`DELETE FROM products WHERE id IN (?)`,
+	}
+
+	for _, query := range cleanupQueries {
+		updatedQuery, args, err := sqlx.In(query, productIDs)
+		if err != nil {
+			return 0, err
+		}
+
+		_, err = tx.ExecContext(ctx, sqlx.Rebind(sqlx.DOLLAR, updatedQuery), args...)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(productIDs), nil
+}
+
 func (r ProductRepo) UpdateProductStock(ctx context.Context, tx *sql.Tx, productID string, updatedStock int) error {
 	query := `
 		UPDATE products
@@ -306,10 +728,19 @@ func (r ProductRepo) ListProducts(ctx context.Context, req ListProductsRequest)
 			p.user_id,
 			p.name,
 			p.price,
+			p.currency,
 			p.image_url,
 			p.stock,
+			p.weight_grams,
+			p.category,
 			p.condition,
 			p.is_purchasable,
+			p.status,
+			p.publish_at,
+			p.unpublish_at,
+			p.sale_price,
+			p.sale_starts_at,
+			p.sale_ends_at,
 			p.created_at
 		FROM
 			products p
@@ -357,6 +788,14 @@ func getFilter(req ListProductsRequest) (string, []interface{}) {
 		args = append(args, req.UserID)
 	}
 
+	// unpublished products are only visible to their owner
+	if req.ViewerID != "" {
+		filter += fmt.Sprintf(" AND (p.user_id = ? OR %s)", visibleProductCondition)
+		args = append(args, req.ViewerID)
+	} else {
+		filter += fmt.Sprintf(" AND %s", visibleProductCondition)
+	}
+
 	// tags, bit complex
 	if len(req.Tags) > 0 {
 		placeholders := []string{}
@@ -376,6 +815,16 @@ func getFilter(req ListProductsRequest) (string, []interface{}) {
 		filter += " AND p.stock > 0"
 	}
 
+	// price filters are in minor units, so they only make sense within a single currency
+	currency := req.Currency
+	if currency == "" && (req.MaxPrice > 0 || req.MinPrice > 0) {
+		currency = string(money.DefaultCurrency)
+	}
+	if currency != "" {
+		filter += " AND p.currency = ?"
+		args = append(args, currency)
+	}
+
 	if req.MaxPrice > 0 {
 		filter += " AND p.price <= ?"
 		args = append(args, req.MaxPrice)
@@ -431,3 +880,134 @@ func getLimitAndOffset(req ListProductsRequest) (string, []interface{}) {
 
 	return query, args
 }
+
+// LockPurchasableProductsByUserID locks the user's products that can currently be bought
+func (r ProductRepo) LockPurchasableProductsByUserID(ctx context.Context, tx *sql.Tx, userID string) ([]Product, error) {
+	query := `
+		SELECT
+			id,
+			user_id,
+			name,
+			price,
+			currency,
+			image_url,
+			stock,
+			weight_grams,
+			category,
+			condition,
+			is_purchasable,
+			status,
+			publish_at,
+			unpublish_at,
+			sale_price,
+			sale_starts_at,
+			sale_ends_at
+		FROM
+			products
+		WHERE
+			user_id = $1
+			AND is_purchasable = TRUE
+			AND deleted_at IS NULL
+		ORDER BY
+			id
+		FOR UPDATE
+	`
+
+	rows, err := tx.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanProducts(rows)
+}
+
+// LockProductsByIDs locks the products for the rest of the transaction and returns them as they are now,
+// so changes made in the meantime aren't written over with stale data
+func (r ProductRepo) LockProductsByIDs(ctx context.Context, tx *sql.Tx, ids []string) (map[string]Product, error) {
+	query := `
+		SELECT
+			id,
+			user_id,
+			name,
+			price,
+			currency,
+			image_url,
+			stock,
+			weight_grams,
+			category,
+			condition,
+			is_purchasable,
+			status,
+			publish_at,
+			unpublish_at,
+			sale_price,
+			sale_starts_at,
+			sale_ends_at
+		FROM
+			products
+		WHERE
+			id IN (?)
+			AND deleted_at IS NULL
+		ORDER BY
+			id
+		FOR UPDATE
+	`
+
+	updatedQuery, args, err := sqlx.In(query, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.QueryContext(ctx, sqlx.Rebind(sqlx.DOLLAR, updatedQuery), args...)
+	if err != nil {
+		return nil, err
+	}
+
+	products, err := scanProducts(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	productMap := map[string]Product{}
+	for _, product := range products {
+		productMap[product.ID] = product
+	}
+
+	return productMap, nil
+}
+
+// scanProducts reads the rows of a query selecting the same columns as GetProductByID
+func scanProducts(rows *sql.Rows) ([]Product, error) {
+	defer rows.Close()
+
+	products := []Product{}
+	for rows.Next() {
+		var product Product
+		err := rows.Scan(
+			&product.ID,
+			&product.UserID,
+			&product.Name,
+			&product.Price,
+			&product.Currency,
+			&product.ImageURL,
+			&product.Stock,
+			&product.WeightGrams,
+			&product.Category,
+			&product.Condition,
+			&product.IsPurchasable,
+			&product.Status,
+			&product.PublishAt,
+			&product.UnpublishAt,
+			&product.SalePrice,
+			&product.SaleStartsAt,
+			&product.SaleEndsAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		products = append(products, product)
+	}
+
+	return products, rows.Err()
+}
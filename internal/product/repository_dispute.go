@@ -0,0 +1,422 @@
+package product
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+// CreateDispute opens a dispute on the order. An order can only be disputed once, so it returns
+// errDisputeExists if the order already has one.
+func (r ProductRepo) CreateDispute(ctx context.Context, tx *sql.Tx, dispute Dispute) error {
+	query := `
+		INSERT INTO disputes
+			(
+				id,
+				order_id,
+				buyer_id,
+				seller_id,
+				reason,
+				status,
+				created_at
+			)
+		VALUES
+			(
+				:id,
+				:order_id,
+				:buyer_id,
+				:seller_id,
+				:reason,
+				:status,
+				:created_at
+			)
+		ON CONFLICT (order_id) DO NOTHING
+	`
+
+	updatedQuery, args, err := sqlx.Named(query, dispute)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, sqlx.Rebind(sqlx.DOLLAR, updatedQuery), args...)
+	if err != nil {
+		return err
+	}
+
+	affectedRows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affectedRows != 1 {
+		return errDisputeExists
+	}
+
+	return nil
+}
+
+func (r ProductRepo) GetDisputeByOrderID(ctx context.Context, orderID string) (Dispute, error) {
+	var result Dispute
+
+	query := `
+		SELECT
+			id,
+			order_id,
+			buyer_id,
+			seller_id,
+			reason,
+			status,
+			proposed_refund_amount,
+			proposed_restore_stock,
+			proposed_by,
+			resolution,
+			resolved_by,
+			created_at,
+			resolved_at
+		FROM
+			disputes
+		WHERE
+			order_id = $1
+		LIMIT 1
+	`
+
+	err := r.db.GetContext(ctx, &result, query, orderID)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r ProductRepo) GetDisputeByID(ctx context.Context, disputeID string) (Dispute, error) {
+	var result Dispute
+
+	query := `
+		SELECT
+			id,
+			order_id,
+			buyer_id,
+			seller_id,
+			reason,
+			status,
+			proposed_refund_amount,
+			proposed_restore_stock,
+			proposed_by,
+			resolution,
+			resolved_by,
+			created_at,
+			resolved_at
+		FROM
+			disputes
+		WHERE
+			id = $1
+		LIMIT 1
+	`
+
+	err := r.db.GetContext(ctx, &result, query, disputeID)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r ProductRepo) ListDisputes(ctx context.Context, req ListDisputesRequest) ([]Dispute, int, error) {
+	var disputes []Dispute
+
+	conditions := "TRUE"
+	args := []interface{}{}
+	if req.Status != "" {
+		conditions = "status = $1"
+		args = append(args, req.Status)
+	}
+
+	var count int
+	err := r.db.GetContext(ctx, &count, fmt.Sprintf(`SELECT COUNT(*) FROM disputes WHERE %s`, conditions), args...)
+	if err != nil {
+		return disputes, count, err
+	}
+
+	limit := req.Limit
+	if limit <= 0 {
+		limit = 10
+	}
+
+	offset := req.Offset
+	if offset < 0 {
+		offset = 0
+	}
+
+	query := fmt.Sprintf(`
+		SELECT
+			id,
+			order_id,
+			buyer_id,
+			seller_id,
+			reason,
+			status,
+			proposed_refund_amount,
+			proposed_restore_stock,
+			proposed_by,
+			resolution,
+			resolved_by,
+			created_at,
+			resolved_at
+		FROM
+			disputes
+		WHERE
+			%s
+		ORDER BY
+			created_at ASC
+		LIMIT $%d OFFSET $%d
+	`, conditions, len(args)+1, len(args)+2)
+
+	err = r.db.SelectContext(ctx, &disputes, query, append(args, limit, offset)...)
+	if err != nil {
+		return disputes, count, err
+	}
+
+	return disputes, count, nil
+}
+
+// ProposeRefund replaces the dispute's refund proposal, as long as the dispute is still open
+func (r ProductRepo) ProposeRefund(ctx context.Context, tx *sql.Tx, disputeID string, amount int64, restoreStock bool, proposedBy string) error {
+	query := `
+		UPDATE disputes
+		SET
+			proposed_refund_amount = $1,
+			proposed_restore_stock = $2,
+			proposed_by = $3
+		WHERE
+			id = $4
+			AND status = $5
+	`
+
+	result, err := tx.ExecContext(ctx, query, amount, restoreStock, proposedBy, disputeID, DisputeStatusOpen)
+	if err != nil {
+		return err
+	}
+
+	affectedRows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affectedRows != 1 {
+		return errDisputeNotOpen
+	}
+
+	return nil
+}
+
+// AcceptRefundProposal resolves the dispute with its refund proposal. The proposal is matched
+// against the one the accepting party saw, so a proposal changed in between isn't accepted.
+func (r ProductRepo) AcceptRefundProposal(ctx context.Context, tx *sql.Tx, dispute Dispute, resolvedBy string, resolvedAt time.Time) error {
+	query := `
+		UPDATE disputes
+		SET
+			status = $1,
+			resolution = $2,
+			resolved_by = $3,
+			resolved_at = $4
+		WHERE
+			id = $5
+			AND status = $6
+			AND proposed_by = $7
+			AND proposed_refund_amount = $8
+			AND proposed_restore_stock = $9
+	`
+
+	result, err := tx.ExecContext(ctx, query,
+		DisputeStatusResolved, DisputeResolutionRefunded, resolvedBy, resolvedAt,
+		dispute.ID, DisputeStatusOpen, dispute.ProposedBy, dispute.ProposedRefundAmount, dispute.ProposedRestoreStock,
+	)
+	if err != nil {
+		return err
+	}
+
+	affectedRows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affectedRows != 1 {
+		return errDisputeChanged
+	}
+
+	return nil
+}
+
+// ResolveDispute closes an open dispute with the given resolution, regardless of any proposal
+func (r ProductRepo) ResolveDispute(ctx context.Context, tx *sql.Tx, disputeID, resolution, resolvedBy string, resolvedAt time.Time) error {
+	query := `
+		UPDATE disputes
+		SET
+			status = $1,
+			resolution = $2,
+			resolved_by = $3,
+			resolved_at = $4
+		WHERE
+			id = $5
+			AND status = $6
+	`
+
+	result, err := tx.ExecContext(ctx, query, DisputeStatusResolved, resolution, resolvedBy, resolvedAt, disputeID, DisputeStatusOpen)
+	if err != nil {
+		return err
+	}
+
+	affectedRows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affectedRows != 1 {
+		return errDisputeNotOpen
+	}
+
+	return nil
+}
+
+func (r ProductRepo) CreateDisputeMessage(ctx context.Context, tx *sql.Tx, message DisputeMessage) error {
+	query := `
+		INSERT INTO dispute_messages
+			(
+				id,
+				dispute_id,
+				sender_id,
+				message,
+				evidence_image_urls,
+				created_at
+			)
+		VALUES
+			(
+				:id,
+				:dispute_id,
+				:sender_id,
+				:message,
+				:evidence_image_urls,
+				:created_at
+			)
+	`
+
+	updatedQuery, args, err := sqlx.Named(query, message)
+	if err != nil {
+		return err
+	}
+
+	if tx != nil {
+		_, err = tx.ExecContext(ctx, sqlx.Rebind(sqlx.DOLLAR, updatedQuery), args...)
+	} else {
+		_, err = r.db.ExecContext(ctx, sqlx.Rebind(sqlx.DOLLAR, updatedQuery), args...)
+	}
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r ProductRepo) ListDisputeMessages(ctx context.Context, disputeID string) ([]DisputeMessage, error) {
+	var messages []DisputeMessage
+
+	query := `
+		SELECT
+			id,
+			dispute_id,
+			sender_id,
+			message,
+			evidence_image_urls,
+			created_at
+		FROM
+			dispute_messages
+		WHERE
+			dispute_id = $1
+		ORDER BY
+			created_at ASC
+	`
+
+	err := r.db.SelectContext(ctx, &messages, query, disputeID)
+	if err != nil {
+		return messages, err
+	}
+
+	return messages, nil
+}
+
+func (r ProductRepo) CreateRefund(ctx context.Context, tx *sql.Tx, refund Refund) error {
+	query := `
+		INSERT INTO refunds
+			(
+				id,
+				order_id,
+				dispute_id,
+				amount,
+				currency,
+				restored_quantity,
+				created_at
+			)
+		VALUES
+			(
+				:id,
+				:order_id,
+				:dispute_id,
+				:amount,
+				:currency,
+				:restored_quantity,
+				:created_at
+			)
+	`
+
+	updatedQuery, args, err := sqlx.Named(query, refund)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, sqlx.Rebind(sqlx.DOLLAR, updatedQuery), args...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// AddOrderRefund adds the refund to the order's refunded amount, returning its refunded amount, status and
+// completion time after the refund. A completed order stays completed unless it is refunded in full, the
+// others are moved to partially_refunded or refunded. The status is decided here rather than by the caller,
+// so an order completed concurrently is never taken out of completed.
+func (r ProductRepo) AddOrderRefund(ctx context.Context, tx *sql.Tx, orderID string, amount int64) (Order, error) {
+	query := `
+		UPDATE orders
+		SET
+			refunded_amount = refunded_amount + $1,
+			status = CASE
+				WHEN refunded_amount + $1 = total THEN $2
+				WHEN completed_at IS NOT NULL THEN $3
+				ELSE $4
+			END
+		WHERE
+			id = $5
+			AND refunded_amount + $1 <= total
+		RETURNING
+			refunded_amount,
+			status,
+			completed_at
+	`
+
+	var result Order
+	row := tx.QueryRowContext(ctx, query, amount, OrderStatusRefunded, OrderStatusCompleted, OrderStatusPartiallyRefunded, orderID)
+	err := row.Scan(
+		&result.RefundedAmount,
+		&result.Status,
+		&result.CompletedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return result, errors.New("error affected row count is not equal to 1")
+		}
+		return result, err
+	}
+
+	return result, nil
+}
@@ -0,0 +1,187 @@
+package product
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+func (r ProductRepo) AddFavourite(ctx context.Context, userID, productID string) error {
+	query := `
+		INSERT INTO favourites
+			(user_id, product_id)
+		VALUES
+			($1, $2)
+		ON CONFLICT DO NOTHING
+	`
+
+	_, err := r.db.ExecContext(ctx, query, userID, productID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r ProductRepo) RemoveFavourite(ctx context.Context, userID, productID string) error {
+	query := `
+		DELETE FROM
+			favourites
+		WHERE
+			user_id = $1
+			AND product_id = $2
+	`
+
+	_, err := r.db.ExecContext(ctx, query, userID, productID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ListFavouritedProducts lists the products favourited by the user, most recently favourited first.
+// Products that were deleted or are no longer published are left out.
+func (r ProductRepo) ListFavouritedProducts(ctx context.Context, userID string, req ListFavouritesRequest) ([]Product, int, error) {
+	var products []Product
+
+	filter := fmt.Sprintf(`
+		f.user_id = $1
+		AND p.deleted_at IS NULL
+		AND (p.user_id = $1 OR %s)
+	`, visibleProductCondition)
+
+	countQuery := fmt.Sprintf(`
+		SELECT
+			COUNT(*)
+		FROM
+			favourites f
+			INNER JOIN products p
+			ON p.id = f.product_id
+		WHERE
+			%s
+	`, filter)
+
+	var count int
+	err := r.db.GetContext(ctx, &count, countQuery, userID)
+	if err != nil {
+		return products, count, err
+	}
+
+	limit := req.Limit
+	if limit <= 0 {
+		limit = 10
+	}
+
+	offset := req.Offset
+	if offset < 0 {
+		offset = 0
+	}
+
+	query := fmt.Sprintf(`
+		SELECT
+			p.id,
+			p.user_id,
+			p.name,
+			p.price,
+			p.currency,
+			p.image_url,
+			p.stock,
+			p.weight_grams,
+			p.category,
+			p.condition,
+			p.is_purchasable,
+			p.status,
+			p.publish_at,
+			p.unpublish_at,
+			p.sale_price,
+			p.sale_starts_at,
+			p.sale_ends_at,
+			p.created_at
+		FROM
+			favourites f
+			INNER JOIN products p
+			ON p.id = f.product_id
+		WHERE
+			%s
+		ORDER BY
+			f.created_at DESC
+		LIMIT $2 OFFSET $3
+	`, filter)
+
+	err = r.db.SelectContext(ctx, &products, query, userID, limit, offset)
+	if err != nil {
+		return products, count, err
+	}
+
+	return products, count, nil
+}
+
+// GetFavouritedProductIDs returns which of the products are favourited by the user
+func (r ProductRepo) GetFavouritedProductIDs(ctx context.Context, userID string, productIDs []string) (map[string]bool, error) {
+	query := `
+		SELECT
+			product_id
+		FROM
+			favourites
+		WHERE
+			user_id = ?
+			AND product_id IN (?)
+	`
+
+	updatedQuery, args, err := sqlx.In(query, userID, productIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var favouritedIDs []string
+	err = r.db.SelectContext(ctx, &favouritedIDs, sqlx.Rebind(sqlx.DOLLAR, updatedQuery), args...)
+	if err != nil {
+		return nil, err
+	}
+
+	mapRes := map[string]bool{}
+	for _, v := range favouritedIDs {
+		mapRes[v] = true
+	}
+
+	return mapRes, nil
+}
+
+type favouriteCountResult struct {
+	ProductID      string `db:"product_id"`
+	FavouriteCount int    `db:"favourite_count"`
+}
+
+func (r ProductRepo) GetFavouriteCountByProductIDs(ctx context.Context, productIDs []string) (map[string]int, error) {
+	query := `
+		SELECT
+			product_id,
+			COUNT(*) AS favourite_count
+		FROM
+			favourites
+		WHERE
+			product_id IN (?)
+		GROUP BY
+			product_id
+	`
+
+	updatedQuery, args, err := sqlx.In(query, productIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var favouriteCounts []favouriteCountResult
+	err = r.db.SelectContext(ctx, &favouriteCounts, sqlx.Rebind(sqlx.DOLLAR, updatedQuery), args...)
+	if err != nil {
+		return nil, err
+	}
+
+	mapRes := map[string]int{}
+	for _, v := range favouriteCounts {
+		mapRes[v.ProductID] = v.FavouriteCount
+	}
+
+	return mapRes, nil
+}
@@ -3,6 +3,8 @@ package product
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 )
@@ -13,19 +15,83 @@ func (r ProductRepo) CreateOrder(ctx context.Context, tx *sql.Tx, order Order) e
 			(
 				id,
 				user_id,
+				seller_id,
 				product_id,
 				bank_account_id,
+				bank_code,
+				bank_name,
+				bank_account_name,
+				bank_account_number,
 				payment_proof_image_url,
-				quantity
+				quantity,
+				coupon_id,
+				coupon_code,
+				status,
+				payment_deadline,
+				product_name,
+				product_image_url,
+				product_condition,
+				shipping_recipient_name,
+				shipping_phone,
+				shipping_address_line,
+				shipping_city,
+				shipping_province,
+				shipping_postal_code,
+				shipping_country,
+				shipping_service,
+				currency,
+				unit_price,
+				subtotal,
+				discount_amount,
+				shipping_cost,
+				total,
+				refunded_amount,
+				commission_rule_id,
+				commission_rate_basis_points,
+				commission_amount,
+				tax_amount,
+				created_at
 			)
 		VALUES
 			(
 				:id,
 				:user_id,
+				:seller_id,
 				:product_id,
 				:bank_account_id,
+				:bank_code,
+				:bank_name,
+				:bank_account_name,
+				:bank_account_number,
 				:payment_proof_image_url,
-				:quantity
+				:quantity,
+				:coupon_id,
+				:coupon_code,
+				:status,
+				:payment_deadline,
+				:product_name,
+				:product_image_url,
+				:product_condition,
+				:shipping_recipient_name,
+				:shipping_phone,
+				:shipping_address_line,
+				:shipping_city,
+				:shipping_province,
+				:shipping_postal_code,
+				:shipping_country,
+				:shipping_service,
+				:currency,
+				:unit_price,
+				:subtotal,
+				:discount_amount,
+				:shipping_cost,
+				:total,
+				:refunded_amount,
+				:commission_rule_id,
+				:commission_rate_basis_points,
+				:commission_amount,
+				:tax_amount,
+				:created_at
 			)
 	`
 
@@ -48,6 +114,155 @@ func (r ProductRepo) CreateOrder(ctx context.Context, tx *sql.Tx, order Order) e
 	return nil
 }
 
+func (r ProductRepo) GetOrderByID(ctx context.Context, orderID string) (Order, error) {
+	var result Order
+
+	query := `
+		SELECT
+			id,
+			user_id,
+			seller_id,
+			product_id,
+			bank_account_id,
+			bank_code,
+			bank_name,
+			bank_account_name,
+			bank_account_number,
+			payment_proof_image_url,
+			quantity,
+			coupon_id,
+			coupon_code,
+			status,
+			payment_deadline,
+			product_name,
+			product_image_url,
+			product_condition,
+			shipping_recipient_name,
+			shipping_phone,
+			shipping_address_line,
+			shipping_city,
+			shipping_province,
+			shipping_postal_code,
+			shipping_country,
+			shipping_service,
+			courier,
+			tracking_number,
+			shipped_at,
+			completed_at,
+			currency,
+			unit_price,
+			subtotal,
+			discount_amount,
+			shipping_cost,
+			total,
+			refunded_amount,
+			commission_rule_id,
+			commission_rate_basis_points,
+			commission_amount,
+			tax_amount,
+			created_at
+		FROM
+			orders
+		WHERE
+			id = $1
+		LIMIT 1
+	`
+
+	err := r.db.GetContext(ctx, &result, query, orderID)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r ProductRepo) ListOrders(ctx context.Context, userID string, req ListOrdersRequest) ([]Order, int, error) {
+	var orders []Order
+
+	userColumn := "user_id"
+	if req.As == "seller" {
+		userColumn = "seller_id"
+	}
+
+	countQuery := fmt.Sprintf(`SELECT COUNT(*) FROM orders WHERE %s = $1`, userColumn)
+
+	var count int
+	err := r.db.GetContext(ctx, &count, countQuery, userID)
+	if err != nil {
+		return orders, count, err
+	}
+
+	limit := req.Limit
+	if limit <= 0 {
+		limit = 10
+	}
+
+	offset := req.Offset
+	if offset < 0 {
+		offset = 0
+	}
+
+	query := fmt.Sprintf(`
+		SELECT
+			id,
+			user_id,
+			seller_id,
+			product_id,
+			bank_account_id,
+			bank_code,
+			bank_name,
+			bank_account_name,
+			bank_account_number,
+			payment_proof_image_url,
+			quantity,
+			coupon_id,
+			coupon_code,
+			status,
+			payment_deadline,
+			product_name,
+			product_image_url,
+			product_condition,
+			shipping_recipient_name,
+			shipping_phone,
+			shipping_address_line,
+			shipping_city,
+			shipping_province,
+			shipping_postal_code,
+			shipping_country,
+			shipping_service,
+			courier,
+			tracking_number,
+			shipped_at,
+			completed_at,
+			currency,
+			unit_price,
+			subtotal,
+			discount_amount,
+			shipping_cost,
+			total,
+			refunded_amount,
+			commission_rule_id,
+			commission_rate_basis_points,
+			commission_amount,
+			tax_amount,
+			created_at
+		FROM
+			orders
+		WHERE
+			%s = $1
+		ORDER BY
+			created_at DESC
+		LIMIT $2 OFFSET $3
+	`, userColumn)
+
+	err = r.db.SelectContext(ctx, &orders, query, userID, limit, offset)
+	if err != nil {
+		return orders, count, err
+	}
+
+	return orders, count, nil
+}
+
 type purchaseCountResult struct {
 	ProductID     string `db:"product_id"`
 	PurchaseCount int    `db:"purchase_count"`
@@ -62,11 +277,12 @@ func (r ProductRepo) GetPurchaseCountByProductIDs(ctx context.Context, productID
 			orders
 		WHERE
 			product_id IN (?)
+			AND status <> ?
 		GROUP BY
 			product_id
 	`
 
-	updatedQuery, args, err := sqlx.In(query, productIDs)
+	updatedQuery, args, err := sqlx.In(query, productIDs, OrderStatusCancelled)
 	if err != nil {
 		return nil, err
 	}
@@ -95,13 +311,231 @@ func (r ProductRepo) GetProductPurchasedByUserID(ctx context.Context, userID str
 			ON p.id = o.product_id
 		WHERE
 			p.user_id = $1
+			AND o.status <> $2
 	`
 
 	var count int
-	err := r.db.GetContext(ctx, &count, query, userID)
+	err := r.db.GetContext(ctx, &count, query, userID, OrderStatusCancelled)
 	if err != nil {
 		return count, err
 	}
 
 	return count, nil
 }
+
+// ShipOrder stores the order's shipment details. It fails with errOrderNotShippable if the order
+// can no longer be shipped, e.g. when it was shipped concurrently.
+func (r ProductRepo) ShipOrder(ctx context.Context, tx *sql.Tx, order Order) error {
+	query := `
+		UPDATE orders
+		SET
+			status = $1,
+			courier = $2,
+			tracking_number = $3,
+			shipped_at = $4
+		WHERE
+			id = $5
+			AND status IN ($6, $7)
+	`
+
+	result, err := tx.ExecContext(ctx, query, order.Status, order.Courier, order.TrackingNumber, order.ShippedAt, order.ID, OrderStatusPaid, OrderStatusPartiallyRefunded)
+	if err != nil {
+		return err
+	}
+
+	affectedRows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affectedRows != 1 {
+		return errOrderNotShippable
+	}
+
+	return nil
+}
+
+// CompleteOrder marks a shipped order as completed, returning what the seller is to be credited with as of
+// the update, so a refund made concurrently is never credited. It fails with errOrderNotCompletable if
+// the order isn't shipped, or has been completed or fully refunded already.
+func (r ProductRepo) CompleteOrder(ctx context.Context, tx *sql.Tx, orderID string, completedAt time.Time) (Order, error) {
+	query := `
+		UPDATE orders
+		SET
+			status = $1,
+			completed_at = $2
+		WHERE
+			id = $3
+			AND status IN ($4, $5)
+			AND shipped_at IS NOT NULL
+		RETURNING
+			seller_id,
+			currency,
+			total,
+			refunded_amount,
+			commission_amount
+	`
+
+	var result Order
+	row := tx.QueryRowContext(ctx, query, OrderStatusCompleted, completedAt, orderID, OrderStatusShipped, OrderStatusPartiallyRefunded)
+	err := row.Scan(
+		&result.SellerID,
+		&result.Currency,
+		&result.Total,
+		&result.RefundedAmount,
+		&result.CommissionAmount,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return result, errOrderNotCompletable
+		}
+		return result, err
+	}
+
+	return result, nil
+}
+
+// UpdateOrderStatus moves the order from one status to another, returning the order's parties. It returns
+// sql.ErrNoRows if the order isn't in the expected status anymore.
+func (r ProductRepo) UpdateOrderStatus(ctx context.Context, tx *sql.Tx, orderID, fromStatus, toStatus string) (Order, error) {
+	query := `
+		UPDATE orders
+		SET
+			status = $1
+		WHERE
+			id = $2
+			AND status = $3
+		RETURNING
+			id,
+			user_id,
+			seller_id,
+			product_id,
+			status
+	`
+
+	var result Order
+	row := tx.QueryRowContext(ctx, query, toStatus, orderID, fromStatus)
+	err := row.Scan(
+		&result.ID,
+		&result.UserID,
+		&result.SellerID,
+		&result.ProductID,
+		&result.Status,
+	)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// LockOrder locks the order until the transaction ends
+func (r ProductRepo) LockOrder(ctx context.Context, tx *sql.Tx, orderID string) error {
+	query := `
+		SELECT
+			id
+		FROM
+			orders
+		WHERE
+			id = $1
+		FOR UPDATE
+	`
+
+	var id string
+	return tx.QueryRowContext(ctx, query, orderID).Scan(&id)
+}
+
+// ClaimExpiredOrders locks a batch of unpaid orders past their payment deadline. Locked orders are
+// skipped, so a payment being settled at the same time is never cancelled from under it.
+func (r ProductRepo) ClaimExpiredOrders(ctx context.Context, tx *sql.Tx, now time.Time, limit int) ([]Order, error) {
+	query := `
+		SELECT
+			id,
+			user_id,
+			seller_id,
+			product_id,
+			quantity,
+			coupon_id,
+			status
+		FROM
+			orders
+		WHERE
+			status = $1
+			AND payment_deadline <= $2
+		ORDER BY
+			payment_deadline
+		LIMIT $3
+		FOR UPDATE SKIP LOCKED
+	`
+
+	rows, err := tx.QueryContext(ctx, query, OrderStatusPendingPayment, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orders := []Order{}
+	for rows.Next() {
+		var order Order
+		err = rows.Scan(
+			&order.ID,
+			&order.UserID,
+			&order.SellerID,
+			&order.ProductID,
+			&order.Quantity,
+			&order.CouponID,
+			&order.Status,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		orders = append(orders, order)
+	}
+
+	return orders, rows.Err()
+}
+
+// DecrementProductStock atomically takes stock out of a live product and returns the new stock.
+// It returns sql.ErrNoRows if the product doesn't have enough stock left.
+func (r ProductRepo) DecrementProductStock(ctx context.Context, tx *sql.Tx, productID string, quantity int) (int, error) {
+	query := `
+		UPDATE products
+		SET
+			stock = stock - $1
+		WHERE
+			id = $2
+			AND stock >= $1
+			AND deleted_at IS NULL
+		RETURNING
+			stock
+	`
+
+	var stock int
+	err := tx.QueryRowContext(ctx, query, quantity, productID).Scan(&stock)
+	if err != nil {
+		return stock, err
+	}
+
+	return stock, nil
+}
+
+// IncrementProductStock atomically returns stock to a product, even a deleted one, and returns the new stock
+func (r ProductRepo) IncrementProductStock(ctx context.Context, tx *sql.Tx, productID string, quantity int) (int, error) {
+	query := `
+		UPDATE products
+		SET
+			stock = stock + $1
+		WHERE
+			id = $2
+		RETURNING
+			stock
+	`
+
+	var stock int
+	err := tx.QueryRowContext(ctx, query, quantity, productID).Scan(&stock)
+	if err != nil {
+		return stock, err
+	}
+
+	return stock, nil
+}
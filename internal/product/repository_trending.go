@@ -0,0 +1,128 @@
+package product
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	// trendingWindowDays is how far back views and purchases are considered
+	trendingWindowDays = 30
+	// trendingHalfLifeDays is after how many days a view or purchase is worth half as much
+	trendingHalfLifeDays = 3.0
+	// trendingPurchaseWeight is how many views a purchased unit is worth
+	trendingPurchaseWeight = 10.0
+
+	defaultTrendingLimit = 20
+)
+
+type trendingProduct struct {
+	Product
+	Score float64 `db:"score"`
+}
+
+// ListTrendingProducts ranks the visible products by a time-decayed score of their views and purchases
+func (r ProductRepo) ListTrendingProducts(ctx context.Context, req ListTrendingProductsRequest, viewerID string) ([]Product, error) {
+	var result []trendingProduct
+
+	filter := ""
+	// the score CTE placeholders come first, in the order they appear in the query
+	args := []interface{}{trendingWindowDays, trendingPurchaseWeight, trendingWindowDays, trendingHalfLifeDays}
+
+	if viewerID != "" {
+		filter += fmt.Sprintf(" AND (p.user_id = ? OR %s)", visibleProductCondition)
+		args = append(args, viewerID)
+	} else {
+		filter += fmt.Sprintf(" AND %s", visibleProductCondition)
+	}
+
+	if req.Tag != "" {
+		filter += " AND EXISTS (SELECT 1 FROM product_tags pt WHERE pt.product_id = p.id AND pt.tag = ?)"
+		args = append(args, req.Tag)
+	}
+
+	if req.Condition != "" {
+		filter += " AND p.condition = ?"
+		args = append(args, req.Condition)
+	}
+
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultTrendingLimit
+	}
+	args = append(args, limit)
+
+	query := fmt.Sprintf(`
+		WITH activity AS (
+			SELECT
+				product_id,
+				day,
+				views::float AS weight
+			FROM
+				product_daily_views
+			WHERE
+				day > CURRENT_DATE - ?::int
+			UNION ALL
+			SELECT
+				product_id,
+				day,
+				units * ?::float AS weight
+			FROM
+				product_daily_sales
+			WHERE
+				day > CURRENT_DATE - ?::int
+		),
+		scores AS (
+			SELECT
+				product_id,
+				SUM(weight * POWER(0.5, (CURRENT_DATE - day) / ?::float)) AS score
+			FROM
+				activity
+			GROUP BY
+				product_id
+		)
+		SELECT
+			p.id,
+			p.user_id,
+			p.name,
+			p.price,
+			p.currency,
+			p.image_url,
+			p.stock,
+			p.weight_grams,
+			p.category,
+			p.condition,
+			p.is_purchasable,
+			p.status,
+			p.publish_at,
+			p.unpublish_at,
+			p.sale_price,
+			p.sale_starts_at,
+			p.sale_ends_at,
+			p.created_at,
+			s.score
+		FROM
+			products p
+			INNER JOIN scores s
+			ON s.product_id = p.id
+		WHERE
+			p.deleted_at IS NULL %s
+		ORDER BY
+			s.score DESC, p.created_at DESC
+		LIMIT ?
+	`, filter)
+
+	err := r.db.SelectContext(ctx, &result, sqlx.Rebind(sqlx.DOLLAR, query), args...)
+	if err != nil {
+		return nil, err
+	}
+
+	products := make([]Product, len(result))
+	for i, v := range result {
+		products[i] = v.Product
+	}
+
+	return products, nil
+}
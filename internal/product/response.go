@@ -1,15 +1,47 @@
 package product
 
+import (
+	"time"
+
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/payment"
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/tax"
+	"github.com/ahmadnaufal/openidea-shopifyx/pkg/money"
+)
+
 type ProductResponse struct {
-	ProductID     string   `json:"productId"`
-	Name          string   `json:"name"`
-	Price         int      `json:"price"`
-	ImageURL      string   `json:"imageUrl"`
-	Stock         int      `json:"stock"`
-	Condition     string   `json:"condition"`
-	Tags          []string `json:"tags"`
-	IsPurchasable bool     `json:"isPurchasable"`
-	PurchaseCount int      `json:"purchaseCount"`
+	ProductID string `json:"productId"`
+	Name      string `json:"name"`
+	// Price is the original price, EffectivePrice is the price after an active sale is applied
+	Price          money.Money          `json:"price"`
+	EffectivePrice money.Money          `json:"effectivePrice"`
+	Sale           *ProductSaleResponse `json:"sale,omitempty"`
+	ImageURL       string               `json:"imageUrl"`
+	Stock          int                  `json:"stock"`
+	WeightGrams    int                  `json:"weightGrams"`
+	Category       *string              `json:"category"`
+	Condition      string               `json:"condition"`
+	Tags           []string             `json:"tags"`
+	IsPurchasable  bool                 `json:"isPurchasable"`
+	PurchaseCount  int                  `json:"purchaseCount"`
+	Status         string               `json:"status"`
+	PublishAt      *time.Time           `json:"publishAt,omitempty"`
+	UnpublishAt    *time.Time           `json:"unpublishAt,omitempty"`
+	// IsFavourited is only set for logged in users, FavouriteCount only for the product's owner
+	IsFavourited   *bool `json:"isFavourited,omitempty"`
+	FavouriteCount *int  `json:"favouriteCount,omitempty"`
+}
+
+type ProductSaleResponse struct {
+	SalePrice money.Money `json:"salePrice"`
+	StartsAt  time.Time   `json:"startsAt"`
+	EndsAt    time.Time   `json:"endsAt"`
+	IsActive  bool        `json:"isActive"`
+}
+
+type TrashedProductResponse struct {
+	Product         ProductResponse `json:"product"`
+	DeletedAt       time.Time       `json:"deletedAt"`
+	RestorableUntil time.Time       `json:"restorableUntil"`
 }
 
 type ProductDetailResponse struct {
@@ -24,16 +56,97 @@ type ProductDetailSellerResponse struct {
 }
 
 type BankAccountResponse struct {
-	BankAccountID     string `json:"bankAccountId"`
-	BankName          string `json:"bankName"`
-	BankAccountName   string `json:"bankAccountName"`
-	BankAccountNumber string `json:"bankAccountNumber"`
+	BankAccountID     string  `json:"bankAccountId"`
+	BankCode          *string `json:"bankCode"`
+	BankName          string  `json:"bankName"`
+	BankAccountName   string  `json:"bankAccountName"`
+	BankAccountNumber string  `json:"bankAccountNumber"`
+	IsDefault         bool    `json:"isDefault"`
 }
 
 type OrderResponse struct {
-	ID                   string `json:"id"`
-	ProductID            string `json:"productId"`
-	BankAccountID        string `json:"bankAccountId"`
-	PaymentProofImageURL string `json:"paymentProofImageUrl"`
-	Quantity             int    `json:"quantity"`
+	ID                   string                   `json:"id"`
+	ProductID            string                   `json:"productId"`
+	BankAccountID        string                   `json:"bankAccountId"`
+	BankAccount          OrderBankAccountResponse `json:"bankAccount"`
+	PaymentProofImageURL string                   `json:"paymentProofImageUrl"`
+	Quantity             int                      `json:"quantity"`
+	Status               string                   `json:"status"`
+	PaymentDeadline      *time.Time               `json:"paymentDeadline,omitempty"`
+	ProductName          string                   `json:"productName"`
+	ProductImageURL      string                   `json:"productImageUrl"`
+	ProductCondition     string                   `json:"productCondition"`
+	UnitPrice            money.Money              `json:"unitPrice"`
+	Subtotal             money.Money              `json:"subtotal"`
+	CouponCode           *string                  `json:"couponCode"`
+	ShippingAddress      *OrderAddressResponse    `json:"shippingAddress"`
+	Payment              *payment.PaymentResponse `json:"payment,omitempty"`
+	Shipment             *OrderShipmentResponse   `json:"shipment"`
+	CompletedAt          *time.Time               `json:"completedAt"`
+	Discount             money.Money              `json:"discount"`
+	ShippingService      *string                  `json:"shippingService"`
+	ShippingCost         money.Money              `json:"shippingCost"`
+	Tax                  money.Money              `json:"tax"`
+	Taxes                []tax.LineResponse       `json:"taxes"`
+	Refunded             money.Money              `json:"refunded"`
+	Total                money.Money              `json:"total"`
+	CreatedAt            time.Time                `json:"createdAt"`
+}
+
+type BulkProductResultResponse struct {
+	ProductID string `json:"productId"`
+	Success   bool   `json:"success"`
+	Code      string `json:"code,omitempty"`
+	Message   string `json:"message,omitempty"`
+}
+
+// OrderBankAccountResponse is the seller's bank account as it was when the order was made
+type OrderBankAccountResponse struct {
+	BankCode          *string `json:"bankCode"`
+	BankName          string  `json:"bankName"`
+	BankAccountName   string  `json:"bankAccountName"`
+	BankAccountNumber string  `json:"bankAccountNumber"`
+}
+
+type OrderAddressResponse struct {
+	RecipientName string `json:"recipientName"`
+	Phone         string `json:"phone"`
+	AddressLine   string `json:"addressLine"`
+	City          string `json:"city"`
+	Province      string `json:"province"`
+	PostalCode    string `json:"postalCode"`
+	Country       string `json:"country"`
+}
+
+type OrderShipmentResponse struct {
+	Courier        string    `json:"courier"`
+	TrackingNumber string    `json:"trackingNumber"`
+	ShippedAt      time.Time `json:"shippedAt"`
+}
+
+type DisputeResponse struct {
+	DisputeID  string                   `json:"disputeId"`
+	OrderID    string                   `json:"orderId"`
+	Reason     string                   `json:"reason"`
+	Status     string                   `json:"status"`
+	Proposal   *RefundProposalResponse  `json:"proposal"`
+	Resolution *string                  `json:"resolution"`
+	Messages   []DisputeMessageResponse `json:"messages,omitempty"`
+	CreatedAt  time.Time                `json:"createdAt"`
+	ResolvedAt *time.Time               `json:"resolvedAt"`
+}
+
+type RefundProposalResponse struct {
+	Amount       money.Money `json:"amount"`
+	RestoreStock bool        `json:"restoreStock"`
+	// ProposedBy is the role of the party who proposed the refund: buyer or seller
+	ProposedBy string `json:"proposedBy"`
+}
+
+type DisputeMessageResponse struct {
+	// SenderRole is buyer, seller or admin
+	SenderRole        string    `json:"senderRole"`
+	Message           string    `json:"message"`
+	EvidenceImageURLs []string  `json:"evidenceImageUrls"`
+	CreatedAt         time.Time `json:"createdAt"`
 }
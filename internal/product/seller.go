@@ -0,0 +1,33 @@
+package product
+
+import (
+	"context"
+	"database/sql"
+)
+
+// MarkSellerProductsUnpurchasable stops all the seller's products from being bought, in the caller's
+// transaction, and returns how many were changed. It lets the bank accounts take the seller's products
+// off sale when the seller is left with nowhere to be paid.
+func MarkSellerProductsUnpurchasable(ctx context.Context, tx *sql.Tx, sellerID string) (int, error) {
+	products, err := ProductRepoImpl.LockPurchasableProductsByUserID(ctx, tx, sellerID)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, product := range products {
+		previousProduct := product
+		product.IsPurchasable = false
+
+		err = ProductRepoImpl.UpdateProduct(ctx, tx, product)
+		if err != nil {
+			return 0, err
+		}
+
+		err = enqueueProductChangeEvents(ctx, tx, previousProduct, product)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(products), nil
+}
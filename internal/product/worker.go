@@ -0,0 +1,185 @@
+package product
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+)
+
+const (
+	purgeBatchSize       = 500
+	orderExpiryBatchSize = 100
+)
+
+// RunPurgeWorker periodically hard-deletes soft-deleted products whose retention window
+// has passed. It blocks until ctx is cancelled, so it should be run in its own goroutine.
+func RunPurgeWorker(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := purgeDeletedProducts(ctx)
+		if err != nil {
+			log.Printf("error purging deleted products: %v", err)
+		} else if purged > 0 {
+			log.Printf("purged %d deleted products", purged)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func purgeDeletedProducts(ctx context.Context) (int, error) {
+	deletedBefore := time.Now().Add(-TrashRetention)
+
+	total := 0
+	for {
+		tx, err := TrxProvider.NewTransaction(ctx)
+		if err != nil {
+			return total, err
+		}
+
+		purged, err := ProductRepoImpl.PurgeDeletedProducts(ctx, tx, deletedBefore, purgeBatchSize)
+		if err != nil {
+			tx.Rollback()
+			return total, err
+		}
+
+		err = tx.Commit()
+		if err != nil {
+			return total, err
+		}
+
+		total += purged
+		if purged < purgeBatchSize {
+			return total, nil
+		}
+	}
+}
+
+// RunOrderExpiryWorker periodically cancels unpaid orders past their payment deadline and returns
+// their stock. It blocks until ctx is cancelled, so it should be run in its own goroutine.
+func RunOrderExpiryWorker(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		cancelled, err := cancelExpiredOrders(ctx)
+		if err != nil {
+			log.Printf("error cancelling expired orders: %v", err)
+		} else if cancelled > 0 {
+			log.Printf("cancelled %d expired orders", cancelled)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func cancelExpiredOrders(ctx context.Context) (int, error) {
+	total := 0
+	for {
+		cancelled, err := cancelExpiredOrdersBatch(ctx)
+		if err != nil {
+			return total, err
+		}
+
+		total += cancelled
+		if cancelled < orderExpiryBatchSize {
+			return total, nil
+		}
+	}
+}
+
+// cancelExpiredOrdersBatch cancels a batch of orders in one transaction, so an order is never
+// cancelled without its stock, coupon usage and payment being released with it
+func cancelExpiredOrdersBatch(ctx context.Context) (int, error) {
+	tx, err := TrxProvider.NewTransaction(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	orders, err := ProductRepoImpl.ClaimExpiredOrders(ctx, tx, time.Now(), orderExpiryBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, order := range orders {
+		err = cancelExpiredOrder(ctx, tx, order)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+
+	return len(orders), nil
+}
+
+func cancelExpiredOrder(ctx context.Context, tx *sql.Tx, order Order) error {
+	cancelledOrder, err := ProductRepoImpl.UpdateOrderStatus(ctx, tx, order.ID, OrderStatusPendingPayment, OrderStatusCancelled)
+	if err != nil {
+		return err
+	}
+
+	err = PaymentRepoImpl.ExpirePayment(ctx, tx, order.ID)
+	if err != nil {
+		return err
+	}
+
+	if order.CouponID != nil {
+		err = CouponRepoImpl.ReleaseCouponUsage(ctx, tx, *order.CouponID)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = returnOrderStock(ctx, tx, order)
+	if err != nil {
+		return err
+	}
+
+	return emitOrderCancelled(ctx, tx, cancelledOrder, OrderCancelReasonPaymentExpired)
+}
+
+// returnOrderStock puts the order's items back in stock, and announces it like any other stock change
+func returnOrderStock(ctx context.Context, tx *sql.Tx, order Order) error {
+	stock, err := ProductRepoImpl.IncrementProductStock(ctx, tx, order.ProductID, order.Quantity)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+
+	// only live products announce the returned stock, nobody is watching a deleted one
+	product, err := ProductRepoImpl.GetProductByID(ctx, order.ProductID)
+	if err == nil {
+		previousProduct := product
+		previousProduct.Stock = stock - order.Quantity
+		product.Stock = stock
+
+		err = enqueueProductChangeEvents(ctx, tx, previousProduct, product)
+		if err != nil {
+			return err
+		}
+
+		err = emitStockChanged(ctx, tx, product)
+		if err != nil {
+			return err
+		}
+	} else if err != sql.ErrNoRows {
+		return err
+	}
+
+	return nil
+}
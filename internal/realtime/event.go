@@ -0,0 +1,56 @@
+package realtime
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+)
+
+// Channel is the postgres NOTIFY channel events are published on, so every instance receives them
+const Channel = "shopifyx_events"
+
+const (
+	EventTypeStockChanged       = "stock_changed"
+	EventTypeOrderStatusChanged = "order_status_changed"
+)
+
+// Event is delivered to the connected clients watching ProductID, and to the clients of UserIDs
+type Event struct {
+	Type      string          `json:"type"`
+	ProductID string          `json:"productId,omitempty"`
+	UserIDs   []string        `json:"userIds,omitempty"`
+	Data      json.RawMessage `json:"data"`
+}
+
+type StockChangedData struct {
+	ProductID string `json:"productId"`
+	Stock     int    `json:"stock"`
+}
+
+type OrderStatusChangedData struct {
+	OrderID   string `json:"orderId"`
+	ProductID string `json:"productId"`
+	Status    string `json:"status"`
+}
+
+// Publish sends the event to every instance. Notifications are only delivered when tx commits, so
+// clients never see a change that was rolled back. Postgres limits the payload to 8000 bytes.
+func Publish(ctx context.Context, tx *sql.Tx, event Event, data interface{}) error {
+	var err error
+	event.Data, err = json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `SELECT pg_notify($1, $2)`, Channel, string(payload))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
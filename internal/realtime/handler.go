@@ -0,0 +1,110 @@
+package realtime
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/model"
+	"github.com/ahmadnaufal/openidea-shopifyx/pkg/jwt"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	heartbeatInterval = 25 * time.Second
+	// maxWatchedProducts limits the products a client can watch on top of its favourites and subscriptions
+	maxWatchedProducts = 50
+)
+
+var (
+	RealtimeRepoImpl *RealtimeRepo
+	HubImpl          *Hub
+
+	// VisibleProductsFilter keeps the products the viewer is allowed to see. It is provided by the
+	// products' owner, so this package doesn't depend on it.
+	VisibleProductsFilter func(ctx context.Context, viewerID string, productIDs []string) ([]string, error)
+)
+
+func RegisterRoute(r *fiber.App, jwtProvider jwt.JWTProvider) {
+	// browsers' EventSource can't send headers, so the token can also be passed as a query param
+	r.Get("/v1/events", jwtProvider.StreamMiddleware(), StreamEvents)
+}
+
+// StreamEvents streams server-sent events to the logged in user: stock changes of the products they
+// watch (their favourites, subscriptions and the ones in the products query param) and status
+// changes of the orders they bought or sold.
+func StreamEvents(c *fiber.Ctx) error {
+	claims, err := jwt.GetLoggedInUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "forbidden",
+		})
+	}
+
+	productIDs := []string{}
+	for _, productID := range strings.Split(c.Query("products"), ",") {
+		if productID = strings.TrimSpace(productID); productID != "" {
+			productIDs = append(productIDs, productID)
+		}
+	}
+
+	if len(productIDs) > maxWatchedProducts {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: fmt.Sprintf("cannot watch more than %d products", maxWatchedProducts),
+			Code:    "failed_request_body_validation",
+		})
+	}
+
+	watchedProductIDs, err := RealtimeRepoImpl.GetWatchedProductIDs(c.Context(), claims.UserID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	// drafts and other sellers' unpublished products can't be watched, even if the client knows their ID
+	visibleProductIDs, err := VisibleProductsFilter(c.Context(), claims.UserID, append(productIDs, watchedProductIDs...))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	sub := HubImpl.subscribe(claims.UserID, visibleProductIDs)
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	// tell proxies like nginx not to buffer the stream
+	c.Set("X-Accel-Buffering", "no")
+
+	// the writer outlives the handler, so it must not touch c
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer HubImpl.unsubscribe(sub)
+
+		heartbeat := time.NewTicker(heartbeatInterval)
+		defer heartbeat.Stop()
+
+		fmt.Fprint(w, ": connected\n\n")
+		for {
+			if err := w.Flush(); err != nil {
+				// the client is gone
+				return
+			}
+
+			select {
+			case event := <-sub.events:
+				fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, event.Data)
+			case <-heartbeat.C:
+				fmt.Fprint(w, ": ping\n\n")
+			}
+		}
+	})
+
+	return nil
+}
@@ -0,0 +1,73 @@
+package realtime
+
+import (
+	"log"
+	"sync"
+)
+
+const clientBufferSize = 32
+
+type client struct {
+	userID     string
+	productIDs map[string]bool
+	events     chan Event
+}
+
+// Hub keeps track of the clients connected to this instance and fans events out to them
+type Hub struct {
+	mu      sync.RWMutex
+	clients map[*client]struct{}
+}
+
+func NewHub() *Hub {
+	return &Hub{
+		clients: map[*client]struct{}{},
+	}
+}
+
+func (h *Hub) subscribe(userID string, productIDs []string) *client {
+	c := &client{
+		userID:     userID,
+		productIDs: map[string]bool{},
+		events:     make(chan Event, clientBufferSize),
+	}
+	for _, productID := range productIDs {
+		c.productIDs[productID] = true
+	}
+
+	h.mu.Lock()
+	h.clients[c] = struct{}{}
+	h.mu.Unlock()
+
+	return c
+}
+
+func (h *Hub) unsubscribe(c *client) {
+	h.mu.Lock()
+	delete(h.clients, c)
+	h.mu.Unlock()
+}
+
+// Broadcast delivers the event to the interested clients. It never blocks: a client that falls too
+// far behind misses the event rather than holding up everyone else.
+func (h *Hub) Broadcast(event Event) {
+	userIDs := map[string]bool{}
+	for _, userID := range event.UserIDs {
+		userIDs[userID] = true
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for c := range h.clients {
+		if !userIDs[c.userID] && (event.ProductID == "" || !c.productIDs[event.ProductID]) {
+			continue
+		}
+
+		select {
+		case c.events <- event:
+		default:
+			log.Printf("realtime client of user %s is too slow, dropping %s event", c.userID, event.Type)
+		}
+	}
+}
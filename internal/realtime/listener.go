@@ -0,0 +1,61 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	listenerMinReconnectInterval = 10 * time.Second
+	listenerMaxReconnectInterval = time.Minute
+	listenerPingInterval         = 90 * time.Second
+)
+
+// RunListener listens for the events published by every instance and broadcasts them to the hub.
+// It uses its own connection since LISTEN doesn't work through the pool. It blocks until ctx is
+// cancelled, so it should be run in its own goroutine.
+func RunListener(ctx context.Context, dsn string, hub *Hub) {
+	listener := pq.NewListener(dsn, listenerMinReconnectInterval, listenerMaxReconnectInterval, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("realtime listener error: %v", err)
+		}
+	})
+	defer listener.Close()
+
+	err := listener.Listen(Channel)
+	if err != nil {
+		log.Printf("error listening to %s: %v", Channel, err)
+		return
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case notification := <-listener.Notify:
+			// a nil notification means the connection was re-established, events in between are lost
+			if notification == nil {
+				log.Printf("realtime listener reconnected")
+				continue
+			}
+
+			var event Event
+			err := json.Unmarshal([]byte(notification.Extra), &event)
+			if err != nil {
+				log.Printf("error decoding realtime event: %v", err)
+				continue
+			}
+
+			hub.Broadcast(event)
+
+		case <-time.After(listenerPingInterval):
+			// make sure a silently dropped connection is noticed and re-established
+			go listener.Ping()
+		}
+	}
+}
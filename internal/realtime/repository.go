@@ -0,0 +1,32 @@
+package realtime
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type RealtimeRepo struct {
+	db *sqlx.DB
+}
+
+func NewRealtimeRepo(db *sqlx.DB) RealtimeRepo {
+	return RealtimeRepo{db: db}
+}
+
+// GetWatchedProductIDs returns the products the user favourited or subscribed to
+func (r RealtimeRepo) GetWatchedProductIDs(ctx context.Context, userID string) ([]string, error) {
+	query := `
+		SELECT product_id FROM favourites WHERE user_id = $1
+		UNION
+		SELECT product_id FROM product_subscriptions WHERE user_id = $1
+	`
+
+	productIDs := []string{}
+	err := r.db.SelectContext(ctx, &productIDs, query, userID)
+	if err != nil {
+		return productIDs, err
+	}
+
+	return productIDs, nil
+}
@@ -0,0 +1,190 @@
+package shipping
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/config"
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/model"
+	"github.com/ahmadnaufal/openidea-shopifyx/pkg/jwt"
+	"github.com/ahmadnaufal/openidea-shopifyx/pkg/money"
+	"github.com/ahmadnaufal/openidea-shopifyx/pkg/validation"
+	"github.com/gofiber/fiber/v2"
+)
+
+var (
+	ShippingRepoImpl *ShippingRepo
+	TrxProvider      *config.TransactionProvider
+)
+
+func RegisterRoute(r *fiber.App, jwtProvider jwt.JWTProvider) {
+	shippingGroup := r.Group("/v1/shipping")
+	authMiddleware := jwtProvider.Middleware()
+	shippingGroup.Use(authMiddleware)
+
+	shippingGroup.Get("/rates", GetRateTable)
+	shippingGroup.Put("/rates", ReplaceRateTable)
+}
+
+func GetRateTable(c *fiber.Ctx) error {
+	claims, err := jwt.GetLoggedInUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "forbidden",
+		})
+	}
+
+	ctx := c.Context()
+	settings, err := ShippingRepoImpl.GetSettings(ctx, claims.UserID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return c.Status(fiber.StatusNotFound).JSON(model.ErrorResponse{
+				Message: "shipping rates have not been set up",
+				Code:    "entity_not_found",
+			})
+		}
+
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	rates, err := ShippingRepoImpl.ListRatesByUserID(ctx, claims.UserID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(model.DataResponse{
+		Message: "ok",
+		Data:    rateTableToResponse(settings, rates),
+	})
+}
+
+func ReplaceRateTable(c *fiber.Ctx) error {
+	var payload RateTableRequest
+
+	claims, err := jwt.GetLoggedInUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "forbidden",
+		})
+	}
+
+	if err := c.BodyParser(&payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "invalid_request_body",
+		})
+	}
+
+	// validation for request body
+	if err := validation.Validate(payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "failed_request_body_validation",
+		})
+	}
+
+	settings := Settings{
+		UserID:       claims.UserID,
+		OriginRegion: NormalizeRegion(payload.OriginRegion),
+		UpdatedAt:    time.Now(),
+	}
+
+	// a parcel must match a single tier per service & regions, so duplicated tiers are rejected
+	rates := make([]TableRate, len(payload.Rates))
+	seenTiers := map[TableRate]bool{}
+	for i, rate := range payload.Rates {
+		rates[i] = TableRate{
+			UserID:            claims.UserID,
+			Service:           rate.Service,
+			OriginRegion:      NormalizeRegion(rate.OriginRegion),
+			DestinationRegion: NormalizeRegion(rate.DestinationRegion),
+			MaxWeightGrams:    rate.MaxWeightGrams,
+			Cost:              rate.Cost.Round().Amount,
+			Currency:          rate.Cost.Currency,
+			EstimatedDays:     rate.EstimatedDays,
+		}
+
+		tier := TableRate{
+			Service:           rates[i].Service,
+			OriginRegion:      rates[i].OriginRegion,
+			DestinationRegion: rates[i].DestinationRegion,
+			MaxWeightGrams:    rates[i].MaxWeightGrams,
+			Currency:          rates[i].Currency,
+		}
+		if seenTiers[tier] {
+			return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+				Message: "rates contain the same service, regions and weight tier more than once",
+				Code:    "failed_request_body_validation",
+			})
+		}
+		seenTiers[tier] = true
+	}
+
+	ctx := c.Context()
+	tx, err := TrxProvider.NewTransaction(ctx)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+	defer tx.Rollback()
+
+	err = ShippingRepoImpl.ReplaceRateTable(ctx, tx, settings, rates)
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(model.DataResponse{
+		Message: "Shipping rates saved successfully",
+		Data:    rateTableToResponse(settings, rates),
+	})
+}
+
+func rateTableToResponse(settings Settings, rates []TableRate) RateTableResponse {
+	response := RateTableResponse{
+		OriginRegion: settings.OriginRegion,
+		Rates:        []TableRateResponse{},
+	}
+
+	for _, rate := range rates {
+		response.Rates = append(response.Rates, TableRateResponse{
+			Service:           rate.Service,
+			OriginRegion:      rate.OriginRegion,
+			DestinationRegion: rate.DestinationRegion,
+			MaxWeightGrams:    rate.MaxWeightGrams,
+			Cost:              money.New(rate.Cost, rate.Currency),
+			EstimatedDays:     rate.EstimatedDays,
+		})
+	}
+
+	return response
+}
+
+// RatesToResponse converts quoted rates to their API representation
+func RatesToResponse(rates []Rate) []RateResponse {
+	responses := make([]RateResponse, len(rates))
+	for i, rate := range rates {
+		responses[i] = RateResponse{
+			Service:       rate.Service,
+			Cost:          rate.Cost,
+			EstimatedDays: rate.EstimatedDays,
+		}
+	}
+
+	return responses
+}
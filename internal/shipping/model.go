@@ -0,0 +1,51 @@
+package shipping
+
+import (
+	"strings"
+	"time"
+
+	"github.com/ahmadnaufal/openidea-shopifyx/pkg/money"
+)
+
+// AnyRegion matches every origin or destination region in a rate table
+const AnyRegion = "*"
+
+type RateTableRequest struct {
+	// OriginRegion is where the seller ships from, e.g. the province of their warehouse
+	OriginRegion string             `json:"originRegion" validate:"required,max=60"`
+	Rates        []TableRateRequest `json:"rates" validate:"max=200,dive"`
+}
+
+type TableRateRequest struct {
+	Service string `json:"service" validate:"required,min=2,max=30"`
+	// regions are matched case-insensitively, "*" matches any region
+	OriginRegion      string `json:"originRegion" validate:"required,max=60"`
+	DestinationRegion string `json:"destinationRegion" validate:"required,max=60"`
+	// MaxWeightGrams is the upper bound of the weight tier, a parcel uses the smallest tier it fits in
+	MaxWeightGrams int         `json:"maxWeightGrams" validate:"required,gte=1"`
+	Cost           money.Money `json:"cost" validate:"required"`
+	EstimatedDays  int         `json:"estimatedDays" validate:"omitempty,gte=0,lte=60"`
+}
+
+type Settings struct {
+	UserID       string    `db:"user_id"`
+	OriginRegion string    `db:"origin_region"`
+	UpdatedAt    time.Time `db:"updated_at"`
+}
+
+type TableRate struct {
+	ID                int            `db:"id"`
+	UserID            string         `db:"user_id"`
+	Service           string         `db:"service"`
+	OriginRegion      string         `db:"origin_region"`
+	DestinationRegion string         `db:"destination_region"`
+	MaxWeightGrams    int            `db:"max_weight_grams"`
+	Cost              int64          `db:"cost"`
+	Currency          money.Currency `db:"currency"`
+	EstimatedDays     int            `db:"estimated_days"`
+}
+
+// NormalizeRegion makes region names comparable, so "West Java" and "west java " are the same region
+func NormalizeRegion(region string) string {
+	return strings.ToLower(strings.TrimSpace(region))
+}
@@ -0,0 +1,133 @@
+package shipping
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sort"
+
+	"github.com/ahmadnaufal/openidea-shopifyx/pkg/money"
+)
+
+const (
+	ProviderTable = "table"
+	ProviderFake  = "fake"
+)
+
+type QuoteRequest struct {
+	SellerID          string
+	DestinationRegion string
+	WeightGrams       int
+	// Currency of the order, rates in other currencies are never offered
+	Currency money.Currency
+}
+
+// ErrNotDeliverable is returned when the seller charges for shipping, but none of their rates
+// covers the parcel: its destination, weight or currency
+var ErrNotDeliverable = errors.New("the product cannot be delivered to this address")
+
+// Rate is one shipping service the buyer can choose from
+type Rate struct {
+	Service       string
+	Cost          money.Money
+	EstimatedDays int
+}
+
+// RateProvider quotes the shipping services available for a parcel, cheapest first.
+// No rates means the seller doesn't charge for shipping at all; sellers who do charge but
+// have no rate for the parcel get ErrNotDeliverable.
+type RateProvider interface {
+	Quote(ctx context.Context, req QuoteRequest) ([]Rate, error)
+}
+
+// NewRateProvider returns the provider with the given name, falling back to the table provider
+func NewRateProvider(name string, repo *ShippingRepo) RateProvider {
+	if name == ProviderFake {
+		return FakeProvider{}
+	}
+
+	return TableRateProvider{repo: repo}
+}
+
+// TableRateProvider quotes from the rate tables the sellers configure themselves
+type TableRateProvider struct {
+	repo *ShippingRepo
+}
+
+func (p TableRateProvider) Quote(ctx context.Context, req QuoteRequest) ([]Rate, error) {
+	settings, err := p.repo.GetSettings(ctx, req.SellerID)
+	if err != nil {
+		// sellers without a rate table ship for free
+		if err == sql.ErrNoRows {
+			return []Rate{}, nil
+		}
+		return nil, err
+	}
+
+	tableRates, err := p.repo.FindMatchingRates(ctx, req.SellerID, settings.OriginRegion, NormalizeRegion(req.DestinationRegion), req.WeightGrams, req.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	// only sellers with an empty rate table ship for free, the others just don't cover this parcel
+	if len(tableRates) == 0 {
+		allRates, err := p.repo.ListRatesByUserID(ctx, req.SellerID)
+		if err != nil {
+			return nil, err
+		}
+		if len(allRates) > 0 {
+			return nil, ErrNotDeliverable
+		}
+	}
+
+	rates := make([]Rate, len(tableRates))
+	for i, tableRate := range tableRates {
+		rates[i] = Rate{
+			Service:       tableRate.Service,
+			Cost:          money.New(tableRate.Cost, tableRate.Currency),
+			EstimatedDays: tableRate.EstimatedDays,
+		}
+	}
+
+	sortRates(rates)
+
+	return rates, nil
+}
+
+// FakeProvider returns fixed rates, for tests and local development. Without any rates
+// it offers a single free "standard" service in the requested currency, and with rates but none
+// in the requested currency it returns ErrNotDeliverable.
+type FakeProvider struct {
+	Rates []Rate
+	Err   error
+}
+
+func (p FakeProvider) Quote(ctx context.Context, req QuoteRequest) ([]Rate, error) {
+	if p.Err != nil {
+		return nil, p.Err
+	}
+
+	if len(p.Rates) == 0 {
+		return []Rate{{Service: "standard", Cost: money.New(0, req.Currency)}}, nil
+	}
+
+	rates := []Rate{}
+	for _, rate := range p.Rates {
+		if rate.Cost.Currency == req.Currency {
+			rates = append(rates, rate)
+		}
+	}
+	if len(rates) == 0 {
+		return nil, ErrNotDeliverable
+	}
+
+	sortRates(rates)
+
+	return rates, nil
+}
+
+func sortRates(rates []Rate) {
+	sort.SliceStable(rates, func(i, j int) bool {
+		return rates[i].Cost.Amount < rates[j].Cost.Amount
+	})
+}
@@ -0,0 +1,174 @@
+package shipping
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/ahmadnaufal/openidea-shopifyx/pkg/money"
+	"github.com/jmoiron/sqlx"
+)
+
+type ShippingRepo struct {
+	db *sqlx.DB
+}
+
+func NewShippingRepo(db *sqlx.DB) ShippingRepo {
+	return ShippingRepo{db: db}
+}
+
+func (r ShippingRepo) GetSettings(ctx context.Context, userID string) (Settings, error) {
+	var result Settings
+
+	query := `
+		SELECT
+			user_id,
+			origin_region,
+			updated_at
+		FROM
+			shipping_settings
+		WHERE
+			user_id = $1
+		LIMIT 1
+	`
+
+	err := r.db.GetContext(ctx, &result, query, userID)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r ShippingRepo) ListRatesByUserID(ctx context.Context, userID string) ([]TableRate, error) {
+	var rates []TableRate
+
+	query := `
+		SELECT
+			id,
+			user_id,
+			service,
+			origin_region,
+			destination_region,
+			max_weight_grams,
+			cost,
+			currency,
+			estimated_days
+		FROM
+			shipping_rates
+		WHERE
+			user_id = $1
+		ORDER BY
+			service, origin_region, destination_region, max_weight_grams
+	`
+
+	err := r.db.SelectContext(ctx, &rates, query, userID)
+	if err != nil {
+		return rates, err
+	}
+
+	return rates, nil
+}
+
+// ReplaceRateTable stores the seller's origin region and replaces their whole rate table
+func (r ShippingRepo) ReplaceRateTable(ctx context.Context, tx *sql.Tx, settings Settings, rates []TableRate) error {
+	upsertQuery := `
+		INSERT INTO shipping_settings
+			(user_id, origin_region, updated_at)
+		VALUES
+			($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE
+		SET
+			origin_region = EXCLUDED.origin_region,
+			updated_at = EXCLUDED.updated_at
+	`
+
+	_, err := tx.ExecContext(ctx, upsertQuery, settings.UserID, settings.OriginRegion, settings.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM shipping_rates WHERE user_id = $1`, settings.UserID)
+	if err != nil {
+		return err
+	}
+
+	if len(rates) == 0 {
+		return nil
+	}
+
+	insertQuery := `
+		INSERT INTO shipping_rates
+			(
+				user_id,
+				service,
+				origin_region,
+				destination_region,
+				max_weight_grams,
+				cost,
+				currency,
+				estimated_days
+			)
+		VALUES
+			(
+				:user_id,
+				:service,
+				:origin_region,
+				:destination_region,
+				:max_weight_grams,
+				:cost,
+				:currency,
+				:estimated_days
+			)
+	`
+
+	updatedQuery, args, err := sqlx.Named(insertQuery, rates)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, sqlx.Rebind(sqlx.DOLLAR, updatedQuery), args...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// FindMatchingRates returns one rate per service for a parcel: the most specific region match,
+// exact regions over "*", and within it the smallest weight tier the parcel fits in
+func (r ShippingRepo) FindMatchingRates(ctx context.Context, userID, originRegion, destinationRegion string, weightGrams int, currency money.Currency) ([]TableRate, error) {
+	var rates []TableRate
+
+	query := `
+		SELECT DISTINCT ON (service)
+			id,
+			user_id,
+			service,
+			origin_region,
+			destination_region,
+			max_weight_grams,
+			cost,
+			currency,
+			estimated_days
+		FROM
+			shipping_rates
+		WHERE
+			user_id = $1
+			AND origin_region IN ($2, $5)
+			AND destination_region IN ($3, $5)
+			AND max_weight_grams >= $4
+			AND currency = $6
+		ORDER BY
+			service,
+			destination_region = $5,
+			origin_region = $5,
+			max_weight_grams
+	`
+
+	err := r.db.SelectContext(ctx, &rates, query, userID, originRegion, destinationRegion, weightGrams, AnyRegion, currency)
+	if err != nil {
+		return rates, err
+	}
+
+	return rates, nil
+}
@@ -0,0 +1,23 @@
+package shipping
+
+import "github.com/ahmadnaufal/openidea-shopifyx/pkg/money"
+
+type RateTableResponse struct {
+	OriginRegion string              `json:"originRegion"`
+	Rates        []TableRateResponse `json:"rates"`
+}
+
+type TableRateResponse struct {
+	Service           string      `json:"service"`
+	OriginRegion      string      `json:"originRegion"`
+	DestinationRegion string      `json:"destinationRegion"`
+	MaxWeightGrams    int         `json:"maxWeightGrams"`
+	Cost              money.Money `json:"cost"`
+	EstimatedDays     int         `json:"estimatedDays"`
+}
+
+type RateResponse struct {
+	Service       string      `json:"service"`
+	Cost          money.Money `json:"cost"`
+	EstimatedDays int         `json:"estimatedDays"`
+}
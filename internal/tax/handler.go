@@ -0,0 +1,203 @@
+package tax
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/admin"
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/commission"
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/model"
+	"github.com/ahmadnaufal/openidea-shopifyx/pkg/jwt"
+	"github.com/ahmadnaufal/openidea-shopifyx/pkg/money"
+	"github.com/ahmadnaufal/openidea-shopifyx/pkg/validation"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+var (
+	TaxRepoImpl *TaxRepo
+)
+
+func RegisterRoute(r *fiber.App, jwtProvider jwt.JWTProvider) {
+	authMiddleware := jwtProvider.Middleware()
+
+	ruleGroup := r.Group("/v1/admin/tax/rules", authMiddleware, admin.Middleware())
+	ruleGroup.Post("/", CreateRule)
+	ruleGroup.Get("/", ListRules)
+	ruleGroup.Put("/:rule_id", UpdateRule)
+	ruleGroup.Delete("/:rule_id", DeleteRule)
+}
+
+func CreateRule(c *fiber.Ctx) error {
+	var payload RuleRequest
+
+	if err := c.BodyParser(&payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "invalid_request_body",
+		})
+	}
+
+	// validation for request body
+	if err := validation.Validate(payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "failed_request_body_validation",
+		})
+	}
+
+	now := time.Now()
+	rule := Rule{
+		ID:        uuid.NewString(),
+		CreatedAt: now,
+	}
+	applyRuleRequest(&rule, payload, now)
+
+	err := TaxRepoImpl.CreateRule(c.Context(), rule)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(model.DataResponse{
+		Message: "Tax rule created successfully",
+		Data:    ruleEntityToResponse(rule),
+	})
+}
+
+func ListRules(c *fiber.Ctx) error {
+	rules, err := TaxRepoImpl.ListRules(c.Context())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	responses := make([]RuleResponse, len(rules))
+	for i, rule := range rules {
+		responses[i] = ruleEntityToResponse(rule)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(model.DataResponse{
+		Message: "ok",
+		Data:    responses,
+	})
+}
+
+// UpdateRule changes the rule for the orders made from now on, orders already made keep their taxes
+func UpdateRule(c *fiber.Ctx) error {
+	var payload RuleRequest
+
+	if err := c.BodyParser(&payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "invalid_request_body",
+		})
+	}
+
+	// validation for request body
+	if err := validation.Validate(payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "failed_request_body_validation",
+		})
+	}
+
+	ctx := c.Context()
+	rule, err := TaxRepoImpl.GetRuleByID(ctx, c.Params("rule_id"))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return c.Status(fiber.StatusNotFound).JSON(model.ErrorResponse{
+				Message: "tax rule not found",
+				Code:    "entity_not_found",
+			})
+		}
+
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	applyRuleRequest(&rule, payload, time.Now())
+
+	err = TaxRepoImpl.UpdateRule(ctx, rule)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(model.DataResponse{
+		Message: "Tax rule updated successfully",
+		Data:    ruleEntityToResponse(rule),
+	})
+}
+
+func DeleteRule(c *fiber.Ctx) error {
+	ctx := c.Context()
+	rule, err := TaxRepoImpl.GetRuleByID(ctx, c.Params("rule_id"))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return c.Status(fiber.StatusNotFound).JSON(model.ErrorResponse{
+				Message: "tax rule not found",
+				Code:    "entity_not_found",
+			})
+		}
+
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	err = TaxRepoImpl.DeleteRule(ctx, rule.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(model.DataResponse{
+		Message: "Tax rule deleted successfully",
+	})
+}
+
+func applyRuleRequest(rule *Rule, payload RuleRequest, now time.Time) {
+	rule.Name = payload.Name
+	rule.RateBasisPoints = payload.RateBasisPoints
+	rule.Inclusive = *payload.Inclusive
+	rule.IsActive = *payload.IsActive
+	rule.Currency = nil
+	if payload.Currency != "" {
+		currency := money.Currency(payload.Currency)
+		rule.Currency = &currency
+	}
+
+	// exemptions are matched against products' categories, which are stored normalized
+	rule.ExemptCategories = []string{}
+	for _, category := range payload.ExemptCategories {
+		rule.ExemptCategories = append(rule.ExemptCategories, commission.NormalizeCategory(category))
+	}
+
+	rule.UpdatedAt = now
+}
+
+func ruleEntityToResponse(rule Rule) RuleResponse {
+	return RuleResponse{
+		RuleID:           rule.ID,
+		Name:             rule.Name,
+		RateBasisPoints:  rule.RateBasisPoints,
+		Inclusive:        rule.Inclusive,
+		Currency:         rule.Currency,
+		ExemptCategories: rule.ExemptCategories,
+		IsActive:         rule.IsActive,
+		CreatedAt:        rule.CreatedAt,
+		UpdatedAt:        rule.UpdatedAt,
+	}
+}
@@ -0,0 +1,81 @@
+package tax
+
+import (
+	"time"
+
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/commission"
+	"github.com/ahmadnaufal/openidea-shopifyx/pkg/money"
+	"github.com/lib/pq"
+)
+
+// basisPointsPerUnit is 100%, rates are in hundredths of a percent
+const basisPointsPerUnit = 10000
+
+type RuleRequest struct {
+	Name string `json:"name" validate:"required,max=30"`
+	// RateBasisPoints is the tax rate in hundredths of a percent, e.g. 1100 for 11%
+	RateBasisPoints int64 `json:"rateBasisPoints" validate:"gt=0,lte=10000"`
+	// Inclusive taxes are already part of the price, exclusive ones are added on top of it
+	Inclusive *bool `json:"inclusive" validate:"required"`
+	// Currency limits the rule to orders in the currency, e.g. PPN to orders in IDR
+	Currency         string   `json:"currency" validate:"omitempty,oneof=IDR SGD MYR"`
+	ExemptCategories []string `json:"exemptCategories" validate:"omitempty,dive,min=1,max=50"`
+	IsActive         *bool    `json:"isActive" validate:"required"`
+}
+
+type Rule struct {
+	ID               string          `db:"id"`
+	Name             string          `db:"name"`
+	RateBasisPoints  int64           `db:"rate_basis_points"`
+	Inclusive        bool            `db:"inclusive"`
+	Currency         *money.Currency `db:"currency"`
+	ExemptCategories pq.StringArray  `db:"exempt_categories"`
+	IsActive         bool            `db:"is_active"`
+	CreatedAt        time.Time       `db:"created_at"`
+	UpdatedAt        time.Time       `db:"updated_at"`
+}
+
+// AppliesTo checks whether an order in the currency, of a product in the category, is taxed by the rule
+func (r Rule) AppliesTo(currency money.Currency, category string) bool {
+	if !r.IsActive {
+		return false
+	}
+
+	if r.Currency != nil && *r.Currency != currency {
+		return false
+	}
+
+	category = commission.NormalizeCategory(category)
+	for _, exempt := range r.ExemptCategories {
+		if category != "" && exempt == category {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Line is a tax charged on an order. The rule is snapshotted, so changing it later doesn't change
+// the taxes of orders already made.
+type Line struct {
+	ID              string         `db:"id"`
+	OrderID         string         `db:"order_id"`
+	TaxRuleID       *string        `db:"tax_rule_id"`
+	Name            string         `db:"name"`
+	RateBasisPoints int64          `db:"rate_basis_points"`
+	Inclusive       bool           `db:"inclusive"`
+	TaxableAmount   int64          `db:"taxable_amount"`
+	Amount          int64          `db:"amount"`
+	Currency        money.Currency `db:"currency"`
+	CreatedAt       time.Time      `db:"created_at"`
+}
+
+// Breakdown is the tax on an amount
+type Breakdown struct {
+	// Net is the amount before any tax, i.e. without the inclusive taxes
+	Net   money.Money
+	Lines []Line
+	// Inclusive is the tax already in the amount, Exclusive the tax to be added to it
+	Inclusive money.Money
+	Exclusive money.Money
+}
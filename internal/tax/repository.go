@@ -0,0 +1,291 @@
+package tax
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+type TaxRepo struct {
+	db *sqlx.DB
+}
+
+func NewTaxRepo(db *sqlx.DB) TaxRepo {
+	return TaxRepo{db: db}
+}
+
+func (r TaxRepo) CreateRule(ctx context.Context, rule Rule) error {
+	query := `
+		INSERT INTO tax_rules
+			(
+				id,
+				name,
+				rate_basis_points,
+				inclusive,
+				currency,
+				exempt_categories,
+				is_active,
+				created_at,
+				updated_at
+			)
+		VALUES
+			(
+				:id,
+				:name,
+				:rate_basis_points,
+				:inclusive,
+				:currency,
+				:exempt_categories,
+				:is_active,
+				:created_at,
+				:updated_at
+			)
+	`
+
+	updatedQuery, args, err := sqlx.Named(query, rule)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx, sqlx.Rebind(sqlx.DOLLAR, updatedQuery), args...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r TaxRepo) ListRules(ctx context.Context) ([]Rule, error) {
+	var rules []Rule
+
+	query := `
+		SELECT
+			id,
+			name,
+			rate_basis_points,
+			inclusive,
+			currency,
+			exempt_categories,
+			is_active,
+			created_at,
+			updated_at
+		FROM
+			tax_rules
+		ORDER BY
+			created_at
+	`
+
+	err := r.db.SelectContext(ctx, &rules, query)
+	if err != nil {
+		return rules, err
+	}
+
+	return rules, nil
+}
+
+func (r TaxRepo) ListActiveRules(ctx context.Context) ([]Rule, error) {
+	var rules []Rule
+
+	query := `
+		SELECT
+			id,
+			name,
+			rate_basis_points,
+			inclusive,
+			currency,
+			exempt_categories,
+			is_active,
+			created_at,
+			updated_at
+		FROM
+			tax_rules
+		WHERE
+			is_active = TRUE
+		ORDER BY
+			created_at
+	`
+
+	err := r.db.SelectContext(ctx, &rules, query)
+	if err != nil {
+		return rules, err
+	}
+
+	return rules, nil
+}
+
+func (r TaxRepo) GetRuleByID(ctx context.Context, ruleID string) (Rule, error) {
+	var result Rule
+
+	query := `
+		SELECT
+			id,
+			name,
+			rate_basis_points,
+			inclusive,
+			currency,
+			exempt_categories,
+			is_active,
+			created_at,
+			updated_at
+		FROM
+			tax_rules
+		WHERE
+			id = $1
+		LIMIT 1
+	`
+
+	err := r.db.GetContext(ctx, &result, query, ruleID)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r TaxRepo) UpdateRule(ctx context.Context, rule Rule) error {
+	query := `
+		UPDATE tax_rules
+		SET
+			name = :name,
+			rate_basis_points = :rate_basis_points,
+			inclusive = :inclusive,
+			currency = :currency,
+			exempt_categories = :exempt_categories,
+			is_active = :is_active,
+			updated_at = :updated_at
+		WHERE
+			id = :id
+	`
+
+	updatedQuery, args, err := sqlx.Named(query, rule)
+	if err != nil {
+		return err
+	}
+
+	result, err := r.db.ExecContext(ctx, sqlx.Rebind(sqlx.DOLLAR, updatedQuery), args...)
+	if err != nil {
+		return err
+	}
+
+	affectedRows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affectedRows != 1 {
+		return errors.New("error affected row count is not equal to 1")
+	}
+
+	return nil
+}
+
+func (r TaxRepo) DeleteRule(ctx context.Context, ruleID string) error {
+	query := `
+		DELETE FROM tax_rules
+		WHERE
+			id = $1
+	`
+
+	result, err := r.db.ExecContext(ctx, query, ruleID)
+	if err != nil {
+		return err
+	}
+
+	affectedRows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affectedRows != 1 {
+		return errors.New("error affected row count is not equal to 1")
+	}
+
+	return nil
+}
+
+func (r TaxRepo) CreateOrderTaxLines(ctx context.Context, tx *sql.Tx, lines []Line) error {
+	if len(lines) == 0 {
+		return nil
+	}
+
+	query := `
+		INSERT INTO order_tax_lines
+			(
+				id,
+				order_id,
+				tax_rule_id,
+				name,
+				rate_basis_points,
+				inclusive,
+				taxable_amount,
+				amount,
+				currency,
+				created_at
+			)
+		VALUES
+			(
+				:id,
+				:order_id,
+				:tax_rule_id,
+				:name,
+				:rate_basis_points,
+				:inclusive,
+				:taxable_amount,
+				:amount,
+				:currency,
+				:created_at
+			)
+	`
+
+	updatedQuery, args, err := sqlx.Named(query, lines)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, sqlx.Rebind(sqlx.DOLLAR, updatedQuery), args...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ListOrderTaxLines returns the tax lines of all the orders, with the inclusive taxes first
+func (r TaxRepo) ListOrderTaxLines(ctx context.Context, orderIDs []string) ([]Line, error) {
+	var lines []Line
+	if len(orderIDs) == 0 {
+		return lines, nil
+	}
+
+	query := `
+		SELECT
+			id,
+			order_id,
+			tax_rule_id,
+			name,
+			rate_basis_points,
+			inclusive,
+			taxable_amount,
+			amount,
+			currency,
+			created_at
+		FROM
+			order_tax_lines
+		WHERE
+			order_id IN (?)
+		ORDER BY
+			order_id, inclusive DESC, name
+	`
+
+	updatedQuery, args, err := sqlx.In(query, orderIDs)
+	if err != nil {
+		return lines, err
+	}
+
+	err = r.db.SelectContext(ctx, &lines, sqlx.Rebind(sqlx.DOLLAR, updatedQuery), args...)
+	if err != nil {
+		return lines, err
+	}
+
+	return lines, nil
+}
@@ -0,0 +1,43 @@
+package tax
+
+import (
+	"time"
+
+	"github.com/ahmadnaufal/openidea-shopifyx/pkg/money"
+)
+
+type RuleResponse struct {
+	RuleID           string          `json:"ruleId"`
+	Name             string          `json:"name"`
+	RateBasisPoints  int64           `json:"rateBasisPoints"`
+	Inclusive        bool            `json:"inclusive"`
+	Currency         *money.Currency `json:"currency"`
+	ExemptCategories []string        `json:"exemptCategories"`
+	IsActive         bool            `json:"isActive"`
+	CreatedAt        time.Time       `json:"createdAt"`
+	UpdatedAt        time.Time       `json:"updatedAt"`
+}
+
+type LineResponse struct {
+	Name            string      `json:"name"`
+	RateBasisPoints int64       `json:"rateBasisPoints"`
+	Inclusive       bool        `json:"inclusive"`
+	TaxableAmount   money.Money `json:"taxableAmount"`
+	Amount          money.Money `json:"amount"`
+}
+
+// LinesToResponse converts an order's tax lines to their API representation
+func LinesToResponse(lines []Line) []LineResponse {
+	responses := make([]LineResponse, len(lines))
+	for i, line := range lines {
+		responses[i] = LineResponse{
+			Name:            line.Name,
+			RateBasisPoints: line.RateBasisPoints,
+			Inclusive:       line.Inclusive,
+			TaxableAmount:   money.New(line.TaxableAmount, line.Currency),
+			Amount:          money.New(line.Amount, line.Currency),
+		}
+	}
+
+	return responses
+}
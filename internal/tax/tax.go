@@ -0,0 +1,66 @@
+package tax
+
+import "github.com/ahmadnaufal/openidea-shopifyx/pkg/money"
+
+// Calculate works out the taxes of the rules that apply to amount, the price of items in the category.
+// Inclusive taxes are taken out of the amount first, and every tax is then charged on what's left.
+func Calculate(rules []Rule, amount money.Money, category string) Breakdown {
+	var applicable []Rule
+	inclusiveRate := int64(0)
+	for _, rule := range rules {
+		if !rule.AppliesTo(amount.Currency, category) {
+			continue
+		}
+
+		applicable = append(applicable, rule)
+		if rule.Inclusive {
+			inclusiveRate += rule.RateBasisPoints
+		}
+	}
+
+	net := amount
+	if inclusiveRate > 0 {
+		net = amount.Fraction(basisPointsPerUnit, basisPointsPerUnit+inclusiveRate)
+	}
+
+	breakdown := Breakdown{
+		Net:       net,
+		Lines:     []Line{},
+		Inclusive: money.New(0, amount.Currency),
+		Exclusive: money.New(0, amount.Currency),
+	}
+
+	for _, rule := range applicable {
+		ruleID := rule.ID
+		line := Line{
+			TaxRuleID:       &ruleID,
+			Name:            rule.Name,
+			RateBasisPoints: rule.RateBasisPoints,
+			Inclusive:       rule.Inclusive,
+			TaxableAmount:   net.Amount,
+			Amount:          net.Fraction(rule.RateBasisPoints, basisPointsPerUnit).Amount,
+			Currency:        amount.Currency,
+		}
+
+		if rule.Inclusive {
+			breakdown.Inclusive.Amount += line.Amount
+		} else {
+			breakdown.Exclusive.Amount += line.Amount
+		}
+		breakdown.Lines = append(breakdown.Lines, line)
+	}
+
+	// the inclusive taxes are rounded separately, so the last one takes the rounding difference
+	// for the net amount and its taxes to add up to the price
+	if difference := amount.Amount - net.Amount - breakdown.Inclusive.Amount; difference != 0 {
+		for i := len(breakdown.Lines) - 1; i >= 0; i-- {
+			if breakdown.Lines[i].Inclusive {
+				breakdown.Lines[i].Amount += difference
+				breakdown.Inclusive.Amount += difference
+				break
+			}
+		}
+	}
+
+	return breakdown
+}
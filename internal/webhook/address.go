@@ -0,0 +1,107 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var (
+	errEndpointNotHTTPS      = errors.New("webhook url must use https")
+	errEndpointAddressDenied = errors.New("webhook url must point to a public address")
+)
+
+// sharedAddressSpace is the carrier-grade NAT range, which isn't reachable from the internet either
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// isDeniedIP tells whether the address belongs to this host or a private network. Webhooks must only
+// reach the internet, or sellers could use them to probe the platform's internal services.
+func isDeniedIP(ip net.IP) bool {
+	return ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() ||
+		ip.IsUnspecified() ||
+		sharedAddressSpace.Contains(ip)
+}
+
+// validateEndpointURL checks the url uses https and that its host only resolves to public addresses.
+// The addresses are checked again when connecting, as the DNS answer can change in between.
+func validateEndpointURL(ctx context.Context, rawURL string) error {
+	endpointURL, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+
+	if endpointURL.Scheme != "https" {
+		return errEndpointNotHTTPS
+	}
+
+	host := endpointURL.Hostname()
+	if host == "" || strings.EqualFold(host, "localhost") || strings.HasSuffix(strings.ToLower(host), ".localhost") {
+		return errEndpointAddressDenied
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		if isDeniedIP(ip) {
+			return errEndpointAddressDenied
+		}
+		return nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return errEndpointAddressDenied
+	}
+	for _, addr := range addrs {
+		if isDeniedIP(addr.IP) {
+			return errEndpointAddressDenied
+		}
+	}
+
+	return nil
+}
+
+// newDeliveryClient returns the client deliveries are sent with. It refuses to connect to denied
+// addresses whatever the host resolves to at the time, and doesn't follow redirects, which could
+// otherwise lead it to one.
+func newDeliveryClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+
+			ip := net.ParseIP(host)
+			if ip == nil || isDeniedIP(ip) {
+				return errEndpointAddressDenied
+			}
+
+			return nil
+		},
+	}
+
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			// no proxy, the dialer must see the endpoint's own address
+			Proxy:               nil,
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+			MaxIdleConns:        100,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
@@ -0,0 +1,398 @@
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"time"
+
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/config"
+	"github.com/ahmadnaufal/openidea-shopifyx/internal/model"
+	"github.com/ahmadnaufal/openidea-shopifyx/pkg/jwt"
+	"github.com/ahmadnaufal/openidea-shopifyx/pkg/validation"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+var (
+	WebhookRepoImpl *WebhookRepo
+	TrxProvider     *config.TransactionProvider
+)
+
+func RegisterRoute(r *fiber.App, jwtProvider jwt.JWTProvider) {
+	webhookGroup := r.Group("/v1/webhook")
+	authMiddleware := jwtProvider.Middleware()
+	webhookGroup.Use(authMiddleware)
+
+	webhookGroup.Post("/", CreateEndpoint)
+	webhookGroup.Get("/", ListEndpoints)
+	webhookGroup.Get("/deliveries/:delivery_id", GetDelivery)
+	webhookGroup.Post("/deliveries/:delivery_id/redeliver", Redeliver)
+	webhookGroup.Delete("/:endpoint_id", DeleteEndpoint)
+	webhookGroup.Get("/:endpoint_id/deliveries", ListDeliveries)
+}
+
+func CreateEndpoint(c *fiber.Ctx) error {
+	var payload CreateEndpointRequest
+
+	claims, err := jwt.GetLoggedInUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "forbidden",
+		})
+	}
+
+	if err := c.BodyParser(&payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "invalid_request_body",
+		})
+	}
+
+	// validation for request body
+	if err := validation.Validate(payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "failed_request_body_validation",
+		})
+	}
+
+	ctx := c.Context()
+	if err := validateEndpointURL(ctx, payload.URL); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "invalid_webhook_url",
+		})
+	}
+
+	secret, err := generateSecret()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	endpoint := Endpoint{
+		ID:        uuid.NewString(),
+		UserID:    claims.UserID,
+		URL:       payload.URL,
+		Secret:    secret,
+		Events:    payload.Events,
+		CreatedAt: time.Now(),
+	}
+
+	err = WebhookRepoImpl.CreateEndpoint(ctx, endpoint)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	// the secret is only shown once, the seller needs it to verify the signatures
+	response := endpointEntityToResponse(endpoint)
+	response.Secret = endpoint.Secret
+
+	return c.Status(fiber.StatusOK).JSON(model.DataResponse{
+		Message: "Webhook endpoint created successfully",
+		Data:    response,
+	})
+}
+
+func ListEndpoints(c *fiber.Ctx) error {
+	claims, err := jwt.GetLoggedInUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "forbidden",
+		})
+	}
+
+	ctx := c.Context()
+	endpoints, err := WebhookRepoImpl.ListEndpointsByUserID(ctx, claims.UserID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	responses := []EndpointResponse{}
+	for _, endpoint := range endpoints {
+		responses = append(responses, endpointEntityToResponse(endpoint))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(model.DataResponse{
+		Message: "ok",
+		Data:    responses,
+	})
+}
+
+func DeleteEndpoint(c *fiber.Ctx) error {
+	endpointID := c.Params("endpoint_id")
+
+	claims, err := jwt.GetLoggedInUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "forbidden",
+		})
+	}
+
+	ctx := c.Context()
+
+	endpoint, err := WebhookRepoImpl.GetEndpointByID(ctx, endpointID)
+	if err != nil && err != sql.ErrNoRows {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	if err == sql.ErrNoRows || endpoint.UserID != claims.UserID {
+		return c.Status(fiber.StatusNotFound).JSON(model.ErrorResponse{
+			Message: "webhook endpoint not found",
+			Code:    "entity_not_found",
+		})
+	}
+
+	err = deleteEndpoint(ctx, endpointID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(model.DataResponse{
+		Message: "Webhook endpoint deleted successfully",
+	})
+}
+
+func deleteEndpoint(ctx context.Context, endpointID string) error {
+	tx, err := TrxProvider.NewTransaction(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = WebhookRepoImpl.DeleteEndpoint(ctx, tx, endpointID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func ListDeliveries(c *fiber.Ctx) error {
+	endpointID := c.Params("endpoint_id")
+
+	claims, err := jwt.GetLoggedInUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "forbidden",
+		})
+	}
+
+	var req ListDeliveriesRequest
+	if err := c.QueryParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "invalid_request_body",
+		})
+	}
+
+	if err := validation.Validate(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "failed_request_body_validation",
+		})
+	}
+
+	ctx := c.Context()
+
+	endpoint, err := WebhookRepoImpl.GetEndpointByID(ctx, endpointID)
+	if err != nil && err != sql.ErrNoRows {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	if err == sql.ErrNoRows || endpoint.UserID != claims.UserID {
+		return c.Status(fiber.StatusNotFound).JSON(model.ErrorResponse{
+			Message: "webhook endpoint not found",
+			Code:    "entity_not_found",
+		})
+	}
+
+	deliveries, count, err := WebhookRepoImpl.ListDeliveries(ctx, endpointID, req)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	responses := []DeliveryResponse{}
+	for _, delivery := range deliveries {
+		responses = append(responses, deliveryEntityToResponse(delivery))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(model.DataResponse{
+		Message: "ok",
+		Data:    responses,
+		Meta: &model.ResponseMeta{
+			Limit:  req.Limit,
+			Offset: req.Offset,
+			Total:  count,
+		},
+	})
+}
+
+// GetDelivery returns a delivery along with the log of every attempt at sending it
+func GetDelivery(c *fiber.Ctx) error {
+	deliveryID := c.Params("delivery_id")
+
+	claims, err := jwt.GetLoggedInUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "forbidden",
+		})
+	}
+
+	ctx := c.Context()
+
+	delivery, err := WebhookRepoImpl.GetDeliveryByID(ctx, deliveryID)
+	if err != nil && err != sql.ErrNoRows {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	if err == sql.ErrNoRows || delivery.UserID != claims.UserID {
+		return c.Status(fiber.StatusNotFound).JSON(model.ErrorResponse{
+			Message: "webhook delivery not found",
+			Code:    "entity_not_found",
+		})
+	}
+
+	attempts, err := WebhookRepoImpl.ListDeliveryAttempts(ctx, deliveryID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	response := deliveryEntityToResponse(delivery)
+	response.AttemptLog = []DeliveryAttemptResponse{}
+	for _, attempt := range attempts {
+		response.AttemptLog = append(response.AttemptLog, DeliveryAttemptResponse{
+			StatusCode: attempt.StatusCode,
+			Error:      attempt.Error,
+			DurationMS: attempt.DurationMS,
+			CreatedAt:  attempt.CreatedAt,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(model.DataResponse{
+		Message: "ok",
+		Data:    response,
+	})
+}
+
+func Redeliver(c *fiber.Ctx) error {
+	deliveryID := c.Params("delivery_id")
+
+	claims, err := jwt.GetLoggedInUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(model.ErrorResponse{
+			Message: err.Error(),
+			Code:    "forbidden",
+		})
+	}
+
+	ctx := c.Context()
+
+	delivery, err := WebhookRepoImpl.GetDeliveryByID(ctx, deliveryID)
+	if err != nil && err != sql.ErrNoRows {
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	if err == sql.ErrNoRows || delivery.UserID != claims.UserID {
+		return c.Status(fiber.StatusNotFound).JSON(model.ErrorResponse{
+			Message: "webhook delivery not found",
+			Code:    "entity_not_found",
+		})
+	}
+
+	delivery, err = WebhookRepoImpl.Redeliver(ctx, deliveryID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return c.Status(fiber.StatusGone).JSON(model.ErrorResponse{
+				Message: "the webhook endpoint of this delivery was deleted",
+				Code:    "webhook_endpoint_deleted",
+			})
+		}
+
+		return c.Status(fiber.StatusInternalServerError).JSON(model.ErrorResponse{
+			Message: "something wrong with the server. Please contact admin",
+			Code:    "internal_server_error",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(model.DataResponse{
+		Message: "Webhook delivery queued for redelivery",
+		Data:    deliveryEntityToResponse(delivery),
+	})
+}
+
+func generateSecret() (string, error) {
+	secret := make([]byte, 32)
+	_, err := rand.Read(secret)
+	if err != nil {
+		return "", err
+	}
+
+	return "whsec_" + hex.EncodeToString(secret), nil
+}
+
+func endpointEntityToResponse(endpoint Endpoint) EndpointResponse {
+	return EndpointResponse{
+		ID:        endpoint.ID,
+		URL:       endpoint.URL,
+		Events:    endpoint.Events,
+		CreatedAt: endpoint.CreatedAt,
+	}
+}
+
+func deliveryEntityToResponse(delivery Delivery) DeliveryResponse {
+	response := DeliveryResponse{
+		ID:             delivery.ID,
+		EventType:      delivery.EventType,
+		Payload:        delivery.Payload,
+		Status:         delivery.Status,
+		Attempts:       delivery.Attempts,
+		LastStatusCode: delivery.LastStatusCode,
+		LastError:      delivery.LastError,
+		CreatedAt:      delivery.CreatedAt,
+		DeliveredAt:    delivery.DeliveredAt,
+	}
+
+	// the next attempt is only meaningful while the delivery is still being retried
+	if delivery.Status == DeliveryStatusPending {
+		response.NextAttemptAt = &delivery.NextAttemptAt
+	}
+
+	return response
+}
@@ -0,0 +1,115 @@
+package webhook
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	EventOrderCreated        = "order.created"
+	EventProductStockChanged = "product.stock_changed"
+	EventProductDeleted      = "product.deleted"
+	EventOrderPaid           = "order.paid"
+	EventOrderCancelled      = "order.cancelled"
+	EventOrderRefunded       = "order.refunded"
+
+	DeliveryStatusPending   = "pending"
+	DeliveryStatusSucceeded = "succeeded"
+	DeliveryStatusFailed    = "failed"
+)
+
+type CreateEndpointRequest struct {
+	URL    string   `json:"url" validate:"required,http_url,max=255"`
+	Events []string `json:"events" validate:"required,min=1,unique,dive,oneof=order.created order.paid order.cancelled order.refunded product.stock_changed product.deleted"`
+}
+
+type ListDeliveriesRequest struct {
+	Status string `query:"status" validate:"omitempty,oneof=pending succeeded failed"`
+	Limit  int    `query:"limit"`
+	Offset int    `query:"offset"`
+}
+
+// Endpoint is a seller's URL that receives the events it is subscribed to.
+// Payloads sent to it are signed with its secret.
+type Endpoint struct {
+	ID        string         `db:"id"`
+	UserID    string         `db:"user_id"`
+	URL       string         `db:"url"`
+	Secret    string         `db:"secret"`
+	Events    pq.StringArray `db:"events"`
+	CreatedAt time.Time      `db:"created_at"`
+	DeletedAt *time.Time     `db:"deleted_at"`
+}
+
+// Delivery is an event to be sent to one endpoint. It is written in the same transaction as the change
+// that caused the event, and sent by the delivery worker afterwards.
+type Delivery struct {
+	ID             string          `db:"id"`
+	EndpointID     string          `db:"endpoint_id"`
+	UserID         string          `db:"user_id"`
+	EventType      string          `db:"event_type"`
+	Payload        json.RawMessage `db:"payload"`
+	Status         string          `db:"status"`
+	Attempts       int             `db:"attempts"`
+	NextAttemptAt  time.Time       `db:"next_attempt_at"`
+	LastStatusCode *int            `db:"last_status_code"`
+	LastError      *string         `db:"last_error"`
+	CreatedAt      time.Time       `db:"created_at"`
+	DeliveredAt    *time.Time      `db:"delivered_at"`
+}
+
+// claimedDelivery is a delivery along with where to send it
+type claimedDelivery struct {
+	Delivery
+	URL    string `db:"url"`
+	Secret string `db:"secret"`
+}
+
+// DeliveryAttempt is one try at sending a delivery, kept as the delivery log
+type DeliveryAttempt struct {
+	ID         string    `db:"id"`
+	DeliveryID string    `db:"delivery_id"`
+	StatusCode *int      `db:"status_code"`
+	Error      *string   `db:"error"`
+	DurationMS int64     `db:"duration_ms"`
+	CreatedAt  time.Time `db:"created_at"`
+}
+
+type ProductStockChangedData struct {
+	ProductID string `json:"productId"`
+	Stock     int    `json:"stock"`
+}
+
+type OrderPaidData struct {
+	OrderID   string `json:"orderId"`
+	ProductID string `json:"productId"`
+}
+
+type OrderCancelledData struct {
+	OrderID   string `json:"orderId"`
+	ProductID string `json:"productId"`
+	Reason    string `json:"reason"`
+}
+
+type OrderRefundedData struct {
+	OrderID   string `json:"orderId"`
+	ProductID string `json:"productId"`
+	// Amount is in the order currency's minor units
+	Amount           int64  `json:"amount"`
+	Currency         string `json:"currency"`
+	RestoredQuantity int    `json:"restoredQuantity"`
+}
+
+type ProductDeletedData struct {
+	ProductID string `json:"productId"`
+}
+
+// eventEnvelope is the JSON body sent to the endpoints
+type eventEnvelope struct {
+	ID        string          `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"createdAt"`
+	Data      json.RawMessage `json:"data"`
+}
@@ -0,0 +1,495 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+type WebhookRepo struct {
+	db *sqlx.DB
+}
+
+func NewWebhookRepo(db *sqlx.DB) WebhookRepo {
+	return WebhookRepo{db: db}
+}
+
+func (r WebhookRepo) CreateEndpoint(ctx context.Context, endpoint Endpoint) error {
+	query := `
+		INSERT INTO webhook_endpoints
+			(id, user_id, url, secret, events, created_at)
+		VALUES
+			(:id, :user_id, :url, :secret, :events, :created_at)
+	`
+
+	updatedQuery, args, err := sqlx.Named(query, endpoint)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx, sqlx.Rebind(sqlx.DOLLAR, updatedQuery), args...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r WebhookRepo) ListEndpointsByUserID(ctx context.Context, userID string) ([]Endpoint, error) {
+	var endpoints []Endpoint
+
+	query := `
+		SELECT
+			id,
+			user_id,
+			url,
+			secret,
+			events,
+			created_at,
+			deleted_at
+		FROM
+			webhook_endpoints
+		WHERE
+			user_id = $1
+			AND deleted_at IS NULL
+		ORDER BY
+			created_at DESC
+	`
+
+	err := r.db.SelectContext(ctx, &endpoints, query, userID)
+	if err != nil {
+		return endpoints, err
+	}
+
+	return endpoints, nil
+}
+
+func (r WebhookRepo) GetEndpointByID(ctx context.Context, endpointID string) (Endpoint, error) {
+	var result Endpoint
+
+	query := `
+		SELECT
+			id,
+			user_id,
+			url,
+			secret,
+			events,
+			created_at,
+			deleted_at
+		FROM
+			webhook_endpoints
+		WHERE
+			id = $1
+			AND deleted_at IS NULL
+		LIMIT 1
+	`
+
+	err := r.db.GetContext(ctx, &result, query, endpointID)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// DeleteEndpoint soft-deletes the endpoint, so its delivery log is kept, and gives up on its pending deliveries
+func (r WebhookRepo) DeleteEndpoint(ctx context.Context, tx *sql.Tx, endpointID string) error {
+	query := `
+		UPDATE webhook_endpoints
+		SET
+			deleted_at = NOW()
+		WHERE
+			id = $1
+			AND deleted_at IS NULL
+	`
+
+	result, err := tx.ExecContext(ctx, query, endpointID)
+	if err != nil {
+		return err
+	}
+
+	affectedRows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affectedRows != 1 {
+		return errors.New("error affected row count is not equal to 1")
+	}
+
+	deliveriesQuery := `
+		UPDATE webhook_deliveries
+		SET
+			status = $1,
+			last_error = 'endpoint was deleted'
+		WHERE
+			endpoint_id = $2
+			AND status = $3
+	`
+
+	_, err = tx.ExecContext(ctx, deliveriesQuery, DeliveryStatusFailed, endpointID, DeliveryStatusPending)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// EnqueueEvent writes a delivery for every endpoint of the user subscribed to the event. It must be called
+// in the same transaction as the change that caused the event, so the event is sent if and only if the
+// change is committed.
+func (r WebhookRepo) EnqueueEvent(ctx context.Context, tx *sql.Tx, userID, eventType string, data interface{}) error {
+	query := `
+		SELECT
+			id
+		FROM
+			webhook_endpoints
+		WHERE
+			user_id = $1
+			AND $2 = ANY(events)
+			AND deleted_at IS NULL
+	`
+
+	var endpointIDs []string
+	rows, err := tx.QueryContext(ctx, query, userID, eventType)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var endpointID string
+		if err := rows.Scan(&endpointID); err != nil {
+			return err
+		}
+		endpointIDs = append(endpointIDs, endpointID)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if len(endpointIDs) == 0 {
+		return nil
+	}
+
+	rawData, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	deliveries := []Delivery{}
+	for _, endpointID := range endpointIDs {
+		deliveryID := uuid.NewString()
+
+		// the delivery ID doubles as the event ID, so receivers can deduplicate retries
+		payload, err := json.Marshal(eventEnvelope{
+			ID:        deliveryID,
+			Type:      eventType,
+			CreatedAt: now,
+			Data:      rawData,
+		})
+		if err != nil {
+			return err
+		}
+
+		deliveries = append(deliveries, Delivery{
+			ID:            deliveryID,
+			EndpointID:    endpointID,
+			UserID:        userID,
+			EventType:     eventType,
+			Payload:       payload,
+			Status:        DeliveryStatusPending,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+		})
+	}
+
+	insertQuery := `
+		INSERT INTO webhook_deliveries
+			(id, endpoint_id, user_id, event_type, payload, status, attempts, next_attempt_at, created_at)
+		VALUES
+			(:id, :endpoint_id, :user_id, :event_type, :payload, :status, :attempts, :next_attempt_at, :created_at)
+	`
+
+	updatedQuery, args, err := sqlx.Named(insertQuery, deliveries)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, sqlx.Rebind(sqlx.DOLLAR, updatedQuery), args...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ClaimDueDeliveries picks up to limit pending deliveries that are due, and pushes their next attempt
+// back by lease so no other worker picks them up while they are being sent
+func (r WebhookRepo) ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]claimedDelivery, error) {
+	var deliveries []claimedDelivery
+
+	query := `
+		WITH due AS (
+			SELECT
+				id
+			FROM
+				webhook_deliveries
+			WHERE
+				status = $1
+				AND next_attempt_at <= NOW()
+			ORDER BY
+				next_attempt_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE webhook_deliveries d
+		SET
+			next_attempt_at = NOW() + make_interval(secs => $3)
+		FROM
+			due,
+			webhook_endpoints e
+		WHERE
+			d.id = due.id
+			AND e.id = d.endpoint_id
+		RETURNING
+			d.id,
+			d.endpoint_id,
+			d.user_id,
+			d.event_type,
+			d.payload,
+			d.status,
+			d.attempts,
+			d.next_attempt_at,
+			d.last_status_code,
+			d.last_error,
+			d.created_at,
+			d.delivered_at,
+			e.url,
+			e.secret
+	`
+
+	err := r.db.SelectContext(ctx, &deliveries, query, DeliveryStatusPending, limit, lease.Seconds())
+	if err != nil {
+		return deliveries, err
+	}
+
+	return deliveries, nil
+}
+
+// SaveDeliveryAttempt logs the attempt and stores the delivery's updated status
+func (r WebhookRepo) SaveDeliveryAttempt(ctx context.Context, tx *sql.Tx, delivery Delivery, attempt DeliveryAttempt) error {
+	attemptQuery := `
+		INSERT INTO webhook_delivery_attempts
+			(id, delivery_id, status_code, error, duration_ms, created_at)
+		VALUES
+			(:id, :delivery_id, :status_code, :error, :duration_ms, :created_at)
+	`
+
+	updatedQuery, args, err := sqlx.Named(attemptQuery, attempt)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, sqlx.Rebind(sqlx.DOLLAR, updatedQuery), args...)
+	if err != nil {
+		return err
+	}
+
+	deliveryQuery := `
+		UPDATE webhook_deliveries
+		SET
+			status = $1,
+			attempts = $2,
+			next_attempt_at = $3,
+			last_status_code = $4,
+			last_error = $5,
+			delivered_at = $6
+		WHERE
+			id = $7
+	`
+
+	result, err := tx.ExecContext(
+		ctx, deliveryQuery,
+		delivery.Status, delivery.Attempts, delivery.NextAttemptAt, delivery.LastStatusCode,
+		delivery.LastError, delivery.DeliveredAt, delivery.ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	affectedRows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affectedRows != 1 {
+		return errors.New("error affected row count is not equal to 1")
+	}
+
+	return nil
+}
+
+func (r WebhookRepo) ListDeliveries(ctx context.Context, endpointID string, req ListDeliveriesRequest) ([]Delivery, int, error) {
+	var deliveries []Delivery
+
+	countQuery := `
+		SELECT
+			COUNT(*)
+		FROM
+			webhook_deliveries
+		WHERE
+			endpoint_id = $1
+			AND ($2 = '' OR status = $2)
+	`
+
+	var count int
+	err := r.db.GetContext(ctx, &count, countQuery, endpointID, req.Status)
+	if err != nil {
+		return deliveries, count, err
+	}
+
+	limit := req.Limit
+	if limit <= 0 {
+		limit = 10
+	}
+
+	offset := req.Offset
+	if offset < 0 {
+		offset = 0
+	}
+
+	query := `
+		SELECT
+			id,
+			endpoint_id,
+			user_id,
+			event_type,
+			payload,
+			status,
+			attempts,
+			next_attempt_at,
+			last_status_code,
+			last_error,
+			created_at,
+			delivered_at
+		FROM
+			webhook_deliveries
+		WHERE
+			endpoint_id = $1
+			AND ($2 = '' OR status = $2)
+		ORDER BY
+			created_at DESC
+		LIMIT $3 OFFSET $4
+	`
+
+	err = r.db.SelectContext(ctx, &deliveries, query, endpointID, req.Status, limit, offset)
+	if err != nil {
+		return deliveries, count, err
+	}
+
+	return deliveries, count, nil
+}
+
+func (r WebhookRepo) GetDeliveryByID(ctx context.Context, deliveryID string) (Delivery, error) {
+	var result Delivery
+
+	query := `
+		SELECT
+			id,
+			endpoint_id,
+			user_id,
+			event_type,
+			payload,
+			status,
+			attempts,
+			next_attempt_at,
+			last_status_code,
+			last_error,
+			created_at,
+			delivered_at
+		FROM
+			webhook_deliveries
+		WHERE
+			id = $1
+		LIMIT 1
+	`
+
+	err := r.db.GetContext(ctx, &result, query, deliveryID)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r WebhookRepo) ListDeliveryAttempts(ctx context.Context, deliveryID string) ([]DeliveryAttempt, error) {
+	var attempts []DeliveryAttempt
+
+	query := `
+		SELECT
+			id,
+			delivery_id,
+			status_code,
+			error,
+			duration_ms,
+			created_at
+		FROM
+			webhook_delivery_attempts
+		WHERE
+			delivery_id = $1
+		ORDER BY
+			created_at
+	`
+
+	err := r.db.SelectContext(ctx, &attempts, query, deliveryID)
+	if err != nil {
+		return attempts, err
+	}
+
+	return attempts, nil
+}
+
+// Redeliver queues the delivery to be sent again right away with a fresh set of retries.
+// It returns sql.ErrNoRows when the delivery's endpoint was deleted.
+func (r WebhookRepo) Redeliver(ctx context.Context, deliveryID string) (Delivery, error) {
+	var result Delivery
+
+	query := `
+		UPDATE webhook_deliveries d
+		SET
+			status = $1,
+			attempts = 0,
+			next_attempt_at = NOW()
+		FROM
+			webhook_endpoints e
+		WHERE
+			d.id = $2
+			AND e.id = d.endpoint_id
+			AND e.deleted_at IS NULL
+		RETURNING
+			d.id,
+			d.endpoint_id,
+			d.user_id,
+			d.event_type,
+			d.payload,
+			d.status,
+			d.attempts,
+			d.next_attempt_at,
+			d.last_status_code,
+			d.last_error,
+			d.created_at,
+			d.delivered_at
+	`
+
+	err := r.db.GetContext(ctx, &result, query, DeliveryStatusPending, deliveryID)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
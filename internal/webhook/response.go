@@ -0,0 +1,36 @@
+package webhook
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type EndpointResponse struct {
+	ID     string   `json:"id"`
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	// Secret is only returned when the endpoint is created
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type DeliveryResponse struct {
+	ID             string                    `json:"id"`
+	EventType      string                    `json:"eventType"`
+	Payload        json.RawMessage           `json:"payload"`
+	Status         string                    `json:"status"`
+	Attempts       int                       `json:"attempts"`
+	NextAttemptAt  *time.Time                `json:"nextAttemptAt,omitempty"`
+	LastStatusCode *int                      `json:"lastStatusCode"`
+	LastError      *string                   `json:"lastError"`
+	CreatedAt      time.Time                 `json:"createdAt"`
+	DeliveredAt    *time.Time                `json:"deliveredAt"`
+	AttemptLog     []DeliveryAttemptResponse `json:"attemptLog,omitempty"`
+}
+
+type DeliveryAttemptResponse struct {
+	StatusCode *int      `json:"statusCode"`
+	Error      *string   `json:"error"`
+	DurationMS int64     `json:"durationMs"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
@@ -0,0 +1,175 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ahmadnaufal/openidea-shopifyx/pkg/retry"
+	"github.com/google/uuid"
+)
+
+const (
+	deliveryBatchSize   = 50
+	deliveryMaxAttempts = 10
+	deliveryRetryBase   = time.Minute
+	deliveryRetryMax    = 6 * time.Hour
+	// deliveryLeaseMargin is added to the time a batch can take to send, to cover saving its attempts
+	deliveryLeaseMargin = time.Minute
+
+	// only so much of the response is read, to let the connection be reused
+	maxDrainedResponseBytes = 4096
+)
+
+// RunDeliveryWorker periodically sends the pending webhook deliveries, retrying failed ones with
+// exponential backoff. It blocks until ctx is cancelled, so it should be run in its own goroutine.
+func RunDeliveryWorker(ctx context.Context, interval, timeout time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	client := newDeliveryClient(timeout)
+
+	for {
+		sent, err := sendDueDeliveries(ctx, client, timeout)
+		if err != nil {
+			log.Printf("error sending webhook deliveries: %v", err)
+		} else if sent > 0 {
+			log.Printf("sent %d webhook deliveries", sent)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sendDueDeliveries sends a batch of deliveries one after another. They are leased for as long as the
+// whole batch can take, so no other worker claims and sends them again before this one gets to them.
+func sendDueDeliveries(ctx context.Context, client *http.Client, timeout time.Duration) (int, error) {
+	lease := time.Duration(deliveryBatchSize)*timeout + deliveryLeaseMargin
+	deliveries, err := WebhookRepoImpl.ClaimDueDeliveries(ctx, deliveryBatchSize, lease)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, delivery := range deliveries {
+		attempt := send(ctx, client, delivery)
+
+		updated := applyAttempt(delivery.Delivery, attempt)
+		err = saveDeliveryAttempt(ctx, updated, attempt)
+		if err != nil {
+			// the lease runs out and the delivery is retried, which receivers deduplicate by event ID
+			log.Printf("error saving webhook delivery attempt %s: %v", delivery.ID, err)
+		}
+	}
+
+	return len(deliveries), nil
+}
+
+func saveDeliveryAttempt(ctx context.Context, delivery Delivery, attempt DeliveryAttempt) error {
+	tx, err := TrxProvider.NewTransaction(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = WebhookRepoImpl.SaveDeliveryAttempt(ctx, tx, delivery, attempt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// send posts the payload to the endpoint, signed with the endpoint's secret
+func send(ctx context.Context, client *http.Client, delivery claimedDelivery) DeliveryAttempt {
+	attempt := DeliveryAttempt{
+		ID:         uuid.NewString(),
+		DeliveryID: delivery.ID,
+		CreatedAt:  time.Now(),
+	}
+
+	// endpoints made before https was required are never sent to in the clear
+	if !strings.HasPrefix(delivery.URL, "https://") {
+		errMessage := errEndpointNotHTTPS.Error()
+		attempt.Error = &errMessage
+		return attempt
+	}
+
+	timestamp := strconv.FormatInt(attempt.CreatedAt.Unix(), 10)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		errMessage := err.Error()
+		attempt.Error = &errMessage
+		return attempt
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "shopifyx-webhooks/1.0")
+	req.Header.Set("X-Shopifyx-Event", delivery.EventType)
+	req.Header.Set("X-Shopifyx-Delivery", delivery.ID)
+	req.Header.Set("X-Shopifyx-Timestamp", timestamp)
+	req.Header.Set("X-Shopifyx-Signature", Sign(delivery.Secret, timestamp, delivery.Payload))
+
+	resp, err := client.Do(req)
+	attempt.DurationMS = time.Since(attempt.CreatedAt).Milliseconds()
+	if err != nil {
+		errMessage := err.Error()
+		attempt.Error = &errMessage
+		return attempt
+	}
+	defer resp.Body.Close()
+
+	// the response body isn't kept, it could be anything the endpoint chose to return
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxDrainedResponseBytes))
+
+	attempt.StatusCode = &resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		errMessage := fmt.Sprintf("endpoint responded with status %d", resp.StatusCode)
+		attempt.Error = &errMessage
+	}
+
+	return attempt
+}
+
+// applyAttempt returns the delivery updated with the attempt's outcome
+func applyAttempt(delivery Delivery, attempt DeliveryAttempt) Delivery {
+	delivery.Attempts++
+	delivery.LastStatusCode = attempt.StatusCode
+	delivery.LastError = attempt.Error
+
+	switch {
+	case attempt.Error == nil:
+		delivery.Status = DeliveryStatusSucceeded
+		delivery.DeliveredAt = &attempt.CreatedAt
+	case delivery.Attempts >= deliveryMaxAttempts:
+		delivery.Status = DeliveryStatusFailed
+	default:
+		delivery.NextAttemptAt = time.Now().Add(retry.Backoff(delivery.Attempts, deliveryRetryBase, deliveryRetryMax))
+	}
+
+	return delivery
+}
+
+// Sign computes the signature header of a payload: an HMAC-SHA256 of "<timestamp>.<payload>" keyed with
+// the endpoint's secret. Receivers should recompute it and reject old timestamps to prevent replays.
+func Sign(secret, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -40,9 +40,14 @@ func (p *JWTProvider) MiddlewareWithPublic() fiber.Handler {
 			JWTAlg: jwtware.HS256,
 			Key:    p.privateKey,
 		},
-		Filter: func(c *fiber.Ctx) bool {
-			// only filter if there's userOnly
-			return !c.QueryBool("userOnly", false)
+		ErrorHandler: func(c *fiber.Ctx, err error) error {
+			// missing, expired or malformed tokens are served anonymously, unless userOnly is set which
+			// requires a logged in user
+			if !c.QueryBool("userOnly", false) {
+				return c.Next()
+			}
+
+			return c.Status(fiber.StatusUnauthorized).SendString("Invalid or expired JWT")
 		},
 	})
 }
@@ -58,6 +63,21 @@ func (p *JWTProvider) Middleware() fiber.Handler {
 	})
 }
 
+// StreamMiddleware is like Middleware, but also accepts the token from the access_token query param
+// for clients that can't set headers, like the browser's EventSource
+func (p *JWTProvider) StreamMiddleware() fiber.Handler {
+	return jwtware.New(jwtware.Config{
+		ContextKey:  "user",
+		Claims:      jwt.MapClaims{},
+		TokenLookup: "header:Authorization,query:access_token",
+		AuthScheme:  "Bearer",
+		SigningKey: jwtware.SigningKey{
+			JWTAlg: jwtware.HS256,
+			Key:    p.privateKey,
+		},
+	})
+}
+
 func GetLoggedInUser(c *fiber.Ctx) (JWTUser, error) {
 	jwtUser := JWTUser{}
 
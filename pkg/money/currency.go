@@ -0,0 +1,72 @@
+package money
+
+type Currency string
+
+const (
+	IDR Currency = "IDR"
+	SGD Currency = "SGD"
+	MYR Currency = "MYR"
+
+	// DefaultCurrency is the currency used when none is specified
+	DefaultCurrency = IDR
+)
+
+type currencyInfo struct {
+	// exponent is the number of minor unit digits as defined by ISO 4217
+	exponent int
+	symbol   string
+	// symbolSeparator is put between the symbol and the amount when formatting
+	symbolSeparator   string
+	thousandSeparator string
+	decimalSeparator  string
+	// displayDecimals is how many minor unit digits are shown when formatting
+	displayDecimals int
+	// roundingIncrement is the smallest amount, in minor units, that can actually be charged
+	roundingIncrement int64
+}
+
+var currencies = map[Currency]currencyInfo{
+	// sub-rupiah coins are no longer in circulation, so amounts are rounded to whole rupiah
+	IDR: {
+		exponent:          2,
+		symbol:            "Rp",
+		symbolSeparator:   " ",
+		thousandSeparator: ".",
+		decimalSeparator:  ",",
+		displayDecimals:   0,
+		roundingIncrement: 100,
+	},
+	SGD: {
+		exponent:          2,
+		symbol:            "S$",
+		thousandSeparator: ",",
+		decimalSeparator:  ".",
+		displayDecimals:   2,
+		roundingIncrement: 1,
+	},
+	// Malaysia rounds totals to the nearest 5 sen since the 1 sen coin was withdrawn
+	MYR: {
+		exponent:          2,
+		symbol:            "RM",
+		thousandSeparator: ",",
+		decimalSeparator:  ".",
+		displayDecimals:   2,
+		roundingIncrement: 5,
+	},
+}
+
+// IsSupported checks whether the currency can be used for listing products
+func (c Currency) IsSupported() bool {
+	_, ok := currencies[c]
+	return ok
+}
+
+// Exponent returns the number of minor unit digits of the currency
+func (c Currency) Exponent() int {
+	return currencies[c].exponent
+}
+
+// SupportedCurrencies returns all the currencies that can be used for listing products
+func SupportedCurrencies() []Currency {
+	return []Currency{IDR, SGD, MYR}
+}
@@ -0,0 +1,176 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Money is an amount in the currency's minor units (e.g. sen or cents) and its ISO 4217 currency
+type Money struct {
+	Amount   int64    `json:"amount" validate:"gte=0"`
+	Currency Currency `json:"currency" validate:"required,oneof=IDR SGD MYR"`
+}
+
+func New(amount int64, currency Currency) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+var (
+	ErrCurrencyMismatch = errors.New("money: currency mismatch")
+	ErrOverflow         = errors.New("money: amount is too large")
+)
+
+// Add sums two amounts of the same currency
+func (m Money) Add(other Money) (Money, error) {
+	if m.Currency != other.Currency {
+		return Money{}, ErrCurrencyMismatch
+	}
+	return checked(new(big.Int).Add(big.NewInt(m.Amount), big.NewInt(other.Amount)), m.Currency)
+}
+
+// Sub subtracts two amounts of the same currency
+func (m Money) Sub(other Money) (Money, error) {
+	if m.Currency != other.Currency {
+		return Money{}, ErrCurrencyMismatch
+	}
+	return checked(new(big.Int).Sub(big.NewInt(m.Amount), big.NewInt(other.Amount)), m.Currency)
+}
+
+func (m Money) Multiply(quantity int64) (Money, error) {
+	return checked(new(big.Int).Mul(big.NewInt(m.Amount), big.NewInt(quantity)), m.Currency)
+}
+
+// Percentage returns percent% of the amount, rounded with the currency's rounding rules
+func (m Money) Percentage(percent int64) Money {
+	return m.Fraction(percent, 100)
+}
+
+// Fraction returns numerator/denominator (denominator > 0) of the amount, rounded with the currency's rounding rules
+func (m Money) Fraction(numerator, denominator int64) Money {
+	return Money{Amount: fractionRounded(m.Amount, numerator, denominator), Currency: m.Currency}.Round()
+}
+
+// Round rounds the amount half away from zero to the currency's rounding increment
+func (m Money) Round() Money {
+	increment := currencies[m.Currency].roundingIncrement
+	if increment <= 1 {
+		return m
+	}
+
+	return Money{Amount: divideRounded(m.Amount, increment) * increment, Currency: m.Currency}
+}
+
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+// Min returns the smaller of the two amounts of the same currency
+func (m Money) Min(other Money) (Money, error) {
+	if m.Currency != other.Currency {
+		return Money{}, ErrCurrencyMismatch
+	}
+	if other.Amount < m.Amount {
+		return other, nil
+	}
+	return m, nil
+}
+
+// Format formats the amount for display, e.g. "Rp 15.000" or "S$15.00"
+func (m Money) Format() string {
+	info, ok := currencies[m.Currency]
+	if !ok {
+		return fmt.Sprintf("%d %s", m.Amount, m.Currency)
+	}
+
+	amount := m.Amount
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	// drop the minor unit digits that aren't displayed, rounding them away
+	hidden := int64(1)
+	for i := 0; i < info.exponent-info.displayDecimals; i++ {
+		hidden *= 10
+	}
+	amount = divideRounded(amount, hidden)
+
+	unit := int64(1)
+	for i := 0; i < info.displayDecimals; i++ {
+		unit *= 10
+	}
+
+	formatted := groupThousands(amount/unit, info.thousandSeparator)
+	if info.displayDecimals > 0 {
+		formatted += fmt.Sprintf("%s%0*d", info.decimalSeparator, info.displayDecimals, amount%unit)
+	}
+
+	return sign + info.symbol + info.symbolSeparator + formatted
+}
+
+func (m Money) String() string {
+	return m.Format()
+}
+
+// MarshalJSON adds the formatted amount so clients don't need to know each currency's formatting rules
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Amount    int64    `json:"amount"`
+		Currency  Currency `json:"currency"`
+		Formatted string   `json:"formatted"`
+	}{
+		Amount:    m.Amount,
+		Currency:  m.Currency,
+		Formatted: m.Format(),
+	})
+}
+
+// checked returns the amount, or ErrOverflow if it doesn't fit in an int64
+func checked(amount *big.Int, currency Currency) (Money, error) {
+	if !amount.IsInt64() {
+		return Money{}, ErrOverflow
+	}
+	return Money{Amount: amount.Int64(), Currency: currency}, nil
+}
+
+// divideRounded divides a by b (b > 0), rounding half away from zero
+func divideRounded(a, b int64) int64 {
+	if a < 0 {
+		return -((-a + b/2) / b)
+	}
+	return (a + b/2) / b
+}
+
+// fractionRounded returns a*numerator/denominator rounded half away from zero, multiplying in
+// big.Int so large amounts (e.g. an order total times a refund amount) can't overflow int64
+func fractionRounded(a, numerator, denominator int64) int64 {
+	product := new(big.Int).Mul(big.NewInt(a), big.NewInt(numerator))
+	negative := product.Sign() < 0
+	product.Abs(product)
+
+	d := big.NewInt(denominator)
+	product.Add(product, new(big.Int).Quo(d, big.NewInt(2)))
+	result := product.Quo(product, d).Int64()
+	if negative {
+		return -result
+	}
+	return result
+}
+
+func groupThousands(value int64, separator string) string {
+	digits := fmt.Sprintf("%d", value)
+
+	var sb strings.Builder
+	for i, digit := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			sb.WriteString(separator)
+		}
+		sb.WriteRune(digit)
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,107 @@
+package money
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRound(t *testing.T) {
+	tests := []struct {
+		name  string
+		money Money
+		want  int64
+	}{
+		{"IDR rounds down below half", New(149, IDR), 100},
+		{"IDR rounds half up", New(150, IDR), 200},
+		{"IDR rounds half away from zero when negative", New(-150, IDR), -200},
+		{"IDR keeps whole rupiah", New(100000, IDR), 100000},
+		{"MYR rounds down to 5 sen", New(102, MYR), 100},
+		{"MYR rounds up to 5 sen", New(103, MYR), 105},
+		{"MYR rounds down past 5 sen", New(107, MYR), 105},
+		{"MYR rounds up to 10 sen", New(108, MYR), 110},
+		{"SGD keeps every cent", New(123, SGD), 123},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.money.Round()
+			if got.Amount != tt.want || got.Currency != tt.money.Currency {
+				t.Errorf("Round() = %d %s, want %d %s", got.Amount, got.Currency, tt.want, tt.money.Currency)
+			}
+		})
+	}
+}
+
+func TestFraction(t *testing.T) {
+	tests := []struct {
+		name        string
+		money       Money
+		numerator   int64
+		denominator int64
+		want        int64
+	}{
+		{"SGD rounds down below half", New(100, SGD), 1, 3, 33},
+		{"SGD rounds up above half", New(200, SGD), 1, 3, 67},
+		{"SGD rounds away from zero when negative", New(-200, SGD), 1, 3, -67},
+		{"IDR rounds to whole rupiah", New(1000000, IDR), 1, 3, 333300},
+		{"MYR rounds to 5 sen", New(1001, MYR), 1, 2, 500},
+		{"whole amount", New(12345, SGD), 7, 7, 12345},
+		{"no overflow when the product exceeds int64", New(math.MaxInt64/2, SGD), 3, 4, 3458764513820540927},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.money.Fraction(tt.numerator, tt.denominator)
+			if got.Amount != tt.want {
+				t.Errorf("Fraction(%d, %d) = %d, want %d", tt.numerator, tt.denominator, got.Amount, tt.want)
+			}
+		})
+	}
+}
+
+func TestPercentage(t *testing.T) {
+	tests := []struct {
+		name    string
+		money   Money
+		percent int64
+		want    int64
+	}{
+		{"IDR rounds to whole rupiah", New(1234500, IDR), 10, 123500},
+		{"SGD rounds to the cent", New(999, SGD), 15, 150},
+		{"MYR rounds to 5 sen", New(1999, MYR), 50, 1000},
+		{"more than the whole amount", New(1000, SGD), 110, 1100},
+		{"zero percent", New(1000, SGD), 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.money.Percentage(tt.percent)
+			if got.Amount != tt.want {
+				t.Errorf("Percentage(%d) = %d, want %d", tt.percent, got.Amount, tt.want)
+			}
+		})
+	}
+}
+
+func TestArithmeticErrors(t *testing.T) {
+	if _, err := New(100, IDR).Add(New(100, SGD)); err != ErrCurrencyMismatch {
+		t.Errorf("Add() across currencies error = %v, want %v", err, ErrCurrencyMismatch)
+	}
+	if _, err := New(100, IDR).Sub(New(100, SGD)); err != ErrCurrencyMismatch {
+		t.Errorf("Sub() across currencies error = %v, want %v", err, ErrCurrencyMismatch)
+	}
+	if _, err := New(100, IDR).Min(New(100, SGD)); err != ErrCurrencyMismatch {
+		t.Errorf("Min() across currencies error = %v, want %v", err, ErrCurrencyMismatch)
+	}
+	if _, err := New(math.MaxInt64, SGD).Add(New(1, SGD)); err != ErrOverflow {
+		t.Errorf("Add() past int64 error = %v, want %v", err, ErrOverflow)
+	}
+	if _, err := New(math.MaxInt64/2+1, SGD).Multiply(2); err != ErrOverflow {
+		t.Errorf("Multiply() past int64 error = %v, want %v", err, ErrOverflow)
+	}
+
+	got, err := New(250, SGD).Multiply(4)
+	if err != nil || got.Amount != 1000 {
+		t.Errorf("Multiply(4) = %d, %v, want 1000, nil", got.Amount, err)
+	}
+}
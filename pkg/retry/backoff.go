@@ -0,0 +1,25 @@
+package retry
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Backoff returns how long to wait before the given retry attempt (starting at 1), doubling from base
+// up to max. Up to a quarter of the delay is randomized so retries of many failures don't line up.
+func Backoff(attempt int, base, max time.Duration) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+
+	delay := base
+	for i := 1; i < attempt && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+
+	jitter := time.Duration(rand.Int63n(int64(delay)/4 + 1))
+	return delay - jitter
+}
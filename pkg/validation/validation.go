@@ -4,10 +4,23 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/ahmadnaufal/openidea-shopifyx/pkg/money"
 	"github.com/go-playground/validator/v10"
 )
 
-var validate = validator.New(validator.WithRequiredStructEnabled())
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+
+	// positive_amount rejects money of zero, which "required" lets through since the struct isn't empty
+	v.RegisterValidation("positive_amount", func(fl validator.FieldLevel) bool {
+		m, ok := fl.Field().Interface().(money.Money)
+		return ok && m.Amount > 0
+	})
+
+	return v
+}
 
 func Validate(data any) error {
 	err := validate.Struct(data)